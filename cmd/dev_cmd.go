@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/otiszv/render/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	devTemplateDir string
+	devValuesFile  string
+	devTemplate    string
+	devVersion     string
+	devWatch       bool
+	devInterval    time.Duration
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "validate and render templates while developing them",
+	Long: "validate definitions of template repository and render the pipeline template with values file, " +
+		"it re-runs on every change of template repository or values file when --watch is set",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dev(devTemplateDir, devValuesFile, devTemplate, devVersion, devWatch, devInterval)
+	},
+}
+
+func dev(dir string, valuesFile string, template string, version string, watch bool, interval time.Duration) error {
+	if dir == "" {
+		return errors.New("template repository directory is required")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("interval %s should be positive", interval)
+	}
+
+	if !watch {
+		return devRun(dir, valuesFile, template, version)
+	}
+
+	stamps, err := devSnapshot(dir, valuesFile)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := devRun(dir, valuesFile, template, version); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		}
+		fmt.Printf("\nwatching %s for changes...\n", dir)
+
+		for {
+			time.Sleep(interval)
+			current, err := devSnapshot(dir, valuesFile)
+			if err != nil {
+				// files may be in the middle of saving, try it later
+				continue
+			}
+			if changed := devChangedFiles(stamps, current); len(changed) > 0 {
+				stamps = current
+				fmt.Printf("\n==> %s changed at %s\n", changed[0], time.Now().Format("15:04:05"))
+				break
+			}
+		}
+	}
+}
+
+// devRun validate all definitions of dir, and render the pipeline template when they are valid
+func devRun(dir string, valuesFile string, template string, version string) error {
+	if err := validate(nil, dir); err != nil {
+		return err
+	}
+
+	values := renderValues{}
+	if valuesFile != "" {
+		byts, err := ioutil.ReadFile(valuesFile)
+		if err != nil {
+			return err
+		}
+		if err = yaml.Unmarshal(byts, &values); err != nil {
+			return fmt.Errorf("values file %s is invalid: %s", valuesFile, err.Error())
+		}
+	}
+
+	files, err := getFilelist(dir)
+	if err != nil {
+		return err
+	}
+	catalog := domain.NewTemplateCatalog()
+	if err = catalog.LoadFiles(files); err != nil {
+		return err
+	}
+
+	if template == "" {
+		names := map[string]struct{}{}
+		for _, item := range catalog.PipelineTemplates {
+			names[item.Name] = struct{}{}
+			template = item.Name
+		}
+		if len(names) != 1 {
+			return fmt.Errorf("there are %d pipeline templates in %s, specify the one to render by --template", len(names), dir)
+		}
+	}
+
+	spec, err := catalog.ResolvePipelineTemplate(template, version)
+	if err != nil {
+		return err
+	}
+	if spec.IsExtended() {
+		spec, err = spec.Expand(catalog.ResolvePipelineTemplate)
+		if err != nil {
+			return err
+		}
+	}
+
+	jenkinsfile, err := renderPipelineTemplateSpec(spec, catalog, values)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%s", jenkinsfile)
+	return nil
+}
+
+type devFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// devSnapshot modification time and size of files in dir and values file, they are compared to find changes
+func devSnapshot(dir string, valuesFile string) (map[string]devFileStamp, error) {
+	files, err := getFilelist(dir)
+	if err != nil {
+		return nil, err
+	}
+	if valuesFile != "" {
+		files = append(files, valuesFile)
+	}
+
+	stamps := make(map[string]devFileStamp, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stamps[file] = devFileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// devChangedFiles files that are changed, created or removed between snapshots
+func devChangedFiles(previous map[string]devFileStamp, current map[string]devFileStamp) []string {
+	changed := []string{}
+	for file, stamp := range current {
+		if old, ok := previous[file]; !ok || !old.modTime.Equal(stamp.modTime) || old.size != stamp.size {
+			changed = append(changed, file)
+		}
+	}
+	for file := range previous {
+		if _, ok := current[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func init() {
+	devCmd.Flags().StringVarP(
+		&devTemplateDir,
+		"template-dir", "d", "", "provider the pipeline template repository directory that is developing",
+	)
+
+	devCmd.Flags().StringVarP(
+		&devValuesFile,
+		"values", "v", "", "provider the values file to render pipeline template, eg: arguments and scm",
+	)
+
+	devCmd.Flags().StringVarP(
+		&devTemplate,
+		"template", "t", "", "name of pipeline template to render, it could be omitted when there is only one pipeline template",
+	)
+
+	devCmd.Flags().StringVar(
+		&devVersion,
+		"version", "", "version constraint of pipeline template to render",
+	)
+
+	devCmd.Flags().BoolVarP(
+		&devWatch,
+		"watch", "w", false, "watch template repository and values file, re-run on every change",
+	)
+
+	devCmd.Flags().DurationVar(
+		&devInterval,
+		"interval", time.Second, "interval to poll changes of files when watching",
+	)
+
+	RootCmd.AddCommand(devCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDevChangedFiles(t *testing.T) {
+	now := time.Now()
+	previous := map[string]devFileStamp{
+		"same.yaml":     {modTime: now, size: 10},
+		"modified.yaml": {modTime: now, size: 10},
+		"resized.yaml":  {modTime: now, size: 10},
+		"removed.yaml":  {modTime: now, size: 10},
+	}
+	current := map[string]devFileStamp{
+		"same.yaml":     {modTime: now, size: 10},
+		"modified.yaml": {modTime: now.Add(time.Second), size: 10},
+		"resized.yaml":  {modTime: now, size: 11},
+		"created.yaml":  {modTime: now, size: 10},
+	}
+
+	expected := []string{"created.yaml", "modified.yaml", "removed.yaml", "resized.yaml"}
+	if actual := devChangedFiles(previous, current); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, but got %v", expected, actual)
+	}
+	if actual := devChangedFiles(current, current); len(actual) != 0 {
+		t.Errorf("unexpected changed files: %v", actual)
+	}
+}
+
+func TestDevSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dev")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "definition"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := filepath.Join(dir, "definition", "pipe.yaml")
+	values := filepath.Join(dir, "values.json")
+	for _, file := range []string{template, values} {
+		if err := ioutil.WriteFile(file, []byte("{}"), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	previous, err := devSnapshot(dir, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(template, []byte("{\"kind\": \"PipelineTemplate\"}"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current, err := devSnapshot(dir, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if actual := devChangedFiles(previous, current); !reflect.DeepEqual(actual, []string{template}) {
+		t.Errorf("expected %s is changed, but got %v", template, actual)
+	}
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/otiszv/render/domain"
+	"github.com/otiszv/render/goutils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffValuesFile string
+	diffDir        string
+)
+
+var diffCmd = &cobra.Command{
+	Use:          "diff old.yaml new.yaml",
+	Short:        "compare two versions of template",
+	Long:         "compare two versions of pipeline template or task template semantically, and the rendered Jenkinsfile when values file is provided",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return diff(args[0], args[1], diffValuesFile, diffDir)
+	},
+}
+
+// renderValues values file used to render pipeline template
+type renderValues struct {
+	Arguments map[string]interface{} `json:"arguments"`
+	SCM       *domain.SCMInfo        `json:"scm"`
+}
+
+func diff(oldFile string, newFile string, valuesFile string, dir string) error {
+	oldKube, newKube := domain.Kubernete{}, domain.Kubernete{}
+	if err := oldKube.LoadFromFile(oldFile); err != nil {
+		return err
+	}
+	if err := newKube.LoadFromFile(newFile); err != nil {
+		return err
+	}
+	if oldKube.Kind.TemplateKind() != newKube.Kind.TemplateKind() {
+		return fmt.Errorf("could not compare %s with %s", oldKube.Kind, newKube.Kind)
+	}
+
+	catalog := domain.NewTemplateCatalog()
+	if dir != "" {
+		files, err := getFilelist(dir)
+		if err != nil {
+			return err
+		}
+		err = catalog.LoadFiles(files)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch oldKube.Kind.TemplateKind() {
+	case domain.KuberneteKindPipelineTaskTemplate:
+		if valuesFile != "" {
+			return errors.New("values file is only supported by pipeline template")
+		}
+		oldDefinition := domain.JenkinsPipelineTaskTemplateDefinition(oldKube)
+		oldSpec, err := oldDefinition.PipelineTaskTemplateSpec()
+		if err != nil {
+			return err
+		}
+		newDefinition := domain.JenkinsPipelineTaskTemplateDefinition(newKube)
+		newSpec, err := newDefinition.PipelineTaskTemplateSpec()
+		if err != nil {
+			return err
+		}
+		printChanges(domain.DiffTaskTemplateSpec(oldSpec, newSpec))
+		return nil
+	case domain.KuberneteKindPipelineTemplate:
+	default:
+		return fmt.Errorf("kind %s could not be compared", oldKube.Kind)
+	}
+
+	oldSpec, err := expandedPipelineTemplateSpec(oldKube, catalog)
+	if err != nil {
+		return err
+	}
+	newSpec, err := expandedPipelineTemplateSpec(newKube, catalog)
+	if err != nil {
+		return err
+	}
+	printChanges(domain.DiffPipelineTemplateSpec(oldSpec, newSpec))
+
+	if valuesFile == "" {
+		return nil
+	}
+
+	byts, err := ioutil.ReadFile(valuesFile)
+	if err != nil {
+		return err
+	}
+	values := renderValues{}
+	err = yaml.Unmarshal(byts, &values)
+	if err != nil {
+		return err
+	}
+
+	oldJenkinsfile, err := renderPipelineTemplateSpec(oldSpec, catalog, values)
+	if err != nil {
+		return fmt.Errorf("render %s error: %s", oldFile, err.Error())
+	}
+	newJenkinsfile, err := renderPipelineTemplateSpec(newSpec, catalog, values)
+	if err != nil {
+		return fmt.Errorf("render %s error: %s", newFile, err.Error())
+	}
+
+	fmt.Println()
+	fmt.Print(goutils.UnifiedDiff(oldJenkinsfile, newJenkinsfile, oldFile, newFile))
+	return nil
+}
+
+func expandedPipelineTemplateSpec(kube domain.Kubernete, catalog *domain.TemplateCatalog) (*domain.PipelineTemplateSpec, error) {
+	definition := domain.JenkinsPipelineTemplateDefinition(kube)
+	spec, err := definition.PipelineTemplateSpec()
+	if err != nil {
+		return nil, err
+	}
+	if !spec.IsExtended() {
+		return spec, nil
+	}
+	return spec.Expand(catalog.ResolvePipelineTemplate)
+}
+
+func renderPipelineTemplateSpec(spec *domain.PipelineTemplateSpec, catalog *domain.TemplateCatalog, values renderValues) (string, error) {
+	taskTemplates, err := catalog.ResolveTaskTemplates(spec)
+	if err != nil {
+		return "", err
+	}
+	return spec.RenderAndFormat(taskTemplates, values.Arguments, values.SCM)
+}
+
+func printChanges(changes []domain.TemplateChange) {
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+		return
+	}
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(
+		&diffValuesFile,
+		"values", "v", "", "provider the values file to render both templates, eg: arguments and scm",
+	)
+
+	diffCmd.Flags().StringVarP(
+		&diffDir,
+		"dir", "d", "", "provider the pipeline template repository directory that contains task templates and parent templates",
+	)
+
+	RootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"github.com/otiszv/render/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expandFile string
+	expandDir  string
+)
+
+var expandCmd = &cobra.Command{
+	Use:          "expand",
+	Short:        "print the fully expanded pipeline template",
+	Long:         "merge pipeline template with the templates it extends and print the result",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return expand(expandFile, expandDir)
+	},
+}
+
+func expand(file string, dir string) error {
+	if file == "" {
+		return errors.New("no file need to expand")
+	}
+
+	kube := domain.Kubernete{}
+	err := kube.LoadFromFile(file)
+	if err != nil {
+		return err
+	}
+	if kube.Kind.TemplateKind() != domain.KuberneteKindPipelineTemplate {
+		return fmt.Errorf("kind %s could not be expanded", kube.Kind)
+	}
+
+	catalog := domain.NewTemplateCatalog()
+	if dir != "" {
+		files, err := getFilelist(dir)
+		if err != nil {
+			return err
+		}
+		err = catalog.LoadFiles(files)
+		if err != nil {
+			return err
+		}
+	}
+
+	definition := domain.JenkinsPipelineTemplateDefinition(kube)
+	spec, err := definition.PipelineTemplateSpec()
+	if err != nil {
+		return err
+	}
+
+	expanded, err := spec.Expand(catalog.ResolvePipelineTemplate)
+	if err != nil {
+		return err
+	}
+
+	err = kube.SetSpec(expanded)
+	if err != nil {
+		return err
+	}
+
+	byts, err := yaml.Marshal(kube)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(byts))
+	return nil
+}
+
+func init() {
+	expandCmd.Flags().StringVarP(
+		&expandFile,
+		"file", "f", "", "provider the pipeline template file that want to be expanded",
+	)
+
+	expandCmd.Flags().StringVarP(
+		&expandDir,
+		"dir", "d", "", "provider the pipeline template repository directory that contains parent templates",
+	)
+
+	RootCmd.AddCommand(expandCmd)
+}
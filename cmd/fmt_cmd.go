@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/otiszv/render/domain"
+	"github.com/otiszv/render/formatter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtCheck            bool
+	fmtWrite            bool
+	fmtIndent           int
+	fmtTabs             bool
+	fmtMaxBlankLines    int
+	fmtNoSpaceBrace     bool
+	fmtTrailingNewline  bool
+	fmtLineEnding       string
+	fmtAlignAssignments bool
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [Jenkinsfile...]",
+	Short: "format jenkinsfiles",
+	Long: "format hand-written jenkinsfiles and print them, \"-\" means stdin. " +
+		"--check lists files that are not formatted, --write rewrites files with formatted contents",
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options, err := fmtOptions()
+		if err != nil {
+			return err
+		}
+		return format(args, options, fmtCheck, fmtWrite)
+	},
+}
+
+func fmtOptions() (formatter.Options, error) {
+	options := formatter.Options{
+		IndentSpaces:       fmtIndent,
+		UseTabs:            fmtTabs,
+		MaxBlankLines:      fmtMaxBlankLines,
+		NoSpaceBeforeBrace: fmtNoSpaceBrace,
+		TrailingNewline:    fmtTrailingNewline,
+		AlignAssignments:   fmtAlignAssignments,
+	}
+	switch fmtLineEnding {
+	case "lf":
+		options.LineEnding = formatter.LineEndingLF
+	case "crlf":
+		options.LineEnding = formatter.LineEndingCRLF
+	default:
+		return options, fmt.Errorf("line ending %s is not support, it should be lf or crlf", fmtLineEnding)
+	}
+	if fmtIndent <= 0 {
+		return options, fmt.Errorf("indent %d should be positive", fmtIndent)
+	}
+	if fmtMaxBlankLines < 0 {
+		return options, fmt.Errorf("max blank lines %d should not be negative", fmtMaxBlankLines)
+	}
+	return options, nil
+}
+
+func format(files []string, options formatter.Options, check bool, write bool) error {
+	if check && write {
+		return errors.New("--check and --write could not be used together")
+	}
+
+	unformatted := []string{}
+	for _, file := range files {
+		if !check && !write {
+			// formatted contents are printed while they are read
+			if err := printFormatted(file, options); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var (
+			byts []byte
+			err  error
+		)
+		if file == domain.StdinPath {
+			if write {
+				return errors.New("stdin could not be written")
+			}
+			byts, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			byts, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			return err
+		}
+
+		content := string(byts)
+		formatted := formatter.FormatWithOptions(content, options)
+		switch {
+		case check:
+			if formatted != content {
+				unformatted = append(unformatted, file)
+				fmt.Println(file)
+			}
+		case write:
+			if formatted == content {
+				continue
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(file, []byte(formatted), info.Mode()); err != nil {
+				return err
+			}
+			fmt.Println(file)
+		}
+	}
+
+	if len(unformatted) > 0 {
+		return fmt.Errorf("%d files are not formatted", len(unformatted))
+	}
+	return nil
+}
+
+func printFormatted(file string, options formatter.Options) error {
+	if file == domain.StdinPath {
+		return formatter.FormatTo(os.Stdout, os.Stdin, options)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return formatter.FormatTo(os.Stdout, f, options)
+}
+
+func init() {
+	fmtCmd.Flags().BoolVarP(&fmtCheck, "check", "c", false, "list files that are not formatted, and fail if there are any")
+	fmtCmd.Flags().BoolVarP(&fmtWrite, "write", "w", false, "rewrite files with formatted contents")
+	fmtCmd.Flags().IntVar(&fmtIndent, "indent", 4, "spaces of an indent level")
+	fmtCmd.Flags().BoolVar(&fmtTabs, "tabs", false, "indent with tabs instead of spaces")
+	fmtCmd.Flags().IntVar(&fmtMaxBlankLines, "max-blank-lines", 1, "max consecutive blank lines that are kept")
+	fmtCmd.Flags().BoolVar(&fmtNoSpaceBrace, "no-brace-space", false, "remove spaces before \"{\"")
+	fmtCmd.Flags().BoolVar(&fmtTrailingNewline, "trailing-newline", true, "end with exactly one line ending")
+	fmtCmd.Flags().StringVar(&fmtLineEnding, "line-ending", "lf", "line ending, one of lf|crlf")
+	fmtCmd.Flags().BoolVar(&fmtAlignAssignments, "align-env", false, "align \"=\" of entries in environment blocks")
+
+	RootCmd.AddCommand(fmtCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/formatter"
+)
+
+func TestFmtOptions(t *testing.T) {
+	defer func(indent, maxBlankLines int, lineEnding string) {
+		fmtIndent, fmtMaxBlankLines, fmtLineEnding = indent, maxBlankLines, lineEnding
+	}(fmtIndent, fmtMaxBlankLines, fmtLineEnding)
+
+	cases := []struct {
+		indent        int
+		maxBlankLines int
+		lineEnding    string
+		message       string
+	}{
+		{indent: 2, maxBlankLines: 1, lineEnding: "crlf"},
+		{indent: 2, maxBlankLines: 1, lineEnding: "cr", message: "line ending cr is not support"},
+		{indent: 0, maxBlankLines: 1, lineEnding: "lf", message: "indent 0 should be positive"},
+		{indent: 4, maxBlankLines: -1, lineEnding: "lf", message: "max blank lines -1 should not be negative"},
+	}
+	for _, c := range cases {
+		fmtIndent, fmtMaxBlankLines, fmtLineEnding = c.indent, c.maxBlankLines, c.lineEnding
+		options, err := fmtOptions()
+		if c.message == "" {
+			if err != nil || options.IndentSpaces != c.indent || options.LineEnding != formatter.LineEndingCRLF {
+				t.Errorf("unexpected options %#v, error: %v", options, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("expected error contains %q, but got %v", c.message, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fmt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	options := formatter.Options{IndentSpaces: 4, TrailingNewline: true}
+	formatted := "steps {\n    echo 'a'\n}\n"
+	files := map[string]string{
+		"formatted":   formatted,
+		"unformatted": "steps{\necho 'a'\n}",
+	}
+	paths := []string{}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	if err := format(paths, options, true, true); err == nil {
+		t.Errorf("--check and --write should not be used together")
+	}
+
+	err = format(paths, options, true, false)
+	if err == nil || err.Error() != "1 files are not formatted" {
+		t.Errorf("expected error of unformatted file, but got %v", err)
+	}
+	// files are not changed by check
+	if byts, _ := ioutil.ReadFile(filepath.Join(dir, "unformatted")); string(byts) != files["unformatted"] {
+		t.Errorf("file should not be changed by check: %q", byts)
+	}
+
+	if err := format(paths, options, false, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if byts, _ := ioutil.ReadFile(filepath.Join(dir, "unformatted")); string(byts) != formatted {
+		t.Errorf("file is not formatted by write: %q", byts)
+	}
+	if err := format(paths, options, true, false); err != nil {
+		t.Errorf("all files should be formatted, but got %v", err)
+	}
+
+	if err := format([]string{filepath.Join(dir, "not-exists")}, options, true, false); err == nil {
+		t.Errorf("expected error of file that does not exist")
+	}
+}
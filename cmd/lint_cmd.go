@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/otiszv/render/domain"
+	"github.com/otiszv/render/jenkinsfile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintConfigFile string
+	lintDir        string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [Jenkinsfile...]",
+	Short: "lint jenkinsfiles",
+	Long: "find problems that make jenkins reject the jenkinsfile, eg: empty steps, empty parallel, unbalanced braces and unknown directives. " +
+		"\"-\" means stdin, and the pipeline config is rendered and linted when --file is set",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lint(args, lintConfigFile, lintDir)
+	},
+}
+
+func lint(files []string, configFile string, dir string) error {
+	if len(files) == 0 && configFile == "" {
+		return errors.New("no file need to lint")
+	}
+
+	failed := false
+	printIssues := func(name string, issues []jenkinsfile.LintIssue) {
+		if len(issues) == 0 {
+			fmt.Printf("√\t %s\n", name)
+			return
+		}
+		failed = true
+		fmt.Printf("×\t %s\n", name)
+		for _, issue := range issues {
+			fmt.Printf("\t %s\n", issue)
+		}
+	}
+
+	for _, file := range files {
+		var (
+			byts []byte
+			err  error
+		)
+		if file == domain.StdinPath {
+			byts, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			byts, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			return err
+		}
+		printIssues(file, jenkinsfile.LintText(string(byts)))
+	}
+
+	if configFile != "" {
+		if dir == "" {
+			return errors.New("template repository directory is required to render pipeline config")
+		}
+		spec, catalog, err := loadPipelineConfig(configFile, dir)
+		if err != nil {
+			return err
+		}
+		pipeline, err := spec.Pipeline(catalog)
+		if err != nil {
+			return err
+		}
+		// problems like single parallel are hidden by rendering, so pipeline is linted before it is rendered
+		pipelineIssues := pipeline.Lint()
+		content, err := pipeline.RenderAndFormat()
+		if err != nil {
+			return err
+		}
+		printIssues(configFile, jenkinsfile.MergeLintIssues(pipelineIssues, jenkinsfile.LintText(content)))
+	}
+
+	if failed {
+		return errors.New("jenkinsfile lint is not pass")
+	}
+	return nil
+}
+
+func init() {
+	lintCmd.Flags().StringVarP(
+		&lintConfigFile,
+		"file", "f", "", "provider the pipeline config file that want to be rendered and linted",
+	)
+
+	lintCmd.Flags().StringVarP(
+		&lintDir,
+		"dir", "d", "", "provider the pipeline template repository directory to render pipeline config",
+	)
+
+	RootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/otiszv/render/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderFile    string
+	renderDir     string
+	renderBatch   string
+	renderWorkers int
+	renderWrite   bool
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "render pipeline config to jenkinsfile",
+	Long: "render pipeline config to jenkinsfile with the pipeline template and task templates in template repository. " +
+		"--batch renders all the entries of manifest concurrently, and reports whether their outputs are changed",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if renderBatch != "" {
+			if renderFile != "" {
+				return errors.New("--file and --batch could not be used together")
+			}
+			return renderBatchManifest(renderBatch, renderDir, renderWorkers, renderWrite)
+		}
+		return render(renderFile, renderDir)
+	},
+}
+
+func render(file string, dir string) error {
+	if file == "" {
+		return errors.New("no file need to render")
+	}
+	if dir == "" {
+		return errors.New("template repository directory is required")
+	}
+
+	jenkinsfile, err := renderPipelineConfig(file, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Print(jenkinsfile)
+	return nil
+}
+
+func renderBatchManifest(file string, dir string, workers int, write bool) error {
+	if dir == "" {
+		return errors.New("template repository directory is required")
+	}
+
+	manifest, err := domain.LoadBatchManifest(file)
+	if err != nil {
+		return err
+	}
+	files, err := getFilelist(dir)
+	if err != nil {
+		return err
+	}
+	catalog := domain.NewTemplateCatalog()
+	if err = catalog.LoadFiles(files); err != nil {
+		return err
+	}
+
+	rendered, changed, failed := 0, 0, 0
+	for _, result := range manifest.Render(catalog, workers) {
+		name := result.Entry.Name
+		if result.Err != nil {
+			failed++
+			fmt.Printf("×\t %s\n\t %s\n", name, result.Err.Error())
+			continue
+		}
+
+		rendered++
+		output := manifest.OutputFile(result.Entry)
+		switch {
+		case output == "":
+			fmt.Printf("√\t %s\n", name)
+		case !result.Changed:
+			fmt.Printf("√\t %s\n\t %s is unchanged\n", name, output)
+		case write:
+			changed++
+			if err := ioutil.WriteFile(output, []byte(result.Jenkinsfile), 0644); err != nil {
+				return err
+			}
+			fmt.Printf("~\t %s\n\t %s is updated\n", name, output)
+		default:
+			changed++
+			fmt.Printf("~\t %s\n\t %s is changed\n", name, output)
+		}
+	}
+
+	fmt.Printf("\n%d rendered, %d changed, %d failed\n", rendered, changed, failed)
+	if failed > 0 {
+		return errors.New("batch render is not pass")
+	}
+	return nil
+}
+
+// renderPipelineConfig render pipeline config file with templates in dir
+func renderPipelineConfig(file string, dir string) (string, error) {
+	spec, catalog, err := loadPipelineConfig(file, dir)
+	if err != nil {
+		return "", err
+	}
+	return spec.Render(catalog)
+}
+
+// loadPipelineConfig load pipeline config in file and the catalog of templates in dir that it could be rendered with,
+// file could have multi documents, but only one of them should be pipeline config
+func loadPipelineConfig(file string, dir string) (*domain.PipelineConfigSpec, *domain.TemplateCatalog, error) {
+	docs, err := domain.LoadDocuments(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	configs := []*domain.KuberneteDocument{}
+	for _, doc := range docs {
+		if doc.Kube.Kind == domain.KuberneteKindPipelineConfig {
+			configs = append(configs, doc)
+		}
+	}
+	switch {
+	case len(configs) == 0 && len(docs) == 1:
+		return nil, nil, fmt.Errorf("kind %s could not be rendered", docs[0].Kube.Kind)
+	case len(configs) == 0:
+		return nil, nil, fmt.Errorf("there is no %s in %s", domain.KuberneteKindPipelineConfig, file)
+	case len(configs) > 1:
+		return nil, nil, fmt.Errorf("there are %d %s in %s, only one could be rendered", len(configs), domain.KuberneteKindPipelineConfig, file)
+	}
+	doc := configs[0]
+	err = doc.Kube.ValidateDefinition()
+	if err != nil {
+		return nil, nil, doc.Locate(err)
+	}
+
+	files, err := getFilelist(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	catalog := domain.NewTemplateCatalog()
+	err = catalog.LoadFiles(files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	definition := domain.JenkinsPipelineConfigDefinition(*doc.Kube)
+	spec, err := definition.PipelineConfigSpec()
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec, catalog, nil
+}
+
+func init() {
+	renderCmd.Flags().StringVarP(
+		&renderFile,
+		"file", "f", "", "provider the pipeline config file that want to be rendered",
+	)
+
+	renderCmd.Flags().StringVarP(
+		&renderDir,
+		"dir", "d", "", "provider the pipeline template repository directory that contains pipeline templates and task templates",
+	)
+
+	renderCmd.Flags().StringVar(
+		&renderBatch,
+		"batch", "", "provider the manifest file whose entries are rendered together",
+	)
+
+	renderCmd.Flags().IntVarP(
+		&renderWorkers,
+		"workers", "j", 0, "number of entries that are rendered concurrently in batch, 0 means the number of CPUs",
+	)
+
+	renderCmd.Flags().BoolVarP(
+		&renderWrite,
+		"write", "w", false, "rewrite changed outputs of batch entries with rendered jenkinsfile",
+	)
+
+	RootCmd.AddCommand(renderCmd)
+}
@@ -3,12 +3,23 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/goutils"
 	"github.com/spf13/cobra"
 )
 
 var cfgFile string
 
+var verbose bool
+
+var (
+	requiredLocales []string
+	defaultLocale   string
+	localeFallbacks []string
+)
+
 var (
 	version   string
 	buildDate string
@@ -41,7 +52,57 @@ func Execute(_version, _buildDate string) {
 func init() {
 	cobra.OnInitialize(initConfig)
 	RootCmd.AddCommand(versionCmd)
+
+	RootCmd.PersistentFlags().BoolVar(
+		&verbose, "verbose", false,
+		"print diagnostic messages of rendering to stderr",
+	)
+
+	RootCmd.PersistentFlags().StringSliceVar(
+		&requiredLocales, "required-locales", []string{common.LocaleZHCN, common.LocaleEN},
+		"locales that display names of templates and arguments should be set in",
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&defaultLocale, "default-locale", common.LocaleEN,
+		"locale that will be tried at last when value of a locale is missing",
+	)
+	RootCmd.PersistentFlags().StringArrayVar(
+		&localeFallbacks, "locale-fallback", []string{},
+		"locales that will be tried in order when value of a locale is missing, eg: de-AT=de,en",
+	)
 }
 
 func initConfig() {
+	if verbose {
+		goutils.Logger.SetOutput(os.Stderr)
+	}
+
+	config, err := parseLocaleConfig(requiredLocales, defaultLocale, localeFallbacks)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(-1)
+	}
+	common.SetLocaleConfig(config)
+}
+
+// parseLocaleConfig parse locale flags, fallbacks are in format of <locale>=<fallback>[,<fallback>...]
+func parseLocaleConfig(required []string, defaultLocale string, fallbacks []string) (common.LocaleConfig, error) {
+	config := common.LocaleConfig{
+		Required:  required,
+		Fallbacks: map[string][]string{},
+		Default:   defaultLocale,
+	}
+	for _, fallback := range fallbacks {
+		segments := strings.SplitN(fallback, "=", 2)
+		if len(segments) != 2 || strings.TrimSpace(segments[0]) == "" || strings.TrimSpace(segments[1]) == "" {
+			return config, fmt.Errorf("invalid locale fallback %q, should be <locale>=<fallback>[,<fallback>...]", fallback)
+		}
+		locale := strings.TrimSpace(segments[0])
+		for _, l := range strings.Split(segments[1], ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				config.Fallbacks[locale] = append(config.Fallbacks[locale], l)
+			}
+		}
+	}
+	return config, nil
 }
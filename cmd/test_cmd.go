@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otiszv/render/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	testDir    string
+	testRun    string
+	testUpdate bool
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "run test cases of templates",
+	Long: "render test cases in *.test.yaml files of template repository, and compare with expected jenkinsfile or errors. " +
+		"golden files of expected jenkinsfile are rewritten with rendered jenkinsfile when --update is set",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return test(testDir, testRun, testUpdate)
+	},
+}
+
+func test(dir string, run string, update bool) error {
+	if dir == "" {
+		return errors.New("template repository directory is required")
+	}
+
+	files, err := getFilelist(dir)
+	if err != nil {
+		return err
+	}
+	catalog := domain.NewTemplateCatalog()
+	if err = catalog.LoadFiles(files); err != nil {
+		return err
+	}
+
+	suiteFiles, err := getTestSuiteFilelist(dir)
+	if err != nil {
+		return err
+	}
+	if len(suiteFiles) == 0 {
+		return fmt.Errorf("no test cases in %s, they should be in *.test.yaml files", dir)
+	}
+
+	passed, failed, updated := 0, 0, 0
+	for _, file := range suiteFiles {
+		suite, err := domain.LoadTemplateTestSuite(file)
+		if err != nil {
+			fmt.Printf("×\t %s\n\t %s\n", file, err.Error())
+			failed++
+			continue
+		}
+
+		for i := range suite.Cases {
+			testCase := &suite.Cases[i]
+			if run != "" && !strings.Contains(testCase.Name, run) {
+				continue
+			}
+			name := fmt.Sprintf("%s/%s", file, testCase.Name)
+
+			result := suite.Run(catalog, testCase)
+			if update && len(testCase.Errors) == 0 && result.Err == nil && result.Expected != result.Jenkinsfile {
+				golden := suite.GoldenFile(testCase)
+				if err := ioutil.WriteFile(golden, []byte(result.Jenkinsfile), 0644); err != nil {
+					return err
+				}
+				// golden file is rewritten, only the other failures are left
+				result = suite.Run(catalog, testCase)
+				fmt.Printf("~\t %s\n\t updated %s\n", name, golden)
+				updated++
+			}
+
+			if result.Passed() {
+				fmt.Printf("√\t %s\n", name)
+				passed++
+				continue
+			}
+			failed++
+			fmt.Printf("×\t %s\n", name)
+			for _, failure := range result.Failures {
+				fmt.Printf("\t %s\n", failure)
+			}
+			if result.Diff != "" {
+				fmt.Print(result.Diff)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed, %d updated\n", passed, failed, updated)
+	if failed > 0 {
+		return errors.New("template test is not pass")
+	}
+	return nil
+}
+
+func getTestSuiteFilelist(dir string) ([]string, error) {
+	pathes := []string{}
+	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if f == nil {
+			return err
+		}
+		if f.IsDir() || !domain.IsTemplateTestFile(f.Name()) {
+			return nil
+		}
+		pathes = append(pathes, path)
+		return nil
+	})
+	if err != nil {
+		return []string{}, err
+	}
+	return pathes, nil
+}
+
+func init() {
+	testCmd.Flags().StringVarP(
+		&testDir,
+		"dir", "d", "", "provider the pipeline template repository directory that contains templates and test cases",
+	)
+
+	testCmd.Flags().StringVarP(
+		&testRun,
+		"run", "r", "", "only run test cases whose name contains it",
+	)
+
+	testCmd.Flags().BoolVarP(
+		&testUpdate,
+		"update", "u", false, "rewrite golden files with rendered jenkinsfile",
+	)
+
+	RootCmd.AddCommand(testCmd)
+}
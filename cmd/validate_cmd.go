@@ -23,6 +23,8 @@ var (
 	files []string
 
 	dir string
+
+	validateOutput string
 )
 var validateDefinitionCmd = &cobra.Command{
 	Use:          "definition",
@@ -35,7 +37,9 @@ var validateDefinitionCmd = &cobra.Command{
 }
 
 func validate(files []string, dir string) error {
-	errs := common.Errors{}
+	if err := checkOutputFormat(validateOutput); err != nil {
+		return err
+	}
 
 	if dir != "" {
 		var err error
@@ -49,32 +53,149 @@ func validate(files []string, dir string) error {
 		return errors.New("no file need to validate")
 	}
 
+	report := newValidateReport()
+	docs := map[string]*domain.KuberneteDocument{}
 	for _, file := range files {
-		kube := domain.Kubernete{}
-		err := kube.LoadFromFile(file)
+		loaded, err := domain.LoadDocuments(file)
 		if err != nil {
-			fmt.Printf("×\t %s\n", file)
-			fmt.Printf("\t %s\n", err.Error())
-			errs = append(errs, err)
+			report.entry(file, file).fail(err)
 		}
 
-		err = kube.ValidateDefinition()
-		if err != nil {
-			fmt.Printf("×\t %s\n", file)
-			fmt.Printf("\t %s\n", err.Error())
-			errs = append(errs, err)
+		for _, doc := range loaded {
+			entry := report.entry(doc.Name(), doc.File)
+			entry.Kind = string(doc.Kube.Kind)
+			entry.fail(doc.Locate(doc.Kube.ValidateDefinition()))
+			docs[doc.Name()] = doc
 		}
+	}
 
-		fmt.Printf("√\t %s\n", file)
+	validateCrossReferences(docs, report)
+
+	err := report.print(os.Stdout, validateOutput)
+	if err != nil {
+		return err
 	}
 
-	if len(errs) > 0 {
+	if report.failed() {
 		return errors.New("template definition validation is not pass")
 	}
-
 	return nil
 }
 
+// validateCrossReferences expand pipeline templates and validate their arguments bindings against task templates in the same file list,
+// and the pipeline templates that pipeline configs refer should be in the same file list
+func validateCrossReferences(docs map[string]*domain.KuberneteDocument, report *validateReport) {
+	catalog := domain.NewTemplateCatalog()
+	for name, doc := range docs {
+		catalog.Add(doc.Kube, name)
+	}
+
+	if err := catalog.ValidateVersions(); err != nil {
+		report.entry(reportEntryVersions, "").fail(err)
+	}
+
+	for _, item := range catalog.PipelineTemplates {
+		spec, err := item.PipelineTemplateSpec()
+		if err != nil {
+			continue
+		}
+
+		entry := report.entry(item.File, docs[item.File].File)
+
+		// positions are unknown after expanded
+		extended := spec.IsExtended()
+		if extended {
+			spec, err = spec.Expand(catalog.ResolvePipelineTemplate)
+			if err != nil {
+				entry.fail(err)
+				continue
+			}
+		}
+
+		taskTemplates, err := catalog.ResolveTaskTemplates(spec)
+		if err != nil {
+			entry.skip(fmt.Sprintf("skip to validate bindings, %s", err.Error()))
+			continue
+		}
+
+		err = spec.ValidateBindings(taskTemplates)
+		if err != nil && !extended {
+			err = docs[item.File].Locate(common.WithPath(err, "spec"))
+		}
+		entry.fail(err)
+
+		err = spec.ValidateUnusedArguments()
+		if err != nil && !extended {
+			err = docs[item.File].Locate(common.WithPath(err, "spec"))
+		}
+		entry.warn(err)
+
+		// scm of pipeline is unknown until pipeline config, see below
+		if !spec.IsWithSCM() {
+			err = spec.ValidateGlobalVars(taskTemplates, nil)
+			if err != nil && !extended {
+				err = docs[item.File].Locate(common.WithPath(err, "spec"))
+			}
+			entry.warn(err)
+		}
+	}
+
+	// global vars could be registered by platforms at runtime
+	for _, item := range catalog.TaskTemplates {
+		definition := domain.JenkinsPipelineTaskTemplateDefinition(*item.Kube)
+		spec, err := definition.PipelineTaskTemplateSpec()
+		if err != nil {
+			continue
+		}
+		err = spec.ValidateGlobalVarRefs(domain.DefaultGlobalVarRegistry)
+		report.entry(item.File, docs[item.File].File).warn(docs[item.File].Locate(common.WithPath(err, "spec")))
+	}
+
+	for name, doc := range docs {
+		if doc.Kube.Kind != domain.KuberneteKindPipelineConfig || doc.Kube.Spec == nil {
+			continue
+		}
+		definition := domain.JenkinsPipelineConfigDefinition(*doc.Kube)
+		spec, err := definition.PipelineConfigSpec()
+		if err != nil {
+			continue
+		}
+		_, err = catalog.FindPipelineTemplate(spec.Template.Name, spec.Template.Version)
+		if err != nil {
+			report.entry(name, doc.File).fail(doc.Locate(common.WithPath(err, "spec.template")))
+			continue
+		}
+
+		err = validateConfigGlobalVars(catalog, spec)
+		report.entry(name, doc.File).warn(doc.Locate(common.MapPath(err, func(string) string {
+			return "spec.scm"
+		})))
+	}
+}
+
+// validateConfigGlobalVars validate global vars that the pipeline template of config refers are available for scm of config
+func validateConfigGlobalVars(catalog *domain.TemplateCatalog, config *domain.PipelineConfigSpec) error {
+	template, err := catalog.ResolvePipelineTemplate(config.Template.Name, config.Template.Version)
+	if err != nil {
+		return nil
+	}
+	if template.IsExtended() {
+		if template, err = template.Expand(catalog.ResolvePipelineTemplate); err != nil {
+			return nil
+		}
+	}
+	// templates without scm are validated by themselves
+	if !template.IsWithSCM() {
+		return nil
+	}
+
+	taskTemplates, err := catalog.ResolveTaskTemplates(template)
+	if err != nil {
+		return nil
+	}
+	return template.ValidateGlobalVars(taskTemplates, config.SCM)
+}
+
 func getFilelist(dir string) ([]string, error) {
 	var (
 		err error
@@ -95,7 +216,11 @@ func getFilelist(dir string) ([]string, error) {
 			return nil
 		}
 
-		if !strings.HasSuffix(f.Name(), ".yaml") {
+		if !strings.HasSuffix(f.Name(), ".yaml") && !strings.HasSuffix(f.Name(), ".yml") && !strings.HasSuffix(f.Name(), ".json") {
+			return nil
+		}
+		// test cases of templates, see test command
+		if domain.IsTemplateTestFile(f.Name()) {
 			return nil
 		}
 
@@ -113,7 +238,7 @@ func init() {
 
 	validateDefinitionCmd.Flags().StringArrayVarP(
 		&files,
-		"file", "f", []string{}, "provider the file path that want to be validate",
+		"file", "f", []string{}, "provider the file path that want to be validate, \"-\" means stdin",
 	)
 
 	validateDefinitionCmd.Flags().StringVarP(
@@ -121,6 +246,11 @@ func init() {
 		"dir", "d", "", "provider the pipeline template repository directory that want to be validate",
 	)
 
+	validateCmd.PersistentFlags().StringVarP(
+		&validateOutput,
+		"output", "o", outputText, "output format, one of text|json|junit|sarif|github",
+	)
+
 	validateCmd.AddCommand(validateDefinitionCmd)
 	RootCmd.AddCommand(validateCmd)
 }
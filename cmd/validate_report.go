@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+// output formats of validate commands
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputJUnit  = "junit"
+	outputSARIF  = "sarif"
+	outputGitHub = "github"
+)
+
+// reportEntryVersions entry for errors of versions between templates
+const reportEntryVersions = "versions"
+
+// validateReport results of all validated documents, in the order they are validated
+type validateReport struct {
+	Entries []*reportEntry `json:"entries"`
+
+	index map[string]*reportEntry
+}
+
+type reportEntry struct {
+	// Name name of document, see domain.KuberneteDocument.Name
+	Name string `json:"name"`
+	// File path of the file that contains document, empty for entries that are not files
+	File    string        `json:"file,omitempty"`
+	Kind    string        `json:"kind,omitempty"`
+	Passed  bool          `json:"passed"`
+	Skipped []string      `json:"skipped,omitempty"`
+	Errors  []reportError `json:"errors,omitempty"`
+	// Warnings problems that do not fail validation
+	Warnings []reportError `json:"warnings,omitempty"`
+}
+
+type reportError struct {
+	Code     string `json:"code"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Argument string `json:"argument,omitempty"`
+	Task     string `json:"task,omitempty"`
+	Message  string `json:"message"`
+}
+
+func newValidateReport() *validateReport {
+	return &validateReport{
+		Entries: []*reportEntry{},
+		index:   map[string]*reportEntry{},
+	}
+}
+
+// entry get or create entry by name
+func (report *validateReport) entry(name string, file string) *reportEntry {
+	if entry, ok := report.index[name]; ok {
+		return entry
+	}
+	entry := &reportEntry{Name: name, File: file, Passed: true, Errors: []reportError{}}
+	report.Entries = append(report.Entries, entry)
+	report.index[name] = entry
+	return entry
+}
+
+func (report *validateReport) failed() bool {
+	for _, entry := range report.Entries {
+		if !entry.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+func (entry *reportEntry) fail(err error) {
+	if err == nil {
+		return
+	}
+	entry.Passed = false
+	entry.Errors = append(entry.Errors, flattenReportErrors(err)...)
+}
+
+// warn add errors as warnings, entry is still passed
+func (entry *reportEntry) warn(err error) {
+	if err == nil {
+		return
+	}
+	entry.Warnings = append(entry.Warnings, flattenReportErrors(err)...)
+}
+
+func (entry *reportEntry) skip(reason string) {
+	entry.Skipped = append(entry.Skipped, reason)
+}
+
+func flattenReportErrors(err error) []reportError {
+	res := []reportError{}
+	for _, item := range common.FlattenErrors(err) {
+		typed, ok := item.(common.Error)
+		if !ok {
+			res = append(res, reportError{Code: "Error", Message: item.Error()})
+			continue
+		}
+		res = append(res, reportError{
+			Code:     typed.Code,
+			Path:     typed.Path,
+			Line:     typed.Line,
+			Column:   typed.Column,
+			Argument: typed.Argument,
+			Task:     typed.Task,
+			Message:  typed.Message,
+		})
+	}
+	return res
+}
+
+func checkOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputJUnit, outputSARIF, outputGitHub:
+		return nil
+	}
+	return fmt.Errorf("output format %s is not support, it should be one of text|json|junit|sarif|github", format)
+}
+
+func (report *validateReport) print(w io.Writer, format string) error {
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
+	switch format {
+	case outputText:
+		return report.printText(w)
+	case outputJSON:
+		return report.printJSON(w)
+	case outputJUnit:
+		return report.printJUnit(w)
+	case outputSARIF:
+		return report.printSARIF(w)
+	case outputGitHub:
+		return report.printGitHub(w)
+	}
+	return nil
+}
+
+func (err reportError) String() string {
+	location := err.Path
+	if err.Line > 0 {
+		location = fmt.Sprintf("%s(%d:%d)", err.Path, err.Line, err.Column)
+	}
+	if location == "" {
+		return fmt.Sprintf("%s: %s", err.Code, err.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", err.Code, location, err.Message)
+}
+
+func (report *validateReport) printText(w io.Writer) error {
+	for _, entry := range report.Entries {
+		status := "√"
+		if !entry.Passed {
+			status = "×"
+		}
+		fmt.Fprintf(w, "%s\t %s\n", status, entry.Name)
+		for _, err := range entry.Errors {
+			fmt.Fprintf(w, "\t %s\n", err)
+		}
+		for _, err := range entry.Warnings {
+			fmt.Fprintf(w, "\t warning: %s\n", err)
+		}
+		for _, reason := range entry.Skipped {
+			fmt.Fprintf(w, "-\t %s\n", entry.Name)
+			fmt.Fprintf(w, "\t %s\n", reason)
+		}
+	}
+	return nil
+}
+
+func (report *validateReport) printJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+func (report *validateReport) printJUnit(w io.Writer) error {
+	suite := junitTestSuite{Name: "validate definition", Cases: []junitTestCase{}}
+	for _, entry := range report.Entries {
+		output := append([]string{}, entry.Skipped...)
+		for _, err := range entry.Warnings {
+			output = append(output, "warning: "+err.String())
+		}
+		testCase := junitTestCase{
+			Name:      entry.Name,
+			ClassName: entry.Kind,
+			Failures:  []junitFailure{},
+			SystemOut: strings.Join(output, "\n"),
+		}
+		for _, err := range entry.Errors {
+			testCase.Failures = append(testCase.Failures, junitFailure{Type: err.Code, Message: err.Message, Content: err.String()})
+		}
+		suite.Tests++
+		if !entry.Passed {
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	suites := junitTestSuites{
+		Name:     "jenkinsfilext",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+	byts, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s%s\n", xml.Header, byts)
+	return nil
+}
+
+// printSARIF print report as SARIF 2.1.0, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+func (report *validateReport) printSARIF(w io.Writer) error {
+	type sarifMap = map[string]interface{}
+
+	rules := []sarifMap{}
+	ruleIDs := map[string]struct{}{}
+	results := []sarifMap{}
+	for _, entry := range report.Entries {
+		location := sarifMap{}
+		if entry.File != "" {
+			location["physicalLocation"] = sarifMap{
+				"artifactLocation": sarifMap{"uri": entry.File},
+			}
+		}
+
+		type problem struct {
+			reportError
+			level string
+		}
+		problems := []problem{}
+		for _, err := range entry.Errors {
+			problems = append(problems, problem{err, "error"})
+		}
+		for _, err := range entry.Warnings {
+			problems = append(problems, problem{err, "warning"})
+		}
+
+		for _, err := range problems {
+			if _, ok := ruleIDs[err.Code]; !ok {
+				ruleIDs[err.Code] = struct{}{}
+				rules = append(rules, sarifMap{"id": err.Code})
+			}
+
+			result := sarifMap{
+				"ruleId":  err.Code,
+				"level":   err.level,
+				"message": sarifMap{"text": strings.TrimSpace(err.Path + " " + err.Message)},
+			}
+			if entry.File != "" {
+				physical := sarifMap{"artifactLocation": sarifMap{"uri": entry.File}}
+				if err.Line > 0 {
+					physical["region"] = sarifMap{"startLine": err.Line, "startColumn": err.Column}
+				}
+				errLocation := sarifMap{"physicalLocation": physical}
+				if err.Path != "" {
+					errLocation["logicalLocations"] = []sarifMap{{"fullyQualifiedName": err.Path}}
+				}
+				result["locations"] = []sarifMap{errLocation}
+			}
+			results = append(results, result)
+		}
+
+		for _, reason := range entry.Skipped {
+			result := sarifMap{
+				"ruleId":  "Skipped",
+				"level":   "note",
+				"message": sarifMap{"text": reason},
+			}
+			if len(location) > 0 {
+				result["locations"] = []sarifMap{location}
+			}
+			results = append(results, result)
+		}
+	}
+
+	log := sarifMap{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []sarifMap{{
+			"tool": sarifMap{
+				"driver": sarifMap{
+					"name":  "jenkinsfilext",
+					"rules": rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+// printGitHub print report as workflow commands of github actions, they are shown as annotations
+func (report *validateReport) printGitHub(w io.Writer) error {
+	for _, entry := range report.Entries {
+		for _, err := range entry.Errors {
+			printGitHubAnnotation(w, "error", entry, err)
+		}
+		for _, err := range entry.Warnings {
+			printGitHubAnnotation(w, "warning", entry, err)
+		}
+		for _, reason := range entry.Skipped {
+			if entry.File == "" {
+				fmt.Fprintf(w, "::notice::%s\n", escapeGitHubData(reason))
+				continue
+			}
+			fmt.Fprintf(w, "::notice file=%s::%s\n", escapeGitHubProperty(entry.File), escapeGitHubData(reason))
+		}
+	}
+	return nil
+}
+
+// printGitHubAnnotation print err as command, eg: error, warning
+func printGitHubAnnotation(w io.Writer, command string, entry *reportEntry, err reportError) {
+	properties := []string{}
+	if entry.File != "" {
+		properties = append(properties, "file="+escapeGitHubProperty(entry.File))
+	}
+	if err.Line > 0 {
+		properties = append(properties, fmt.Sprintf("line=%d", err.Line), fmt.Sprintf("col=%d", err.Column))
+	}
+	properties = append(properties, "title="+escapeGitHubProperty(err.Code))
+	message := err.Message
+	if err.Path != "" {
+		message = err.Path + ": " + message
+	}
+	fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(properties, ","), escapeGitHubData(message))
+}
+
+func escapeGitHubData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+func escapeGitHubProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+func newTestValidateReport() *validateReport {
+	report := newValidateReport()
+	report.entry("pipe.yaml", "definition/pipe.yaml").fail(common.Errors{
+		common.Error{Code: common.CodeTemplateError, Message: "agent is required", Path: "spec.agent", Line: 3, Column: 5},
+		errors.New("line1\nline2"),
+	})
+	report.entry("pipe.yaml", "definition/pipe.yaml").warn(common.Error{Code: common.CodeValidateError, Message: "argument a, b is not used"})
+	report.entry("clone.yaml", "definition/clone.yaml").skip("skip to validate bindings")
+	report.entry(reportEntryVersions, "").fail(common.NewTemplateDefinitionError("build v1.0.0 is duplicated", nil))
+	return report
+}
+
+func TestPrintGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestValidateReport().print(&buf, outputGitHub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "::error file=definition/pipe.yaml,line=3,col=5,title=TemplateDefinitionError::spec.agent: agent is required\n" +
+		"::error file=definition/pipe.yaml,title=Error::line1%0Aline2\n" +
+		"::warning file=definition/pipe.yaml,title=ValidateError::argument a, b is not used\n" +
+		"::notice file=definition/clone.yaml::skip to validate bindings\n" +
+		"::error title=TemplateDefinitionError::build v1.0.0 is duplicated\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+
+	if escaped := escapeGitHubProperty("a:b,c%\n"); escaped != "a%3Ab%2Cc%25%0A" {
+		t.Errorf("unexpected escaped property: %s", escaped)
+	}
+}
+
+func TestPrintJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestValidateReport().print(&buf, outputJUnit); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	suites := junitTestSuites{}
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid junit xml: %v\n%s", err, buf.String())
+	}
+	if suites.Tests != 3 || suites.Failures != 2 || len(suites.Suites) != 1 {
+		t.Fatalf("unexpected suites: %+v", suites)
+	}
+	cases := suites.Suites[0].Cases
+	if len(cases[0].Failures) != 2 || cases[0].Failures[0].Type != common.CodeTemplateError || cases[0].Failures[0].Content != "TemplateDefinitionError: spec.agent(3:5): agent is required" {
+		t.Errorf("unexpected failures: %+v", cases[0].Failures)
+	}
+	if cases[0].SystemOut != "warning: ValidateError: argument a, b is not used" {
+		t.Errorf("unexpected system out: %s", cases[0].SystemOut)
+	}
+	if len(cases[1].Failures) != 0 || cases[1].SystemOut != "skip to validate bindings" {
+		t.Errorf("unexpected skipped case: %+v", cases[1])
+	}
+}
+
+func TestPrintSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestValidateReport().print(&buf, outputSARIF); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	log := struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid sarif: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected sarif: %s", buf.String())
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Errorf("rules should be unique, but got %+v", run.Tool.Driver.Rules)
+	}
+	levels := []string{}
+	for _, result := range run.Results {
+		levels = append(levels, result.RuleID+":"+result.Level)
+	}
+	expected := []string{"TemplateDefinitionError:error", "Error:error", "ValidateError:warning", "Skipped:note", "TemplateDefinitionError:error"}
+	if len(levels) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, levels)
+	}
+	for i := range expected {
+		if levels[i] != expected[i] {
+			t.Errorf("expected %v, but got %v", expected, levels)
+			break
+		}
+	}
+
+	first := run.Results[0].Locations[0].PhysicalLocation
+	if first.ArtifactLocation.URI != "definition/pipe.yaml" || first.Region == nil || first.Region.StartLine != 3 || first.Region.StartColumn != 5 {
+		t.Errorf("unexpected location: %+v", first)
+	}
+	// errors that are not in files have no location
+	if len(run.Results[4].Locations) != 0 {
+		t.Errorf("unexpected locations: %+v", run.Results[4].Locations)
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	if err := newTestValidateReport().print(&bytes.Buffer{}, "xml"); err == nil {
+		t.Errorf("expected error of unknown format")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/goutils"
 )
 
 type ArgSections []ArgSection
@@ -20,6 +21,36 @@ func (argSections ArgSections) AllArgItems() []ArgItem {
 	return allArgItems
 }
 
+// ValidateDefinition validate definition of all arguments, paths of errors are relative to sections, eg: [0].items[1].schema
+func (argSections ArgSections) ValidateDefinition() error {
+	errs := common.Errors{}
+	for i, section := range argSections {
+		for j, argItem := range section.Items {
+			if err := argItem.ValidateDefinition(); err != nil {
+				err = common.WithArgument(err, argItem.Name)
+				errs = append(errs, common.WithPath(err, fmt.Sprintf("[%d].items[%d]", i, j)))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ArgPath path of argument relative to sections, eg: [0].items[1]
+func (argSections ArgSections) ArgPath(name string) string {
+	for i, section := range argSections {
+		for j, argItem := range section.Items {
+			if argItem.Name == name {
+				return fmt.Sprintf("[%d].items[%d]", i, j)
+			}
+		}
+	}
+	return ""
+}
+
 type ArgSection struct {
 	DisplayName common.MulitLangValue `json:"displayName" mapstructure:"displayName" yaml:"displayName"`
 	Items       []ArgItem             `json:"items"`
@@ -51,27 +82,28 @@ func (arg *ArgItem) GetImplementor() IArgItem {
 func (arg *ArgItem) ValidateDefinition() error {
 	//TODO
 	if strings.TrimSpace(arg.Name) == "" {
-		return common.NewTemplateDefinitionError("name should not be empty", nil)
+		return common.WithPath(common.NewTemplateDefinitionError("name should not be empty", nil), "name")
 	}
 
 	if arg.Schema == nil {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("%s.schema is required", arg.Name), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("%s.schema is required", arg.Name), nil), "schema")
 	}
 	if _, ok := ArgItemImplementors[arg.Schema.Type]; !ok {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("%s.schema.type=%s is not support now", arg.Name, arg.Schema.Type), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("%s.schema.type=%s is not support now", arg.Name, arg.Schema.Type), nil), "schema.type")
 	}
 
 	if arg.DisplayInfo == nil {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("%s.display is required", arg.Name), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("%s.display is required", arg.Name), nil), "display")
 	}
 	if arg.DisplayInfo.Type == "" {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("%s.display.type is required", arg.Name), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("%s.display.type is required", arg.Name), nil), "display.type")
 	}
-	if arg.DisplayInfo.Name.ZH_CN == "" {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("%s.display.Name.zh-CN is required", arg.Name), nil)
+	if err := arg.DisplayInfo.Name.ValidateRequired(fmt.Sprintf("%s.display.Name", arg.Name)); err != nil {
+		return common.WithPath(err, "display.name")
 	}
-	if arg.DisplayInfo.Name.EN == "" {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("%s.display.Name.en is required", arg.Name), nil)
+
+	if err := arg.Relation.ValidateDefinition(); err != nil {
+		return common.WithPath(err, "relation")
 	}
 
 	implementor := arg.GetImplementor()
@@ -95,7 +127,7 @@ func (arg *ArgItem) ValidateValue(value interface{}) error {
 
 func (arg *ArgItem) IsMeaningful(argumentsValues map[string]interface{}) bool {
 	meaningful := arg.Relation.IsMathcShowAction(argumentsValues)
-	fmt.Printf("arg `%s` meaningful = %t \n", arg.Name, meaningful)
+	goutils.Logger.Printf("arg `%s` meaningful = %t \n", arg.Name, meaningful)
 	return meaningful
 }
 
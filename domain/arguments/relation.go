@@ -0,0 +1,181 @@
+package arguments
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+// RelationDependencies return the arguments that each argument's relation depends on.
+// references to reserved scopes which start with `_` (eg: `_system_`) are ignored,
+// references to unknown arguments are returned as unknown, keyed by argument name.
+func (argSections ArgSections) RelationDependencies() (deps map[string][]string, unknown map[string][]string) {
+	deps = map[string][]string{}
+	unknown = map[string][]string{}
+
+	argItems := argSections.AllArgItems()
+	names := make(map[string]struct{}, len(argItems))
+	for _, argItem := range argItems {
+		names[argItem.Name] = struct{}{}
+	}
+
+	for _, argItem := range argItems {
+		deps[argItem.Name] = []string{}
+		for _, ref := range argItem.Relation.ReferredNames() {
+			name, ok := ResolveReferredName(names, ref)
+			if !ok {
+				if !strings.HasPrefix(ref, "_") {
+					unknown[argItem.Name] = append(unknown[argItem.Name], ref)
+				}
+				continue
+			}
+			deps[argItem.Name] = appendIfMissing(deps[argItem.Name], name)
+		}
+	}
+	return
+}
+
+// ResolveReferredName find argument name of referred path, eg: `codeRepo.kind` refers to argument `codeRepo`
+func ResolveReferredName(names map[string]struct{}, ref string) (string, bool) {
+	if _, ok := names[ref]; ok {
+		return ref, true
+	}
+	root := strings.Split(ref, ".")[0]
+	if _, ok := names[root]; ok {
+		return root, true
+	}
+	return "", false
+}
+
+// ValidateRelations report relations that refer to unknown arguments and circular relations
+func (argSections ArgSections) ValidateRelations() error {
+	errs := common.Errors{}
+
+	deps, unknown := argSections.RelationDependencies()
+	for _, argItem := range argSections.AllArgItems() {
+		for _, ref := range unknown[argItem.Name] {
+			err := common.NewTemplateDefinitionError(fmt.Sprintf("%s.relation refers to argument `%s` that is not exists", argItem.Name, ref), nil)
+			err = common.WithArgument(err, argItem.Name)
+			errs = append(errs, common.WithPath(err, common.JoinPath(argSections.ArgPath(argItem.Name), "relation")))
+		}
+	}
+
+	for _, cycle := range findRelationCycles(argSections.AllArgItems(), deps) {
+		err := common.NewTemplateDefinitionError(fmt.Sprintf("circular relation between arguments: %s", strings.Join(cycle, " -> ")), nil)
+		err = common.WithArgument(err, cycle[0])
+		errs = append(errs, common.WithPath(err, common.JoinPath(argSections.ArgPath(cycle[0]), "relation")))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// MeaningfulArgs compute visibility of all arguments.
+// an argument is meaningful only when its relation matches show action and all arguments it depends on are meaningful.
+func (argSections ArgSections) MeaningfulArgs(argumentsValues map[string]interface{}) map[string]bool {
+	argItems := argSections.AllArgItems()
+	itemsMap := make(map[string]ArgItem, len(argItems))
+	for _, argItem := range argItems {
+		itemsMap[argItem.Name] = argItem
+	}
+	deps, _ := argSections.RelationDependencies()
+
+	meaningful := make(map[string]bool, len(argItems))
+	visiting := map[string]bool{}
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if v, ok := meaningful[name]; ok {
+			return v
+		}
+		if visiting[name] {
+			// circular relation is reported by ValidateRelations, just break it here
+			return true
+		}
+		visiting[name] = true
+
+		argItem := itemsMap[name]
+		result := argItem.IsMeaningful(argumentsValues)
+		for _, dep := range deps[name] {
+			// arg that depends on a not meaningful arg is not meaningful too
+			if !visit(dep) {
+				result = false
+			}
+		}
+
+		visiting[name] = false
+		meaningful[name] = result
+		return result
+	}
+
+	for _, argItem := range argItems {
+		visit(argItem.Name)
+	}
+	return meaningful
+}
+
+func findRelationCycles(argItems []ArgItem, deps map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	cycles := [][]string{}
+	reported := map[string]struct{}{}
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dep := range deps[name] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				start := 0
+				for i, n := range path {
+					if n == dep {
+						start = i
+						break
+					}
+				}
+				cycle := append(append([]string{}, path[start:]...), dep)
+
+				// the same cycle could be found from different start point
+				key := append([]string{}, path[start:]...)
+				sort.Strings(key)
+				if _, ok := reported[strings.Join(key, ",")]; !ok {
+					reported[strings.Join(key, ",")] = struct{}{}
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, argItem := range argItems {
+		if state[argItem.Name] == unvisited {
+			visit(argItem.Name)
+		}
+	}
+	return cycles
+}
+
+func appendIfMissing(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
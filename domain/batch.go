@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/otiszv/render/domain/common"
+)
+
+// BatchManifest pipeline configs that are rendered together, eg: all the projects that use a template
+type BatchManifest struct {
+	// File path of manifest file, outputs are relative to it
+	File    string       `json:"-"`
+	Entries []BatchEntry `json:"entries"`
+}
+
+// BatchEntry a pipeline config in batch
+type BatchEntry struct {
+	// Name unique name of entry, eg: name of project
+	Name string `json:"name"`
+	PipelineConfigSpec
+	// Output existing jenkinsfile that rendered jenkinsfile is compared with, relative to manifest file, optional
+	Output string `json:"output"`
+}
+
+// BatchResult result of rendering a batch entry
+type BatchResult struct {
+	Entry *BatchEntry
+	// Jenkinsfile rendered jenkinsfile
+	Jenkinsfile string
+	// Err error of render or reading output file
+	Err error
+	// Changed rendered jenkinsfile is different from output file or output file does not exist, it is false when output is not set
+	Changed bool
+}
+
+// LoadBatchManifest load manifest from file
+func LoadBatchManifest(file string) (*BatchManifest, error) {
+	byts, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, common.NewLoadError(err.Error(), err, map[string]interface{}{"file": file})
+	}
+
+	manifest := &BatchManifest{}
+	if err = yaml.Unmarshal(byts, manifest); err != nil {
+		return nil, common.NewLoadError(fmt.Sprintf("%s: %s", file, err.Error()), err, map[string]interface{}{"file": file})
+	}
+	manifest.File = file
+	return manifest, manifest.validate()
+}
+
+func (manifest *BatchManifest) validate() error {
+	errs := common.Errors{}
+
+	names := map[string]struct{}{}
+	for i := range manifest.Entries {
+		entry := &manifest.Entries[i]
+		path := fmt.Sprintf("entries[%d]", i)
+		if strings.TrimSpace(entry.Name) == "" {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("entry name should be required", nil), path+".name"))
+		} else if _, ok := names[entry.Name]; ok {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("entry name %s should be unique", entry.Name), nil), path+".name"))
+		}
+		names[entry.Name] = struct{}{}
+
+		if err := entry.ValidateDefinition(); err != nil {
+			errs = append(errs, common.WithPath(err, path))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// OutputFile path of output file of entry, it is empty when output is not set
+func (manifest *BatchManifest) OutputFile(entry *BatchEntry) string {
+	if entry.Output == "" || filepath.IsAbs(entry.Output) {
+		return entry.Output
+	}
+	return filepath.Join(filepath.Dir(manifest.File), entry.Output)
+}
+
+// Render render all the entries with templates in catalog by workers concurrently, results are in the order of entries.
+// catalog is shared by workers, so it should not be changed while rendering. workers <= 0 means the number of CPUs
+func (manifest *BatchManifest) Render(catalog *TemplateCatalog, workers int) []*BatchResult {
+	results := make([]*BatchResult, len(manifest.Entries))
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(manifest.Entries) {
+		workers = len(manifest.Entries)
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = manifest.render(catalog, &manifest.Entries[index])
+			}
+		}()
+	}
+
+	for i := range manifest.Entries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func (manifest *BatchManifest) render(catalog *TemplateCatalog, entry *BatchEntry) *BatchResult {
+	result := &BatchResult{Entry: entry}
+	result.Jenkinsfile, result.Err = entry.Render(catalog)
+	if result.Err != nil {
+		return result
+	}
+
+	output := manifest.OutputFile(entry)
+	if output == "" {
+		return result
+	}
+	byts, err := ioutil.ReadFile(output)
+	switch {
+	case os.IsNotExist(err):
+		result.Changed = true
+	case err != nil:
+		result.Err = common.NewLoadError(err.Error(), err, map[string]interface{}{"file": output})
+	default:
+		result.Changed = string(byts) != result.Jenkinsfile
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+const batchTestTemplates = `apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTaskTemplate
+metadata:
+  name: clone
+  annotations:
+    windcloud/displayName.zh-CN: 克隆
+    windcloud/displayName.en: Clone
+    windcloud/version: v1.0.0
+spec:
+  body: |
+    script {
+      {{checkout .SCM}}
+    }
+  arguments:
+  - name: SCM
+    required: true
+    schema:
+      type: object
+    display:
+      type: code
+      name:
+        zh-CN: 代码
+        en: code
+---
+apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTaskTemplate
+metadata:
+  name: build
+  annotations:
+    windcloud/displayName.zh-CN: 构建
+    windcloud/displayName.en: Build
+    windcloud/version: v1.0.0
+spec:
+  body: |
+    sh "go build -o app-{{.tag}}-{{._system_.namespace}} {{globalVar "GIT_COMMIT"}}"
+  arguments:
+  - name: tag
+    required: true
+    schema:
+      type: string
+    display:
+      type: string
+      name:
+        zh-CN: 标签
+        en: tag
+---
+apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTemplate
+metadata:
+  name: golang-build
+  annotations:
+    windcloud/displayName.zh-CN: 构建
+    windcloud/displayName.en: Build
+    windcloud/version: v1.0.0
+spec:
+  withSCM: true
+  agent:
+    label: golang
+  environments:
+  - name: APP
+    value: app
+  stages:
+  - name: Clone
+    tasks:
+    - name: Clone
+      type: clone
+  - name: Build
+    tasks:
+    - name: Build
+      type: build
+    - name: Test
+      type: build
+      relation:
+      - action: show
+        when:
+          name: doTest
+          value: true
+  arguments:
+  - displayName:
+      zh-CN: 基本
+      en: Basic
+    items:
+    - name: doTest
+      schema:
+        type: boolean
+      display:
+        type: boolean
+        name:
+          zh-CN: 测试
+          en: test
+    - name: imageTag
+      schema:
+        type: string
+      binding:
+      - Build.args.tag
+      - Test.args.tag
+      display:
+        type: string
+        name:
+          zh-CN: 标签
+          en: tag
+`
+
+func newBatchTestCatalog(t *testing.T) *TemplateCatalog {
+	docs, err := LoadDocumentsFromBytes("templates.yaml", []byte(batchTestTemplates))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	catalog := NewTemplateCatalog()
+	for _, doc := range docs {
+		if !catalog.Add(doc.Kube, doc.Name()) {
+			t.Fatalf("%s is not a template", doc.Name())
+		}
+	}
+	return catalog
+}
+
+// TestBatchRenderConcurrently entries share the catalog, cached jenkinsfile templates and global var registry,
+// run it with -race to check that the render path is safe for concurrent use
+func TestBatchRenderConcurrently(t *testing.T) {
+	catalog := newBatchTestCatalog(t)
+
+	manifest := &BatchManifest{File: "manifest.yaml"}
+	for i := 0; i < 64; i++ {
+		manifest.Entries = append(manifest.Entries, BatchEntry{
+			Name: fmt.Sprintf("app-%d", i),
+			PipelineConfigSpec: PipelineConfigSpec{
+				Template: TemplateRef{Name: "golang-build", Version: "^1.0"},
+				Arguments: map[string]interface{}{
+					"doTest":     i%2 == 0,
+					"imageTag":   fmt.Sprintf("v%d", i),
+					SystemArgKey: map[string]interface{}{"namespace": fmt.Sprintf("ns-%d", i)},
+				},
+				SCM: &SCMInfo{Type: SCMTypeEnum.GIT, RepositoryPath: fmt.Sprintf("http://git/app-%d", i)},
+			},
+		})
+	}
+
+	// the registry could be changed while rendering
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 64; i++ {
+			RegisterGlobalVars(GlobalVar{Name: fmt.Sprintf("BATCH_TEST_%d", i), Description: common.MulitLangValue{common.LocaleEN: "test"}})
+		}
+	}()
+	results := manifest.Render(catalog, 8)
+	wg.Wait()
+
+	if len(results) != len(manifest.Entries) {
+		t.Fatalf("expected %d results, but got %d", len(manifest.Entries), len(results))
+	}
+	for i, result := range results {
+		entry := &manifest.Entries[i]
+		if result.Entry != entry {
+			t.Fatalf("result %d is not in the order of entries", i)
+		}
+		if result.Err != nil {
+			t.Fatalf("%s: unexpected error: %v", entry.Name, result.Err)
+		}
+
+		// result should be the same as the one that is rendered alone
+		expected, err := entry.Render(catalog)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", entry.Name, err)
+		}
+		if result.Jenkinsfile != expected {
+			t.Errorf("%s: expected:\n%s\nbut got:\n%s", entry.Name, expected, result.Jenkinsfile)
+		}
+		if !strings.Contains(result.Jenkinsfile, fmt.Sprintf("app-v%d-ns-%d ${env.GIT_COMMIT}", i, i)) ||
+			!strings.Contains(result.Jenkinsfile, fmt.Sprintf("http://git/app-%d", i)) {
+			t.Errorf("%s: values of entry are not rendered:\n%s", entry.Name, result.Jenkinsfile)
+		}
+		if strings.Contains(result.Jenkinsfile, `stage("Test")`) != (i%2 == 0) {
+			t.Errorf("%s: relation of Test is not applied:\n%s", entry.Name, result.Jenkinsfile)
+		}
+	}
+}
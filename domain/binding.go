@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otiszv/render/domain/arguments"
+	"github.com/otiszv/render/domain/common"
+)
+
+const (
+	bindingScopeArgs = "args"
+)
+
+// fields of a task that could be bound out of `args` scope
+var bindingTaskFields = map[string]string{
+	"options.timeout": arguments.ArgValueTypeEnum.Int,
+	"approve.timeout": arguments.ArgValueTypeEnum.Int,
+}
+
+// argBinding is the parsed form of ArgItem.Binding, eg: `Build.args.imageTag`
+type argBinding struct {
+	Task  string
+	Scope string
+	Field string
+}
+
+func parseBinding(argName string, binding string) (argBinding, error) {
+	segments := strings.Split(binding, ".")
+	if len(segments) <= 1 {
+		return argBinding{}, common.NewTemplateDefinitionError(fmt.Sprintf("Pipeline template error, %s's Binding format:%s error", argName, binding), nil)
+	}
+
+	if segments[1] == bindingScopeArgs {
+		if len(segments) != 3 || segments[2] == "" {
+			return argBinding{}, common.NewTemplateDefinitionError(fmt.Sprintf("Pipeline template error, %s's Binding format:%s error, should be <task>.args.<arg>", argName, binding), nil)
+		}
+		return argBinding{Task: segments[0], Scope: bindingScopeArgs, Field: segments[2]}, nil
+	}
+
+	return argBinding{Task: segments[0], Field: strings.Join(segments[1:], ".")}, nil
+}
+
+// ValidateBindings validate arguments bindings of pipeline template against the task templates it refers.
+// taskTemplatesRef: task templates keyed by task type, same as Render
+func (spec *PipelineTemplateSpec) ValidateBindings(taskTemplatesRef map[string]TaskTemplateSpec) error {
+	errs := common.Errors{}
+
+	tasks := map[string]*Task{}
+	for _, task := range spec.allTasksWithPost() {
+		tasks[task.Name] = task
+	}
+
+	// task name -> arg names that bound by pipeline arguments
+	boundArgs := map[string]map[string]struct{}{}
+	markBound := func(taskName, argName string) {
+		if _, ok := boundArgs[taskName]; !ok {
+			boundArgs[taskName] = map[string]struct{}{}
+		}
+		boundArgs[taskName][argName] = struct{}{}
+	}
+
+	if spec.IsWithSCM() {
+		for _, taskName := range spec.scmBoundTasks() {
+			markBound(taskName, CloneTaskTemplateArgName)
+		}
+	}
+
+	taskPaths := spec.taskPaths()
+
+	for _, argItem := range spec.Arguments.AllArgItems() {
+		argPath := common.JoinPath("arguments", spec.Arguments.ArgPath(argItem.Name))
+		argErrs := common.Errors{}
+		for i, rawBinding := range argItem.Binding {
+			bindingPath := common.JoinPath(argPath, fmt.Sprintf("binding[%d]", i))
+			binding, err := parseBinding(argItem.Name, rawBinding)
+			if err != nil {
+				argErrs = append(argErrs, common.WithPath(err, bindingPath))
+				continue
+			}
+
+			task, ok := tasks[binding.Task]
+			if !ok {
+				argErrs = append(argErrs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("argument %s's binding %s refers to task %s that is not exists", argItem.Name, rawBinding, binding.Task), nil), bindingPath))
+				continue
+			}
+
+			if binding.Scope != bindingScopeArgs {
+				fieldType, ok := bindingTaskFields[binding.Field]
+				if !ok {
+					argErrs = append(argErrs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("argument %s's binding %s refers to field %s that is not support", argItem.Name, rawBinding, binding.Field), nil), bindingPath))
+					continue
+				}
+				if argItem.Schema != nil && argItem.Schema.Type != fieldType {
+					argErrs = append(argErrs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("argument %s's type %s is not compatible with %s, it should be %s", argItem.Name, argItem.Schema.Type, rawBinding, fieldType), nil), bindingPath))
+				}
+				continue
+			}
+
+			taskTemplate, ok := lookupTaskTemplate(taskTemplatesRef, task.Type)
+			if !ok {
+				argErrs = append(argErrs, common.WithPath(common.NewValidateError(fmt.Sprintf("require definition of task template named:%s", task.Type), nil), bindingPath))
+				continue
+			}
+
+			targetArg := taskTemplate.findArgItem(binding.Field)
+			if targetArg == nil {
+				argErrs = append(argErrs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("argument %s's binding %s refers to argument %s that is not exists in task template %s", argItem.Name, rawBinding, binding.Field, task.Type), nil), bindingPath))
+				continue
+			}
+
+			if !isArgTypeCompatible(&argItem, targetArg) {
+				argErrs = append(argErrs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("argument %s's type %s is not compatible with %s's type %s", argItem.Name, argTypeName(&argItem), rawBinding, argTypeName(targetArg)), nil), bindingPath))
+			}
+			markBound(task.Name, binding.Field)
+		}
+		if len(argErrs) > 0 {
+			errs = append(errs, common.WithArgument(argErrs, argItem.Name))
+		}
+	}
+
+	// required task template arguments should be bound or have const value
+	for _, task := range spec.allTasksWithPost() {
+		taskTemplate, ok := lookupTaskTemplate(taskTemplatesRef, task.Type)
+		if !ok {
+			continue
+		}
+
+		var constArgs map[string]interface{}
+		if spec.ConstValues != nil && spec.ConstValues.Tasks[task.Name] != nil {
+			constArgs = spec.ConstValues.Tasks[task.Name].Args
+		}
+
+		for _, templateArg := range taskTemplate.Arguments {
+			if !templateArg.Required || templateArg.Default != nil {
+				continue
+			}
+			if _, ok := boundArgs[task.Name][templateArg.Name]; ok {
+				continue
+			}
+			if _, ok := constArgs[templateArg.Name]; ok {
+				continue
+			}
+			err := common.NewTemplateDefinitionError(fmt.Sprintf("required argument %s of task %s(%s) is not bound and has no const value", templateArg.Name, task.Name, task.Type), nil)
+			err = common.WithArgument(common.WithTask(err, task.Name), templateArg.Name)
+			errs = append(errs, common.WithPath(err, taskPaths[task.Name]))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateUnusedArguments report arguments that are not bound to any task and not referred by any relation,
+// they are harmless for rendering, so errors returned should be treated as warnings
+func (spec *PipelineTemplateSpec) ValidateUnusedArguments() error {
+	errs := common.Errors{}
+	relationRefs := spec.relationReferences()
+	for _, argItem := range spec.Arguments.AllArgItems() {
+		if len(argItem.Binding) > 0 {
+			continue
+		}
+		if _, ok := relationRefs[argItem.Name]; ok {
+			continue
+		}
+		err := common.NewTemplateDefinitionError(fmt.Sprintf("argument %s is not bound to any task and not referred by any relation", argItem.Name), nil)
+		errs = append(errs, common.WithArgument(common.WithPath(err, common.JoinPath("arguments", spec.Arguments.ArgPath(argItem.Name))), argItem.Name))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// scmBoundTasks names of tasks that the SCM argument is bound to when pipeline is with scm.
+// it is bound to all tasks of clone type, or the task named Clone if there is no clone task as it used to be
+func (spec *PipelineTemplateSpec) scmBoundTasks() []string {
+	names := []string{}
+	for _, task := range spec.allTasksWithPost() {
+		if name, _ := SplitTaskType(task.Type); name == CloneTaskTemplateTypeName {
+			names = append(names, task.Name)
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, "Clone")
+	}
+	return names
+}
+
+// relationReferences return names of arguments that referred by relations of arguments and tasks
+func (spec *PipelineTemplateSpec) relationReferences() map[string]struct{} {
+	refs := map[string]struct{}{}
+
+	collect := func(relation *common.Relation) {
+		for _, name := range relation.ReferredNames() {
+			refs[name] = struct{}{}
+			refs[strings.Split(name, ".")[0]] = struct{}{}
+		}
+	}
+
+	for _, argItem := range spec.Arguments.AllArgItems() {
+		collect(argItem.Relation)
+	}
+	for _, task := range spec.allTasksWithPost() {
+		collect(task.Relation)
+	}
+	return refs
+}
+
+func (spec *TaskTemplateSpec) findArgItem(name string) *arguments.ArgItem {
+	for i := range spec.Arguments {
+		if spec.Arguments[i].Name == name {
+			return &spec.Arguments[i]
+		}
+	}
+	return nil
+}
+
+func argTypeName(arg *arguments.ArgItem) string {
+	if arg.Schema == nil {
+		return ""
+	}
+	if arg.Schema.Type == arguments.ArgValueTypeEnum.Array && arg.Schema.Items != nil {
+		return fmt.Sprintf("%s[%s]", arg.Schema.Type, arg.Schema.Items.Type)
+	}
+	return arg.Schema.Type
+}
+
+// isArgTypeCompatible whether value of source could be passed to target
+func isArgTypeCompatible(source *arguments.ArgItem, target *arguments.ArgItem) bool {
+	if source.Schema == nil || target.Schema == nil {
+		// schema is validated by ArgItem.ValidateDefinition
+		return true
+	}
+
+	// object accept any value
+	if target.Schema.Type == arguments.ArgValueTypeEnum.Object {
+		return true
+	}
+
+	return argTypeName(source) == argTypeName(target)
+}
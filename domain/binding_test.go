@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+const bindingTestTemplates = `apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTaskTemplate
+metadata:
+  name: clone
+  annotations:
+    windcloud/version: v1.0.0
+spec:
+  body: |
+    script {
+      {{checkout .SCM}}
+    }
+  arguments:
+  - name: SCM
+    required: true
+    schema:
+      type: object
+    display:
+      type: code
+      name:
+        zh-CN: 代码
+        en: code
+---
+apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTemplate
+metadata:
+  name: checkout
+  annotations:
+    windcloud/version: v1.0.0
+spec:
+  withSCM: true
+  agent:
+    label: golang
+  stages:
+  - name: Checkout
+    tasks:
+    - name: Checkout
+      type: clone
+  arguments:
+  - displayName:
+      zh-CN: 基本
+      en: Basic
+    items:
+    - name: unused
+      schema:
+        type: string
+      display:
+        type: string
+        name:
+          zh-CN: 未使用
+          en: unused
+`
+
+// loadBindingTestTemplates load the pipeline template and task templates keyed by name from yaml documents
+func loadBindingTestTemplates(t *testing.T, yamls string) (*PipelineTemplateSpec, map[string]TaskTemplateSpec) {
+	var spec *PipelineTemplateSpec
+	taskTemplates := map[string]TaskTemplateSpec{}
+	for _, doc := range strings.Split(yamls, "---\n") {
+		kube := Kubernete{}
+		if err := kube.LoadFromYaml(doc); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		switch kube.Kind {
+		case KuberneteKindPipelineTemplate:
+			definition := JenkinsPipelineTemplateDefinition(kube)
+			template, err := definition.PipelineTemplateSpec()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			spec = template
+		case KuberneteKindPipelineTaskTemplate:
+			definition := JenkinsPipelineTaskTemplateDefinition(kube)
+			template, err := definition.PipelineTaskTemplateSpec()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			taskTemplates[kube.GetName("")] = *template
+		}
+	}
+	if spec == nil {
+		t.Fatalf("pipeline template is not found")
+	}
+	return spec, taskTemplates
+}
+
+// TestSCMBindingOfCloneTask the SCM argument is bound to the clone task whatever its name is
+func TestSCMBindingOfCloneTask(t *testing.T) {
+	spec, taskTemplates := loadBindingTestTemplates(t, bindingTestTemplates)
+	if err := spec.ValidateBindings(taskTemplates); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	errs := common.FlattenErrors(spec.ValidateUnusedArguments())
+	if len(errs) != 1 {
+		t.Fatalf("expected one unused argument, but got %v", errs)
+	}
+	if typed, ok := errs[0].(common.Error); !ok || typed.Argument != "unused" || typed.Path != "arguments[0].items[0]" {
+		t.Errorf("unexpected error: %#v", errs[0])
+	}
+
+	render, err := spec.Render(taskTemplates, map[string]interface{}{}, &SCMInfo{Type: SCMTypeEnum.GIT, RepositoryPath: "http://git/app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(render, "http://git/app") {
+		t.Errorf("scm is not rendered in clone task:\n%s", render)
+	}
+}
+
+const timeoutBindingTestTemplates = `apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTaskTemplate
+metadata:
+  name: build
+  annotations:
+    windcloud/version: v1.0.0
+spec:
+  body: |
+    sh "make"
+---
+apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTemplate
+metadata:
+  name: timeout
+  annotations:
+    windcloud/version: v1.0.0
+spec:
+  agent:
+    label: golang
+  stages:
+  - name: Build
+    tasks:
+    - name: Build
+      type: build
+  arguments:
+  - displayName:
+      zh-CN: 基本
+      en: Basic
+    items:
+    - name: timeout
+      schema:
+        type: int
+      binding:
+      - Build.options.timeout
+      display:
+        type: int
+        name:
+          zh-CN: 超时
+          en: timeout
+    - name: approveTimeout
+      schema:
+        type: int
+      binding:
+      - Build.approve.timeout
+      display:
+        type: int
+        name:
+          zh-CN: 审批超时
+          en: approve timeout
+`
+
+// TestTimeoutBinding options and approve of task are not set by const values, they are allocated by binding
+func TestTimeoutBinding(t *testing.T) {
+	spec, taskTemplates := loadBindingTestTemplates(t, timeoutBindingTestTemplates)
+	if err := spec.ValidateBindings(taskTemplates); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// values decoded from json are float64
+	render, err := spec.Render(taskTemplates, map[string]interface{}{"timeout": float64(600), "approveTimeout": float64(60)}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"timeout(time:600, unit:'SECONDS')", `timeout(time:60, unit:"SECONDS")`} {
+		if !strings.Contains(render, expected) {
+			t.Errorf("expected %s in:\n%s", expected, render)
+		}
+	}
+
+	spec, _ = loadBindingTestTemplates(t, timeoutBindingTestTemplates)
+	_, err = spec.Render(taskTemplates, map[string]interface{}{"timeout": 1.5}, nil)
+	if err == nil || !strings.Contains(err.Error(), "options.timeout's value 1.5 should be int") {
+		t.Errorf("expected error of timeout, but got %v", err)
+	}
+}
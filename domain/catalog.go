@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+// TemplateCatalog pipeline templates and task templates loaded from template repository.
+// it should be treated as read only after loaded.
+type TemplateCatalog struct {
+	PipelineTemplates []*CatalogItem
+	TaskTemplates     []*CatalogItem
+}
+
+// CatalogItem a template in catalog
+type CatalogItem struct {
+	Name          string
+	Version       string
+	File          string
+	Kube          *Kubernete
+	ClusterScoped bool
+}
+
+// Key key of item in its scope, eg: `build@1.0.0` or `cluster:build@1.0.0`,
+// cluster scoped and namespaced templates could have the same name and version
+func (item *CatalogItem) Key() string {
+	return item.scopedName(TemplateRef{Name: item.Name, Version: item.Version}.String())
+}
+
+func (item *CatalogItem) scopedName(name string) string {
+	if item.ClusterScoped {
+		return "cluster:" + name
+	}
+	return name
+}
+
+// PipelineTemplateSpec spec of pipeline template item
+func (item *CatalogItem) PipelineTemplateSpec() (*PipelineTemplateSpec, error) {
+	definition := JenkinsPipelineTemplateDefinition(*item.Kube)
+	return definition.PipelineTemplateSpec()
+}
+
+// NewTemplateCatalog create an empty catalog
+func NewTemplateCatalog() *TemplateCatalog {
+	return &TemplateCatalog{
+		PipelineTemplates: []*CatalogItem{},
+		TaskTemplates:     []*CatalogItem{},
+	}
+}
+
+// LoadFiles load templates from files, files that are not templates will be ignored
+func (catalog *TemplateCatalog) LoadFiles(files []string) error {
+	errs := common.Errors{}
+	for _, file := range files {
+		docs, err := LoadDocuments(file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, doc := range docs {
+			catalog.Add(doc.Kube, doc.Name())
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Add add template to catalog, cluster scoped templates are kept in their own scope, see findCatalogItem.
+// it returns false when kube is not a template
+func (catalog *TemplateCatalog) Add(kube *Kubernete, file string) bool {
+	if kube.Metadata == nil || kube.Spec == nil {
+		return false
+	}
+	if !isSupportVersion(kube.ApiVersion) || kube.validateKindVersion() != nil {
+		return false
+	}
+
+	metadata := PipelineTemplateMetadata{}
+	metadata.Name = kube.GetName("")
+	if annotations, err := kube.Metadata.Get("annotations").Map(); err == nil {
+		metadata.Annotations = annotations
+	}
+
+	item := &CatalogItem{
+		Name:          metadata.Name,
+		Version:       metadata.GetAnnotation(AnnotationVersion),
+		File:          file,
+		Kube:          kube,
+		ClusterScoped: kube.Kind.IsClusterScoped(),
+	}
+
+	switch kube.Kind.TemplateKind() {
+	case KuberneteKindPipelineTemplate:
+		catalog.PipelineTemplates = append(catalog.PipelineTemplates, item)
+	case KuberneteKindPipelineTaskTemplate:
+		catalog.TaskTemplates = append(catalog.TaskTemplates, item)
+	default:
+		return false
+	}
+	return true
+}
+
+// findCatalogItem find the highest version that matches the version constraint, see ParseVersionConstraint.
+// namespaced templates take precedence over cluster scoped ones, cluster scoped templates are found only when
+// none of namespaced ones matches
+func findCatalogItem(items []*CatalogItem, name string, constraint string) (*CatalogItem, error) {
+	parsedConstraint, err := ParseVersionConstraint(constraint)
+	if err != nil {
+		return nil, common.NewTemplateDefinitionError(err.Error(), nil)
+	}
+
+	found := findCatalogItemInScope(items, name, constraint, parsedConstraint, false)
+	if found == nil {
+		found = findCatalogItemInScope(items, name, constraint, parsedConstraint, true)
+	}
+	return found, nil
+}
+
+func findCatalogItemInScope(items []*CatalogItem, name string, constraint string, parsedConstraint *VersionConstraint, clusterScoped bool) *CatalogItem {
+	var (
+		found        *CatalogItem
+		foundVersion Version
+	)
+	for _, item := range items {
+		if item.Name != name || item.ClusterScoped != clusterScoped {
+			continue
+		}
+
+		version, err := ParseVersion(item.Version)
+		if err != nil {
+			// not semantic version, only exactly matches
+			if found == nil && (constraint == "" || constraint == item.Version) {
+				found = item
+			}
+			continue
+		}
+		if !parsedConstraint.Check(version) {
+			continue
+		}
+		if found == nil || version.Compare(foundVersion) >= 0 {
+			found = item
+			foundVersion = version
+		}
+	}
+	return found
+}
+
+// FindPipelineTemplate find the highest version of pipeline template that matches version constraint, version is optional
+func (catalog *TemplateCatalog) FindPipelineTemplate(name string, version string) (*CatalogItem, error) {
+	item, err := findCatalogItem(catalog.PipelineTemplates, name, version)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, common.NewValidateError(fmt.Sprintf("require definition of pipeline template %s", TemplateRef{Name: name, Version: version}), nil)
+	}
+	return item, nil
+}
+
+// ResolvePipelineTemplate implements PipelineTemplateResolver
+func (catalog *TemplateCatalog) ResolvePipelineTemplate(name string, version string) (*PipelineTemplateSpec, error) {
+	item, err := catalog.FindPipelineTemplate(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.PipelineTemplateSpec()
+}
+
+// FindTaskTemplate find the highest version of task template that matches version constraint, version is optional
+func (catalog *TemplateCatalog) FindTaskTemplate(name string, version string) (*CatalogItem, error) {
+	item, err := findCatalogItem(catalog.TaskTemplates, name, version)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, common.NewValidateError(fmt.Sprintf("require definition of task template named:%s", TemplateRef{Name: name, Version: version}), nil)
+	}
+	return item, nil
+}
+
+// TaskTemplateSpecs the highest version of task templates that keyed by name
+func (catalog *TemplateCatalog) TaskTemplateSpecs() (map[string]TaskTemplateSpec, error) {
+	specs := map[string]TaskTemplateSpec{}
+	errs := common.Errors{}
+
+	for _, item := range catalog.TaskTemplates {
+		if _, ok := specs[item.Name]; ok {
+			continue
+		}
+		item, err := catalog.FindTaskTemplate(item.Name, "")
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		definition := JenkinsPipelineTaskTemplateDefinition(*item.Kube)
+		spec, err := definition.PipelineTaskTemplateSpec()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		specs[item.Name] = *spec
+	}
+
+	if len(errs) == 0 {
+		return specs, nil
+	}
+	return specs, errs
+}
+
+// ResolveTaskTemplates resolve task templates that tasks of spec refer, keyed by task type, eg: `build@^1.2`.
+// it could be used as taskTemplatesRef when render.
+func (catalog *TemplateCatalog) ResolveTaskTemplates(spec *PipelineTemplateSpec) (map[string]TaskTemplateSpec, error) {
+	specs := map[string]TaskTemplateSpec{}
+	errs := common.Errors{}
+
+	for _, task := range spec.allTasksWithPost() {
+		if _, ok := specs[task.Type]; ok {
+			continue
+		}
+
+		item, err := catalog.FindTaskTemplate(SplitTaskType(task.Type))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		definition := JenkinsPipelineTaskTemplateDefinition(*item.Kube)
+		taskTemplateSpec, err := definition.PipelineTaskTemplateSpec()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		specs[task.Type] = *taskTemplateSpec
+	}
+
+	if len(errs) == 0 {
+		return specs, nil
+	}
+	return specs, errs
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+func newScopedTestCatalog(t *testing.T, templates ...string) *TemplateCatalog {
+	template := `apiVersion: devops.windcloud/v1beta1
+kind: %s
+metadata:
+  name: build
+  annotations:
+    windcloud/version: %s
+spec:
+  agent:
+    label: %s
+  stages:
+  - name: Build
+    tasks:
+    - name: Build
+      type: build
+`
+	catalog := NewTemplateCatalog()
+	for i := 0; i+1 < len(templates); i += 2 {
+		kind, version := templates[i], templates[i+1]
+		kube := &Kubernete{}
+		if err := kube.LoadFromYaml(fmt.Sprintf(template, kind, version, kind)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		catalog.Add(kube, fmt.Sprintf("%d.yaml", i/2))
+	}
+	return catalog
+}
+
+func TestCatalogScopePrecedence(t *testing.T) {
+	catalog := newScopedTestCatalog(t,
+		"ClusterPipelineTemplate", "v1.0.0",
+		"PipelineTemplate", "v1.0.0",
+		"ClusterPipelineTemplate", "v1.1.0",
+		"ClusterPipelineTemplate", "v2.0.0",
+	)
+
+	cases := []struct {
+		constraint string
+		key        string
+	}{
+		// namespaced one takes precedence over cluster scoped one of the same or higher version
+		{"", "build@v1.0.0"},
+		{"^1.0", "build@v1.0.0"},
+		{"v1.0.0", "build@v1.0.0"},
+		// cluster scoped ones are found when none of namespaced ones matches
+		{"^2.0", "cluster:build@v2.0.0"},
+		{">1.0.0 <2.0.0", "cluster:build@v1.1.0"},
+	}
+	for _, c := range cases {
+		t.Run(c.constraint, func(t *testing.T) {
+			item, err := catalog.FindPipelineTemplate("build", c.constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if item.Key() != c.key {
+				t.Errorf("expected %s, but got %s", c.key, item.Key())
+			}
+		})
+	}
+}
+
+func TestCatalogDuplicatedVersionsInScope(t *testing.T) {
+	// templates of different scopes are not duplicated
+	catalog := newScopedTestCatalog(t, "ClusterPipelineTemplate", "v1.0.0", "PipelineTemplate", "v1.0.0")
+	if err := catalog.ValidateVersions(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	catalog = newScopedTestCatalog(t, "PipelineTemplate", "v1.0.0", "ClusterPipelineTemplate", "v1.0.0", "ClusterPipelineTemplate", "v1.0.0")
+	errs := common.FlattenErrors(catalog.ValidateVersions())
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "cluster:build@v1.0.0 is duplicated in 1.yaml and 2.yaml") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
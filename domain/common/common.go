@@ -2,12 +2,9 @@ package common
 
 import (
 	"fmt"
-)
 
-type MulitLangValue struct {
-	ZH_CN string `json:"zh-CN" mapstructure:"zh-CN" yaml:"zh-CN"`
-	EN    string `json:"en"`
-}
+	"github.com/otiszv/render/goutils"
+)
 
 type Relation []RelationItem
 
@@ -20,34 +17,23 @@ func (action RelationAction) Validate() bool {
 	return true
 }
 
-func (action RelationAction) negation() RelationAction {
-	if action == RelationActionSHOW {
-		return RelationActionHIDDEN
-	}
-	if action == RelationActionHIDDEN {
-		return RelationActionSHOW
-	}
-	fmt.Printf("ERROR!, not support argment releation action %s \n", action)
-	return "NOT_SUPPORT_" + action
-}
-
 type RelationItem struct {
-	Action RelationAction
-	When   *RelationWhen
+	Action RelationAction `json:"action"`
+	When   *RelationWhen  `json:"when"`
 }
 
 func (item RelationItem) ValidateDefinition() error {
 	var errs Errors
 	if !item.Action.Validate() {
-		errs = append(errs, NewTemplateDefinitionError(fmt.Sprintf("not support relation action `%s`", item.Action), nil))
+		errs = append(errs, WithPath(NewTemplateDefinitionError(fmt.Sprintf("not support relation action `%s`", item.Action), nil), "action"))
 	}
 
 	if item.When == nil {
-		errs = append(errs, NewTemplateDefinitionError(fmt.Sprintf("when should not be nil"), nil))
+		errs = append(errs, WithPath(NewTemplateDefinitionError(fmt.Sprintf("when should not be nil"), nil), "when"))
 	} else {
 		err := item.When.ValidateDefinition()
 		if err != nil {
-			errs = append(errs, err)
+			errs = append(errs, WithPath(err, "when"))
 		}
 	}
 
@@ -63,11 +49,13 @@ const (
 	RelationActionHIDDEN = "hidden"
 )
 
+// RelationWhen condition of relation, you can only set one of `name and value`, `all`, `any` or `expression`
 type RelationWhen struct {
-	Name  string
-	Value interface{}
-	All   []RelationWhenItem
-	Any   []RelationWhenItem
+	Name       string             `json:"name,omitempty"`
+	Value      interface{}        `json:"value,omitempty"`
+	All        []RelationWhenItem `json:"all,omitempty"`
+	Any        []RelationWhenItem `json:"any,omitempty"`
+	Expression string             `json:"expression,omitempty"`
 }
 
 func (relation RelationWhen) ValidateDefinition() error {
@@ -81,24 +69,40 @@ func (relation RelationWhen) ValidateDefinition() error {
 	if relation.Name != "" {
 		flag = flag + 1
 	}
+	if relation.Expression != "" {
+		flag = flag + 1
+	}
 
 	if flag == 0 {
 		return nil
 	}
 
 	if flag > 1 {
-		return NewTemplateDefinitionError("not support multi relation when, you can only set `name and value` or  `all` or `any`", nil)
+		return NewTemplateDefinitionError("not support multi relation when, you can only set `name and value` or  `all` or `any` or `expression`", nil)
 	}
 
 	errs := Errors{}
 	if relation.All != nil && len(relation.All) > 0 {
 		for i, item := range relation.All {
 			if item.Name == "" {
-				errs = append(errs, NewTemplateDefinitionError(fmt.Sprintf("relation.all[%d].name should not empty", i), nil))
+				errs = append(errs, WithPath(NewTemplateDefinitionError(fmt.Sprintf("relation.all[%d].name should not empty", i), nil), fmt.Sprintf("all[%d].name", i)))
+			}
+		}
+	}
+	if relation.Any != nil && len(relation.Any) > 0 {
+		for i, item := range relation.Any {
+			if item.Name == "" {
+				errs = append(errs, WithPath(NewTemplateDefinitionError(fmt.Sprintf("relation.any[%d].name should not empty", i), nil), fmt.Sprintf("any[%d].name", i)))
 			}
 		}
 	}
 
+	if relation.Expression != "" {
+		if _, err := ParseExpression(relation.Expression); err != nil {
+			errs = append(errs, WithPath(NewTemplateDefinitionError(err.Error(), nil), "expression"))
+		}
+	}
+
 	if len(errs) == 0 {
 		return nil
 	}
@@ -106,43 +110,86 @@ func (relation RelationWhen) ValidateDefinition() error {
 }
 
 type RelationWhenItem struct {
-	Name  string
-	Value interface{}
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
 }
 
-func (rel *Relation) IsMathcShowAction(argumentsValues map[string]interface{}) bool {
-	if rel == nil || len(*rel) == 0 {
-		return true
+// ReferredNames return argument names or paths(eg: `codeRepo.kind`) that referred by relation
+func (rel *Relation) ReferredNames() []string {
+	if rel == nil {
+		return nil
 	}
 
-	relationMap := map[RelationAction]RelationItem{}
-	for _, relation := range *rel {
-		relationMap[relation.Action] = relation
+	names := []string{}
+	for _, item := range *rel {
+		if item.When == nil {
+			continue
+		}
+		if item.When.Name != "" {
+			names = append(names, item.When.Name)
+		}
+		for _, whenItem := range item.When.All {
+			names = append(names, whenItem.Name)
+		}
+		for _, whenItem := range item.When.Any {
+			names = append(names, whenItem.Name)
+		}
+		if item.When.Expression != "" {
+			if expression, err := ParseExpression(item.When.Expression); err == nil {
+				names = append(names, expression.Variables()...)
+			}
+		}
 	}
+	return names
+}
 
-	var choiceRelation RelationItem
-	if len(relationMap) > 1 {
-		if _, ok := relationMap[RelationActionSHOW]; !ok {
-			fmt.Printf("not found show relation of %#v, we think this is matching show action\n", *rel)
-			return true
+// ValidateDefinition validate all relation items
+func (rel *Relation) ValidateDefinition() error {
+	if rel == nil {
+		return nil
+	}
+
+	errs := Errors{}
+	for i, item := range *rel {
+		if err := item.ValidateDefinition(); err != nil {
+			errs = append(errs, WithPath(err, fmt.Sprintf("[%d]", i)))
 		}
+	}
 
-		choiceRelation = relationMap[RelationActionSHOW]
-	} else {
-		choiceRelation = (*rel)[0]
+	if len(errs) == 0 {
+		return nil
 	}
+	return errs
+}
 
-	match := choiceRelation.When.match(argumentsValues)
-	matchShowAction := true
+// IsMathcShowAction whether relation result is show.
+// it is hidden when any `hidden` item matches, otherwise it is show when there is no `show` item or any `show` item matches.
+func (rel *Relation) IsMathcShowAction(argumentsValues map[string]interface{}) bool {
+	if rel == nil || len(*rel) == 0 {
+		return true
+	}
 
-	if match {
-		matchShowAction = choiceRelation.Action == RelationActionSHOW
-	} else {
-		// 不满足 when 指定的条件时，我们认为 需要执行的action 是 原有action的逻辑非。
-		// 故，需要 判断 逻辑非 之后，是否 ==  show, 确定是否满足 show的条件。
-		matchShowAction = choiceRelation.Action.negation() == RelationActionSHOW
+	hasShowAction := false
+	matchShowAction := false
+	for _, relation := range *rel {
+		switch relation.Action {
+		case RelationActionHIDDEN:
+			if relation.When.match(argumentsValues) {
+				return false
+			}
+		case RelationActionSHOW:
+			hasShowAction = true
+			if relation.When.match(argumentsValues) {
+				matchShowAction = true
+			}
+		default:
+			goutils.Logger.Printf("ERROR!, not support argment releation action %s \n", relation.Action)
+		}
 	}
 
+	if !hasShowAction {
+		return true
+	}
 	return matchShowAction
 }
 
@@ -152,7 +199,14 @@ func (when *RelationWhen) match(argumentsValues map[string]interface{}) bool {
 		return true
 	}
 
-	if when.All != nil && len(when.All) > 0 {
+	if when.Expression != "" {
+		expression, err := ParseExpression(when.Expression)
+		if err != nil {
+			goutils.Logger.Printf("ERROR!, %s \n", err.Error())
+			return false
+		}
+		return expression.Evaluate(argumentsValues)
+	} else if when.All != nil && len(when.All) > 0 {
 
 		var res = true
 		for _, item := range when.All {
@@ -177,7 +231,7 @@ func (when *RelationWhen) match(argumentsValues map[string]interface{}) bool {
 }
 
 func (whenItem *RelationWhenItem) match(argumentsValues map[string]interface{}) bool {
-	val, exists := argumentsValues[whenItem.Name]
+	val, exists := LookupValue(argumentsValues, whenItem.Name)
 	if !exists {
 		return false
 	}
@@ -1,25 +1,94 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 )
 
 type Error struct {
 	Code    string                 `json:"code"`
 	Message string                 `json:"message"`
-	Data    map[string]interface{} `json:"data"`
-	Err     error                  `json::"OriginalError"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	// Err original error, it is serialized as its message
+	Err error `json:"-"`
+
+	// Path json-pointer style path of the field that error occurs at, eg: spec.stages[2].tasks[0].agent
+	Path string `json:"path,omitempty"`
+	// Line, Column position of Path in source file, 0 means unknown
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Argument, Task name of argument or task that error occurs at
+	Argument string `json:"argument,omitempty"`
+	Task     string `json:"task,omitempty"`
 }
 
+// stable codes of Error
 const (
-	codeInfoLossingError    = "InfoLossingError"
-	codeValidateError       = "ValidateError"
-	codeTemplateError       = "TemplateDefinitionError"
-	codeTemplateRenderError = "TemplateRenderError"
+	CodeInfoLossingError    = "InfoLossingError"
+	CodeValidateError       = "ValidateError"
+	CodeTemplateError       = "TemplateDefinitionError"
+	CodeTemplateRenderError = "TemplateRenderError"
+	CodeLoadError           = "LoadError"
+)
+
+// sentinel errors that match any Error with the same code by errors.Is, eg: errors.Is(err, ErrValidate)
+var (
+	ErrInfoLossing        error = Error{Code: CodeInfoLossingError}
+	ErrValidate           error = Error{Code: CodeValidateError}
+	ErrTemplateDefinition error = Error{Code: CodeTemplateError}
+	ErrTemplateRender     error = Error{Code: CodeTemplateRenderError}
+	ErrLoad               error = Error{Code: CodeLoadError}
 )
 
 func (err Error) Error() string {
-	return fmt.Sprintf("%s:%s, data=%v", err.Code, err.Message, err.Data)
+	location := err.Path
+	if location != "" && err.Line > 0 {
+		location = fmt.Sprintf("%s(%d:%d)", err.Path, err.Line, err.Column)
+	}
+
+	res := ""
+	if location == "" {
+		res = fmt.Sprintf("%s:%s", err.Code, err.Message)
+	} else {
+		res = fmt.Sprintf("%s:%s: %s", err.Code, location, err.Message)
+	}
+	if len(err.Data) > 0 {
+		res += fmt.Sprintf(", data=%v", err.Data)
+	}
+	if err.Err != nil {
+		res += fmt.Sprintf(", error=%s", err.Err.Error())
+	}
+	return res
+}
+
+// Unwrap return the original error
+func (err Error) Unwrap() error {
+	return err.Err
+}
+
+// Is errors with the same code are treated as the same, so sentinel errors like ErrValidate could be used with errors.Is.
+// errors.Is checks original error in Err too, but IsValidateError and so on do not
+func (err Error) Is(target error) bool {
+	typed, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	return typed.Code != "" && typed.Code == err.Code && (typed.Message == "" || typed.Message == err.Message)
+}
+
+// MarshalJSON serialize original error as its message
+func (err Error) MarshalJSON() ([]byte, error) {
+	type plain Error
+	originalError := ""
+	if err.Err != nil {
+		originalError = err.Err.Error()
+	}
+	return json.Marshal(struct {
+		plain
+		OriginalError string `json:"originalError,omitempty"`
+	}{plain(err), originalError})
 }
 
 func NewInfoLossingError(message string, data map[string]interface{}) error {
@@ -29,7 +98,7 @@ func NewInfoLossingError(message string, data map[string]interface{}) error {
 	}
 
 	return Error{
-		Code:    codeInfoLossingError,
+		Code:    CodeInfoLossingError,
 		Message: message,
 		Data:    data,
 	}
@@ -42,7 +111,7 @@ func NewValidateError(message string, data map[string]interface{}) error {
 	}
 
 	return Error{
-		Code:    codeValidateError,
+		Code:    CodeValidateError,
 		Message: message,
 		Data:    data,
 	}
@@ -55,7 +124,7 @@ func NewTemplateDefinitionError(message string, data map[string]interface{}) err
 	}
 
 	return Error{
-		Code:    codeTemplateError,
+		Code:    CodeTemplateError,
 		Message: message,
 		Data:    data,
 	}
@@ -68,7 +137,22 @@ func NewTemplateRenderError(message string, oriErr error, data map[string]interf
 	}
 
 	return Error{
-		Code:    codeTemplateRenderError,
+		Code:    CodeTemplateRenderError,
+		Message: message,
+		Data:    data,
+		Err:     oriErr,
+	}
+}
+
+// NewLoadError error when loading resources, eg: invalid yaml
+func NewLoadError(message string, oriErr error, data map[string]interface{}) error {
+
+	if message == "" {
+		message = "load error"
+	}
+
+	return Error{
+		Code:    CodeLoadError,
 		Message: message,
 		Data:    data,
 		Err:     oriErr,
@@ -80,45 +164,172 @@ type Errors []error
 
 func (errs Errors) Error() string {
 	ct := ""
-	for _, err := range errs {
+	for _, err := range errs.Flatten() {
 		ct += fmt.Sprintf("%s\n", err)
 	}
 	return fmt.Sprintf("%s, errors=%s", "multi errors", ct)
 }
 
+// Unwrap return all errors, so errors.Is and errors.As could find any of them
+func (errs Errors) Unwrap() []error {
+	return errs
+}
+
+// Flatten expand nested Errors, nil errors are dropped
+func (errs Errors) Flatten() Errors {
+	res := Errors{}
+	for _, err := range errs {
+		switch typed := err.(type) {
+		case nil:
+		case Errors:
+			res = append(res, typed.Flatten()...)
+		default:
+			res = append(res, err)
+		}
+	}
+	return res
+}
+
+// MarshalJSON serialize flattened errors as an array, errors that are not Error are serialized as their messages
+func (errs Errors) MarshalJSON() ([]byte, error) {
+	items := make([]interface{}, 0, len(errs))
+	for _, err := range errs.Flatten() {
+		if _, ok := err.(Error); ok {
+			items = append(items, err)
+			continue
+		}
+		items = append(items, Error{Message: err.Error(), Err: err})
+	}
+	return json.Marshal(items)
+}
+
+// FlattenErrors expand err to a list of errors, see Errors.Flatten
+func FlattenErrors(err error) Errors {
+	if err == nil {
+		return Errors{}
+	}
+	return Errors{err}.Flatten()
+}
+
 //IsTemplateDefinitionError help you judge err type
 func IsTemplateDefinitionError(err error) bool {
-	return isThatError(err, codeTemplateError)
+	return isThatError(err, CodeTemplateError)
 }
 
 //IsTemplateRenderError help you judge err type
 func IsTemplateRenderError(err error) bool {
-	return isThatError(err, codeTemplateRenderError)
+	return isThatError(err, CodeTemplateRenderError)
 }
 
 //IsValidateError help you judge err type
 func IsValidateError(err error) bool {
-	return isThatError(err, codeValidateError)
+	return isThatError(err, CodeValidateError)
 }
 
-func isThatError(err error, code string) bool {
-	_errs, ok := err.(Errors)
+//IsLoadError help you judge err type
+func IsLoadError(err error) bool {
+	return isThatError(err, CodeLoadError)
+}
 
-	if ok {
-		for _, errItem := range _errs {
-			if isThatError(errItem, code) {
-				return true
-			}
+// isThatError whether err or any of Errors has code, original errors in Err are not checked,
+// eg: render error that caused by a validate error is not a validate error, use errors.Is(err, ErrValidate) to check them too
+func isThatError(err error, code string) bool {
+	for _, item := range FlattenErrors(err) {
+		if typed, ok := item.(Error); ok && typed.Code == code {
+			return true
 		}
+	}
+	return false
+}
 
-		return false
+var simplePathKeyRegx = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$-]*$`)
+
+// PathKey key as path segment, keys that contain special chars are quoted, eg: annotations["windcloud/version"]
+func PathKey(key string) string {
+	if simplePathKeyRegx.MatchString(key) {
+		return key
 	}
+	return fmt.Sprintf("[%q]", key)
+}
 
-	_err, ok := err.(Error)
-	if !ok {
-		return false
+// JoinPath join path segments, eg: spec + stages[0] + name = spec.stages[0].name
+func JoinPath(segments ...string) string {
+	path := ""
+	for _, segment := range segments {
+		switch {
+		case segment == "":
+		case path == "" || strings.HasPrefix(segment, "["):
+			path += segment
+		default:
+			path += "." + segment
+		}
 	}
+	return path
+}
+
+// WithPath prefix path of err with segment, errors in Errors are prefixed one by one.
+// errors that are not Error are returned as it is.
+func WithPath(err error, segment string) error {
+	return MapPath(err, func(path string) string {
+		return JoinPath(segment, path)
+	})
+}
+
+// MapPath replace path of err and errors in Errors by mapping
+func MapPath(err error, mapping func(path string) string) error {
+	return mapError(err, func(typed Error) Error {
+		typed.Path = mapping(typed.Path)
+		return typed
+	})
+}
+
+// WithArgument set argument name of err and errors in Errors if it is not set
+func WithArgument(err error, name string) error {
+	return mapError(err, func(typed Error) Error {
+		if typed.Argument == "" {
+			typed.Argument = name
+		}
+		return typed
+	})
+}
+
+// WithTask set task name of err and errors in Errors if it is not set
+func WithTask(err error, name string) error {
+	return mapError(err, func(typed Error) Error {
+		if typed.Task == "" {
+			typed.Task = name
+		}
+		return typed
+	})
+}
 
-	//it was a single Error
-	return _err.Code == code
+// mapError apply mapping to err and errors in Errors, errors that are not Error are returned as it is
+func mapError(err error, mapping func(typed Error) Error) error {
+	switch typed := err.(type) {
+	case Errors:
+		res := make(Errors, 0, len(typed))
+		for _, item := range typed {
+			res = append(res, mapError(item, mapping))
+		}
+		return res
+	case Error:
+		return mapping(typed)
+	}
+	return err
+}
+
+// PositionLocator find line and column of path in source file
+type PositionLocator func(path string) (line int, column int, ok bool)
+
+// Locate fill line and column of err and errors in Errors by their path
+func Locate(err error, locate PositionLocator) error {
+	return mapError(err, func(typed Error) Error {
+		if typed.Line > 0 {
+			return typed
+		}
+		if line, column, ok := locate(typed.Path); ok {
+			typed.Line, typed.Column = line, column
+		}
+		return typed
+	})
 }
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestErrorsIs(t *testing.T) {
+	validate := NewValidateError("value is invalid", nil)
+	render := NewTemplateRenderError("render failed", validate, nil)
+	errs := Errors{NewLoadError("", nil, nil), Errors{NewTemplateDefinitionError("", nil)}}
+
+	cases := []struct {
+		name     string
+		err      error
+		target   error
+		is       func(err error) bool
+		expected bool
+		// result of errors.Is
+		expectedIs bool
+	}{
+		{"validate error", validate, ErrValidate, IsValidateError, true, true},
+		{"render error", render, ErrTemplateRender, IsTemplateRenderError, true, true},
+		// original error is only checked by errors.Is
+		{"render error caused by validate error", render, ErrValidate, IsValidateError, false, true},
+		{"load error in errors", errs, ErrLoad, IsLoadError, true, true},
+		{"definition error in nested errors", errs, ErrTemplateDefinition, IsTemplateDefinitionError, true, true},
+		{"not in errors", errs, ErrValidate, IsValidateError, false, false},
+		{"wrapped by fmt", fmt.Errorf("wrapped: %w", validate), ErrValidate, IsValidateError, false, true},
+		{"not Error", errors.New("validate error"), ErrValidate, IsValidateError, false, false},
+		{"nil", nil, ErrValidate, IsValidateError, false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := c.is(c.err); actual != c.expected {
+				t.Errorf("expected %t, but got %t", c.expected, actual)
+			}
+			if actual := errors.Is(c.err, c.target); actual != c.expectedIs {
+				t.Errorf("expected errors.Is %t, but got %t", c.expectedIs, actual)
+			}
+		})
+	}
+
+	// message of sentinel is matched when it is set
+	if !errors.Is(validate, Error{Code: CodeValidateError, Message: "value is invalid"}) || errors.Is(validate, Error{Code: CodeValidateError, Message: "other"}) {
+		t.Errorf("unexpected result of errors.Is with message")
+	}
+}
+
+func TestErrorsUnwrap(t *testing.T) {
+	load := NewLoadError("invalid yaml", nil, nil)
+	errs := Errors{NewValidateError("", nil), Errors{load}}
+
+	if unwrapped := errs.Unwrap(); len(unwrapped) != 2 {
+		t.Errorf("expected 2 errors, but got %v", unwrapped)
+	}
+	var typed Error
+	if !errors.As(errs, &typed) || typed.Code != CodeValidateError {
+		t.Errorf("expected the first Error, but got %#v", typed)
+	}
+}
+
+func TestErrorsFlatten(t *testing.T) {
+	a, b, c := NewValidateError("a", nil), NewValidateError("b", nil), errors.New("c")
+	errs := Errors{a, nil, Errors{b, Errors{}, Errors{c}}, nil}
+
+	if flattened := errs.Flatten(); !reflect.DeepEqual(flattened, Errors{a, b, c}) {
+		t.Errorf("unexpected flattened errors: %v", flattened)
+	}
+	if flattened := FlattenErrors(a); !reflect.DeepEqual(flattened, Errors{a}) {
+		t.Errorf("unexpected flattened errors: %v", flattened)
+	}
+	if flattened := FlattenErrors(nil); len(flattened) != 0 {
+		t.Errorf("unexpected flattened errors: %v", flattened)
+	}
+}
+
+func TestErrorsMarshalJSON(t *testing.T) {
+	errs := Errors{
+		Errors{WithPath(NewValidateError("value is invalid", nil), "spec")},
+		NewTemplateRenderError("render failed", errors.New("unexpected EOF"), nil),
+		errors.New("plain"),
+	}
+
+	byts, err := json.Marshal(errs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `[` +
+		`{"code":"ValidateError","message":"value is invalid","path":"spec"},` +
+		`{"code":"TemplateRenderError","message":"render failed","originalError":"unexpected EOF"},` +
+		`{"code":"","message":"plain","originalError":"plain"}]`
+	if string(byts) != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, byts)
+	}
+}
+
+func TestWithPath(t *testing.T) {
+	plain := errors.New("plain")
+	err := WithPath(Errors{
+		NewValidateError("a", nil),
+		WithPath(NewValidateError("b", nil), "[1].name"),
+		WithPath(NewValidateError("c", nil), PathKey("windcloud/version")),
+		plain,
+	}, "spec")
+
+	errs := err.(Errors)
+	expected := []string{"spec", "spec[1].name", `spec["windcloud/version"]`}
+	for i, path := range expected {
+		if actual := errs[i].(Error).Path; actual != path {
+			t.Errorf("expected path %s, but got %s", path, actual)
+		}
+	}
+	// errors that are not Error are kept as they are
+	if errs[3] != plain {
+		t.Errorf("unexpected error: %v", errs[3])
+	}
+	if WithPath(nil, "spec") != nil {
+		t.Errorf("nil should be kept")
+	}
+}
+
+func TestWithTaskAndArgument(t *testing.T) {
+	err := Errors{
+		NewValidateError("a", nil),
+		WithArgument(WithTask(NewValidateError("b", nil), "Test"), "tag"),
+	}
+
+	errs := WithArgument(WithTask(err, "Build"), "image").(Errors)
+	// names that have been set are not overridden
+	expected := [][2]string{{"Build", "image"}, {"Test", "tag"}}
+	for i, names := range expected {
+		typed := errs[i].(Error)
+		if typed.Task != names[0] || typed.Argument != names[1] {
+			t.Errorf("expected task %s and argument %s, but got %s and %s", names[0], names[1], typed.Task, typed.Argument)
+		}
+	}
+}
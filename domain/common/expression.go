@@ -0,0 +1,648 @@
+package common
+
+// a small expression language used by RelationWhen.Expression, eg:
+//   codeRepo.kind == "git" && (useCache or not (branch in ["master", "release"]))
+//   imageTag =~ "^v[0-9]+" and _system_.namespace != "prod"
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type exprTokenType int
+
+const (
+	exprTokenEOF exprTokenType = iota
+	exprTokenIdent
+	exprTokenString
+	exprTokenNumber
+	exprTokenOperator
+	exprTokenLeftParen
+	exprTokenRightParen
+	exprTokenLeftBracket
+	exprTokenRightBracket
+	exprTokenComma
+)
+
+type exprToken struct {
+	tokenType exprTokenType
+	value     string
+	pos       int
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "=~", "&&", "||", "<", ">", "!"}
+
+func tokenizeExpression(expr string) ([]exprToken, error) {
+	chars := []rune(expr)
+	tokens := []exprToken{}
+
+	for pos := 0; pos < len(chars); {
+		c := chars[pos]
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '(':
+			tokens = append(tokens, exprToken{exprTokenLeftParen, "(", pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, exprToken{exprTokenRightParen, ")", pos})
+			pos++
+		case c == '[':
+			tokens = append(tokens, exprToken{exprTokenLeftBracket, "[", pos})
+			pos++
+		case c == ']':
+			tokens = append(tokens, exprToken{exprTokenRightBracket, "]", pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, exprToken{exprTokenComma, ",", pos})
+			pos++
+		case c == '"' || c == '\'':
+			start := pos
+			var value strings.Builder
+			pos++
+			closed := false
+			for pos < len(chars) {
+				if chars[pos] == '\\' && pos+1 < len(chars) {
+					value.WriteRune(chars[pos+1])
+					pos += 2
+					continue
+				}
+				if chars[pos] == c {
+					closed = true
+					pos++
+					break
+				}
+				value.WriteRune(chars[pos])
+				pos++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, exprToken{exprTokenString, value.String(), start})
+		case unicode.IsDigit(c) || (c == '-' && pos+1 < len(chars) && unicode.IsDigit(chars[pos+1])):
+			start := pos
+			pos++
+			for pos < len(chars) && (unicode.IsDigit(chars[pos]) || chars[pos] == '.') {
+				pos++
+			}
+			tokens = append(tokens, exprToken{exprTokenNumber, string(chars[start:pos]), start})
+		case unicode.IsLetter(c) || c == '_':
+			start := pos
+			for pos < len(chars) && (unicode.IsLetter(chars[pos]) || unicode.IsDigit(chars[pos]) || chars[pos] == '_' || chars[pos] == '.' || chars[pos] == '-') {
+				pos++
+			}
+			tokens = append(tokens, exprToken{exprTokenIdent, string(chars[start:pos]), start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(chars[pos:]), op) {
+					tokens = append(tokens, exprToken{exprTokenOperator, op, pos})
+					pos += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+			}
+		}
+	}
+
+	tokens = append(tokens, exprToken{exprTokenEOF, "", len(chars)})
+	return tokens, nil
+}
+
+// Expression a parsed relation expression
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+type exprNode interface {
+	eval(values map[string]interface{}) interface{}
+}
+
+type exprLiteral struct {
+	value interface{}
+}
+
+type exprVariable struct {
+	path string
+}
+
+type exprList struct {
+	items []exprNode
+}
+
+type exprUnary struct {
+	operator string
+	operand  exprNode
+}
+
+type exprBinary struct {
+	operator string
+	left     exprNode
+	right    exprNode
+	regex    *regexp.Regexp
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// expressionCacheSize max number of parsed expressions that are cached, so long running processes do not grow without limit
+const expressionCacheSize = 1024
+
+// expressionCache parsed expressions keyed by source, an arbitrary one is evicted when it is full
+var expressionCache = struct {
+	sync.RWMutex
+	items map[string]*Expression
+}{items: map[string]*Expression{}}
+
+func loadCachedExpression(source string) (*Expression, bool) {
+	expressionCache.RLock()
+	defer expressionCache.RUnlock()
+	expression, ok := expressionCache.items[source]
+	return expression, ok
+}
+
+func cacheExpression(expression *Expression) {
+	expressionCache.Lock()
+	defer expressionCache.Unlock()
+	if _, ok := expressionCache.items[expression.source]; !ok && len(expressionCache.items) >= expressionCacheSize {
+		for source := range expressionCache.items {
+			delete(expressionCache.items, source)
+			break
+		}
+	}
+	expressionCache.items[expression.source] = expression
+}
+
+// ParseExpression parse expression text, parsed result will be cached
+func ParseExpression(source string) (*Expression, error) {
+	if cached, ok := loadCachedExpression(source); ok {
+		return cached, nil
+	}
+
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse expression `%s` error: %s", source, err.Error())
+	}
+
+	parser := &exprParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err == nil && parser.peek().tokenType != exprTokenEOF {
+		err = fmt.Errorf("unexpected `%s` at position %d", parser.peek().value, parser.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse expression `%s` error: %s", source, err.Error())
+	}
+
+	expression := &Expression{source: source, root: root}
+	cacheExpression(expression)
+	return expression, nil
+}
+
+// Evaluate evaluate expression with values, result is truthy or not
+func (expr *Expression) Evaluate(values map[string]interface{}) bool {
+	return truthy(expr.root.eval(values))
+}
+
+// Variables return variable paths referred by expression
+func (expr *Expression) Variables() []string {
+	vars := []string{}
+	var walk func(node exprNode)
+	walk = func(node exprNode) {
+		switch n := node.(type) {
+		case *exprVariable:
+			vars = append(vars, n.path)
+		case *exprList:
+			for _, item := range n.items {
+				walk(item)
+			}
+		case *exprUnary:
+			walk(n.operand)
+		case *exprBinary:
+			walk(n.left)
+			walk(n.right)
+		}
+	}
+	walk(expr.root)
+	return vars
+}
+
+func (expr *Expression) String() string {
+	return expr.source
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.tokenType == exprTokenIdent && t.value == word
+}
+
+func (p *exprParser) isOperator(op string) bool {
+	t := p.peek()
+	return t.tokenType == exprTokenOperator && t.value == op
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") || p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{operator: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") || p.isOperator("&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{operator: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isKeyword("not") || p.isOperator("!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{operator: "not", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	var operator string
+	switch {
+	case t.tokenType == exprTokenOperator && t.value != "!" && t.value != "&&" && t.value != "||":
+		operator = p.next().value
+	case p.isKeyword("in"):
+		p.next()
+		operator = "in"
+	case p.isKeyword("matches"):
+		p.next()
+		operator = "=~"
+	case p.isKeyword("not"):
+		p.next()
+		if !p.isKeyword("in") {
+			return nil, fmt.Errorf("expect `in` after `not` at position %d", p.peek().pos)
+		}
+		p.next()
+		operator = "not in"
+	default:
+		return left, nil
+	}
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &exprBinary{operator: operator, left: left, right: right}
+	if operator == "=~" {
+		if literal, ok := right.(*exprLiteral); ok {
+			pattern, ok := literal.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("regex pattern should be string, but got %v", literal.value)
+			}
+			node.regex, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex pattern `%s`: %s", pattern, err.Error())
+			}
+		}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.tokenType {
+	case exprTokenLeftParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().tokenType != exprTokenRightParen {
+			return nil, fmt.Errorf("expect `)` at position %d", p.peek().pos)
+		}
+		p.next()
+		return node, nil
+	case exprTokenLeftBracket:
+		list := &exprList{items: []exprNode{}}
+		if p.peek().tokenType == exprTokenRightBracket {
+			p.next()
+			return list, nil
+		}
+		for {
+			item, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if p.peek().tokenType == exprTokenComma {
+				p.next()
+				continue
+			}
+			if p.peek().tokenType == exprTokenRightBracket {
+				p.next()
+				return list, nil
+			}
+			return nil, fmt.Errorf("expect `,` or `]` at position %d", p.peek().pos)
+		}
+	case exprTokenString:
+		return &exprLiteral{value: t.value}, nil
+	case exprTokenNumber:
+		number, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number `%s` at position %d", t.value, t.pos)
+		}
+		return &exprLiteral{value: number}, nil
+	case exprTokenIdent:
+		switch t.value {
+		case "true":
+			return &exprLiteral{value: true}, nil
+		case "false":
+			return &exprLiteral{value: false}, nil
+		case "null", "nil":
+			return &exprLiteral{value: nil}, nil
+		case "and", "or", "not", "in", "matches":
+			return nil, fmt.Errorf("unexpected `%s` at position %d", t.value, t.pos)
+		}
+		return &exprVariable{path: t.value}, nil
+	case exprTokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected `%s` at position %d", t.value, t.pos)
+}
+
+func (n *exprLiteral) eval(values map[string]interface{}) interface{} {
+	return n.value
+}
+
+func (n *exprVariable) eval(values map[string]interface{}) interface{} {
+	v, _ := LookupValue(values, n.path)
+	return v
+}
+
+func (n *exprList) eval(values map[string]interface{}) interface{} {
+	items := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		items = append(items, item.eval(values))
+	}
+	return items
+}
+
+func (n *exprUnary) eval(values map[string]interface{}) interface{} {
+	return !truthy(n.operand.eval(values))
+}
+
+func (n *exprBinary) eval(values map[string]interface{}) interface{} {
+	switch n.operator {
+	case "and":
+		return truthy(n.left.eval(values)) && truthy(n.right.eval(values))
+	case "or":
+		return truthy(n.left.eval(values)) || truthy(n.right.eval(values))
+	}
+
+	left := n.left.eval(values)
+	right := n.right.eval(values)
+	switch n.operator {
+	case "==":
+		return valueEqual(left, right)
+	case "!=":
+		return !valueEqual(left, right)
+	case "<", "<=", ">", ">=":
+		return valueCompare(n.operator, left, right)
+	case "in":
+		return valueIn(left, right)
+	case "not in":
+		return !valueIn(left, right)
+	case "=~":
+		if left == nil {
+			return false
+		}
+		regex := n.regex
+		if regex == nil {
+			var err error
+			if regex, err = regexp.Compile(fmt.Sprint(right)); err != nil {
+				return false
+			}
+		}
+		return regex.MatchString(fmt.Sprint(left))
+	}
+	return false
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			return b
+		}
+		return v != ""
+	}
+	if f, ok := toFloat(value); ok {
+		return f != 0
+	}
+	return true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func valueEqual(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		return lf == rf
+	}
+	return fmt.Sprint(left) == fmt.Sprint(right)
+}
+
+func valueCompare(operator string, left, right interface{}) bool {
+	if left == nil || right == nil {
+		return false
+	}
+
+	var cmp int
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(fmt.Sprint(left), fmt.Sprint(right))
+	}
+
+	switch operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func valueIn(item, collection interface{}) bool {
+	switch c := collection.(type) {
+	case nil:
+		return false
+	case string:
+		return item != nil && strings.Contains(c, fmt.Sprint(item))
+	case map[string]interface{}:
+		_, ok := c[fmt.Sprint(item)]
+		return ok
+	}
+
+	rv := reflect.ValueOf(collection)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			if valueEqual(item, rv.Index(i).Interface()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LookupValue find value by path like `codeRepo.kind` or `_system_.namespace`.
+// the whole path will be used as name firstly, so names that contains dot still work.
+func LookupValue(values map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := values[path]; ok {
+		return v, true
+	}
+
+	segments := strings.Split(path, ".")
+	var current interface{} = values
+	for _, segment := range segments {
+		next, ok := lookupField(current, segment)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+func lookupField(value interface{}, name string) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		field, ok := v[name]
+		return field, ok
+	case map[interface{}]interface{}:
+		field, ok := v[name]
+		return field, ok
+	case string:
+		// some arguments' value is object in json format, eg: windcloud/coderepositorymix
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &obj); err != nil {
+			return nil, false
+		}
+		return lookupField(obj, name)
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		field := rv.FieldByNameFunc(func(fieldName string) bool {
+			return strings.EqualFold(fieldName, name)
+		})
+		if !field.IsValid() || !field.CanInterface() {
+			return nil, false
+		}
+		return field.Interface(), true
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		field := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !field.IsValid() {
+			return nil, false
+		}
+		return field.Interface(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,148 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpressionEvaluate(t *testing.T) {
+	values := map[string]interface{}{
+		"imageTag": "v1.2.0",
+		"branch":   "master",
+		"useCache": true,
+		"replicas": 3,
+		"ratio":    0.5,
+		"empty":    "",
+		"codeRepo": map[string]interface{}{"kind": "git", "owner": map[string]interface{}{"name": "dev"}},
+		// some arguments' value is object in json format
+		"imageRepo": `{"registry": "harbor", "tags": ["latest"]}`,
+		"_system_":  map[string]interface{}{"namespace": "prod", "jenkins": map[string]interface{}{"historyCount": 30}},
+	}
+
+	cases := []struct {
+		expression string
+		expected   bool
+	}{
+		{`branch == "master"`, true},
+		{`branch != 'master'`, false},
+		{`replicas > 2`, true},
+		{`replicas >= 3 && replicas <= 3`, true},
+		{`replicas < 3`, false},
+		{`ratio < 1`, true},
+		{`replicas == 3.0`, true},
+		{`branch in ["master", "release"]`, true},
+		{`branch not in ["master", "release"]`, false},
+		{`"ma" in branch`, true},
+		{`"kind" in codeRepo`, true},
+		{`imageTag =~ "^v[0-9]+"`, true},
+		{`imageTag matches "^release-"`, false},
+		{`codeRepo.kind == "git"`, true},
+		{`codeRepo.owner.name == "dev"`, true},
+		{`codeRepo.missing == null`, true},
+		{`imageRepo.registry == "harbor"`, true},
+		{`_system_.namespace != "prod"`, false},
+		{`_system_.jenkins.historyCount > 10`, true},
+		{`useCache`, true},
+		{`empty`, false},
+		{`missing`, false},
+		{`not useCache`, false},
+		{`!(branch == "dev")`, true},
+		{`codeRepo.kind == "git" && (useCache or not (branch in ["master", "release"]))`, true},
+		{`branch == "dev" or replicas > 2 and useCache`, true},
+		{`(branch == "dev" or replicas > 2) and not useCache`, false},
+		{`branch == "dev" || branch == "master"`, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expression, func(t *testing.T) {
+			expression, err := ParseExpression(c.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := expression.Evaluate(values); actual != c.expected {
+				t.Errorf("expected %t, but got %t", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseExpressionError(t *testing.T) {
+	cases := []struct {
+		expression string
+		message    string
+	}{
+		{`branch == "master`, "unterminated string"},
+		{`branch == `, "unexpected end of expression"},
+		{`(branch == "master"`, "expect `)`"},
+		{`branch in ["a" "b"]`, "expect `,` or `]`"},
+		{`branch not "a"`, "expect `in` after `not`"},
+		{`branch == "a" "b"`, "unexpected `b`"},
+		{`branch =~ "["`, "invalid regex pattern"},
+		{`branch =~ 1`, "regex pattern should be string"},
+		{`branch # 1`, "unexpected character"},
+		{`and == 1`, "unexpected `and`"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expression, func(t *testing.T) {
+			_, err := ParseExpression(c.expression)
+			if err == nil {
+				t.Fatalf("expected error, but got nil")
+			}
+			if !strings.Contains(err.Error(), c.message) {
+				t.Errorf("expected error contains %q, but got %q", c.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestExpressionVariables(t *testing.T) {
+	expression, err := ParseExpression(`codeRepo.kind == "git" and branch in [defaultBranch, "master"] or not _system_.namespace`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"codeRepo.kind", "branch", "defaultBranch", "_system_.namespace"}
+	if actual := expression.Variables(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, but got %v", expected, actual)
+	}
+}
+
+func TestExpressionCacheIsBounded(t *testing.T) {
+	for i := 0; i < expressionCacheSize*2; i++ {
+		if _, err := ParseExpression(fmt.Sprintf("replicas == %d", i)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expressionCache.RLock()
+	size := len(expressionCache.items)
+	expressionCache.RUnlock()
+	if size > expressionCacheSize {
+		t.Errorf("expected at most %d cached expressions, but got %d", expressionCacheSize, size)
+	}
+}
+
+func TestRelationWhenValidateExpression(t *testing.T) {
+	when := RelationWhen{Expression: `branch == "master`}
+	err := when.ValidateDefinition()
+	if err == nil {
+		t.Fatalf("expected error, but got nil")
+	}
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected one error, but got %v", err)
+	}
+	if typed, ok := errs[0].(Error); !ok || typed.Path != "expression" {
+		t.Errorf("expected error at expression, but got %#v", errs[0])
+	}
+	if !strings.Contains(errs[0].Error(), "unterminated string") {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+
+	when = RelationWhen{Expression: `branch == "master"`}
+	if err := when.ValidateDefinition(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	LocaleZHCN = "zh-CN"
+	LocaleEN   = "en"
+)
+
+// LocaleConfig which locales are required in definition and how to fallback when localize
+type LocaleConfig struct {
+	// Required locales should be set for display name of templates and arguments
+	Required []string
+	// Fallbacks locales that will be tried in order when value of a locale is missing
+	Fallbacks map[string][]string
+	// Default locale that will be tried at last
+	Default string
+}
+
+// locales current locale config, it is read by every Localize, so it is guarded by localesLock
+var (
+	localesLock sync.RWMutex
+	locales     = LocaleConfig{
+		Required:  []string{LocaleZHCN, LocaleEN},
+		Fallbacks: map[string][]string{},
+		Default:   LocaleEN,
+	}
+)
+
+// GetLocaleConfig return current locale config, it should not be changed, use SetLocaleConfig instead
+func GetLocaleConfig() LocaleConfig {
+	localesLock.RLock()
+	defer localesLock.RUnlock()
+	return locales
+}
+
+// SetLocaleConfig replace current locale config, config is copied so it is safe to change it after
+func SetLocaleConfig(config LocaleConfig) {
+	copied := LocaleConfig{
+		Required:  append([]string{}, config.Required...),
+		Fallbacks: make(map[string][]string, len(config.Fallbacks)),
+		Default:   config.Default,
+	}
+	for locale, fallbacks := range config.Fallbacks {
+		copied.Fallbacks[locale] = append([]string{}, fallbacks...)
+	}
+	if copied.Default == "" {
+		copied.Default = LocaleEN
+	}
+
+	localesLock.Lock()
+	defer localesLock.Unlock()
+	locales = copied
+}
+
+// fallbackChain return locales that should be tried in order for locale
+// eg: de-AT -> de-AT, de, <fallbacks of de-AT>, <fallbacks of de>, <default>, <required locales>
+func (config LocaleConfig) fallbackChain(locale string) []string {
+	chain := []string{}
+	add := func(locales ...string) {
+		for _, l := range locales {
+			if l == "" {
+				continue
+			}
+			exists := false
+			for _, c := range chain {
+				if c == l {
+					exists = true
+					break
+				}
+			}
+			if !exists {
+				chain = append(chain, l)
+			}
+		}
+	}
+
+	add(locale)
+	base := strings.SplitN(locale, "-", 2)[0]
+	add(base)
+	add(config.Fallbacks[locale]...)
+	add(config.Fallbacks[base]...)
+	add(config.Default)
+	add(config.Required...)
+	return chain
+}
+
+// MulitLangValue value in multi languages, keyed by locale, eg: zh-CN, en, ja, de.
+// breaking change: it used to be a struct with fields ZH_CN and EN, go callers should use
+// value[LocaleZHCN] or value.ZH_CN() instead of value.ZH_CN, and MulitLangValue{LocaleEN: "..."} as literal.
+// json of it is compatible, see MarshalJSON
+type MulitLangValue map[string]string
+
+// MarshalJSON zh-CN and en are always present even if they are empty, the same as the struct it used to be
+func (value MulitLangValue) MarshalJSON() ([]byte, error) {
+	values := make(map[string]string, len(value)+2)
+	values[LocaleZHCN] = ""
+	values[LocaleEN] = ""
+	for locale, v := range value {
+		values[locale] = v
+	}
+	return json.Marshal(values)
+}
+
+// Localize get value of locale, it will fallback according to Locales when the value of locale is missing
+func (value MulitLangValue) Localize(locale string) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	for _, l := range GetLocaleConfig().fallbackChain(locale) {
+		if v := strings.TrimSpace(value[l]); v != "" {
+			return value[l]
+		}
+	}
+	return ""
+}
+
+// ZH_CN value of zh-CN, it replaces field ZH_CN of the struct MulitLangValue used to be
+func (value MulitLangValue) ZH_CN() string {
+	return value[LocaleZHCN]
+}
+
+// EN value of en, it replaces field EN of the struct MulitLangValue used to be
+func (value MulitLangValue) EN() string {
+	return value[LocaleEN]
+}
+
+// MissingLocales return required locales that value is missing
+func (value MulitLangValue) MissingLocales() []string {
+	missing := []string{}
+	for _, locale := range GetLocaleConfig().Required {
+		if strings.TrimSpace(value[locale]) == "" {
+			missing = append(missing, locale)
+		}
+	}
+	return missing
+}
+
+// ValidateRequired validate all required locales are set, field is used in error message
+func (value MulitLangValue) ValidateRequired(field string) error {
+	errs := Errors{}
+	for _, locale := range value.MissingLocales() {
+		errs = append(errs, WithPath(NewTemplateDefinitionError(fmt.Sprintf("%s.%s is required", field, locale), nil), PathKey(locale)))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
+
+// MulitLangValueFromPrefix collect values whose key has prefix, eg: `windcloud/displayName.` in annotations
+func MulitLangValueFromPrefix(values map[string]interface{}, prefix string) MulitLangValue {
+	value := MulitLangValue{}
+	for key, v := range values {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if str, ok := v.(string); ok {
+			value[strings.TrimPrefix(key, prefix)] = str
+		}
+	}
+	return value
+}
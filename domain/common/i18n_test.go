@@ -0,0 +1,104 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	defer SetLocaleConfig(GetLocaleConfig())
+
+	config := LocaleConfig{
+		Required:  []string{LocaleEN},
+		Fallbacks: map[string][]string{"de": {"fr"}},
+		Default:   LocaleZHCN,
+	}
+	SetLocaleConfig(config)
+	// config is copied
+	config.Fallbacks["de"][0] = "ja"
+	config.Required[0] = "ja"
+
+	value := MulitLangValue{"de": "Bauen", "fr": "Construire", LocaleZHCN: "构建", LocaleEN: "Build"}
+	cases := []struct {
+		value    MulitLangValue
+		locale   string
+		expected string
+	}{
+		{value, "de-AT", "Bauen"},
+		{MulitLangValue{"fr": "Construire", LocaleEN: "Build"}, "de-AT", "Construire"},
+		{MulitLangValue{LocaleZHCN: "构建", LocaleEN: "Build"}, "de", "构建"},
+		{MulitLangValue{LocaleEN: "Build", "de": " "}, "de", "Build"},
+		{MulitLangValue{}, "de", ""},
+	}
+	for _, c := range cases {
+		if actual := c.value.Localize(c.locale); actual != c.expected {
+			t.Errorf("%v: expected %q of %s, but got %q", c.value, c.expected, c.locale, actual)
+		}
+	}
+
+	if missing := (MulitLangValue{LocaleZHCN: "构建"}).MissingLocales(); !reflect.DeepEqual(missing, []string{LocaleEN}) {
+		t.Errorf("expected missing [en], but got %v", missing)
+	}
+}
+
+func TestMulitLangValueAccessors(t *testing.T) {
+	value := MulitLangValue{LocaleZHCN: "构建", LocaleEN: "Build"}
+	if value.ZH_CN() != "构建" || value.EN() != "Build" {
+		t.Errorf("unexpected value: zh-CN=%q en=%q", value.ZH_CN(), value.EN())
+	}
+}
+
+// TestLocaleConfigConcurrently run it with -race
+func TestLocaleConfigConcurrently(t *testing.T) {
+	defer SetLocaleConfig(GetLocaleConfig())
+
+	value := MulitLangValue{LocaleZHCN: "构建", LocaleEN: "Build"}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if i%2 == 0 {
+					SetLocaleConfig(LocaleConfig{Required: []string{LocaleEN}, Default: LocaleZHCN})
+					continue
+				}
+				if value.Localize("de") == "" {
+					t.Errorf("value should be localized")
+				}
+				value.MissingLocales()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMulitLangValueJSON(t *testing.T) {
+	cases := []struct {
+		value    MulitLangValue
+		expected string
+	}{
+		{nil, `{"en":"","zh-CN":""}`},
+		{MulitLangValue{LocaleEN: "Build"}, `{"en":"Build","zh-CN":""}`},
+		{MulitLangValue{LocaleZHCN: "构建", "de": "Bauen"}, `{"de":"Bauen","en":"","zh-CN":"构建"}`},
+	}
+	for _, c := range cases {
+		byts, err := json.Marshal(c.value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(byts) != c.expected {
+			t.Errorf("expected %s, but got %s", c.expected, byts)
+		}
+
+		decoded := MulitLangValue{}
+		if err := json.Unmarshal(byts, &decoded); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if decoded.ZH_CN() != c.value.ZH_CN() || decoded.EN() != c.value.EN() {
+			t.Errorf("expected %v, but got %v", c.value, decoded)
+		}
+	}
+}
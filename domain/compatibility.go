@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/otiszv/render/domain/arguments"
+	"github.com/otiszv/render/domain/common"
+)
+
+// BreakingChange a change of arguments that will break users of the old version
+type BreakingChange struct {
+	Argument string
+	Reason   string
+}
+
+func (change BreakingChange) String() string {
+	return fmt.Sprintf("argument %s %s", change.Argument, change.Reason)
+}
+
+// DetectBreakingChanges compare arguments of two versions of the same template,
+// removed arguments, newly required arguments and type changes are breaking changes.
+func DetectBreakingChanges(oldArgs []arguments.ArgItem, newArgs []arguments.ArgItem) []BreakingChange {
+	changes := []BreakingChange{}
+
+	oldArgsMap := make(map[string]arguments.ArgItem, len(oldArgs))
+	for _, arg := range oldArgs {
+		oldArgsMap[arg.Name] = arg
+	}
+	newArgsMap := make(map[string]arguments.ArgItem, len(newArgs))
+	for _, arg := range newArgs {
+		newArgsMap[arg.Name] = arg
+	}
+
+	for _, oldArg := range oldArgs {
+		if _, ok := newArgsMap[oldArg.Name]; !ok {
+			changes = append(changes, BreakingChange{Argument: oldArg.Name, Reason: "is removed"})
+		}
+	}
+
+	for _, newArg := range newArgs {
+		requiredWithoutDefault := newArg.Required && newArg.Default == nil
+
+		oldArg, ok := oldArgsMap[newArg.Name]
+		if !ok {
+			if requiredWithoutDefault {
+				changes = append(changes, BreakingChange{Argument: newArg.Name, Reason: "is added as required argument without default value"})
+			}
+			continue
+		}
+
+		if requiredWithoutDefault && !(oldArg.Required && oldArg.Default == nil) {
+			changes = append(changes, BreakingChange{Argument: newArg.Name, Reason: "becomes required without default value"})
+		}
+
+		oldType, newType := argTypeName(&oldArg), argTypeName(&newArg)
+		if oldType != newType {
+			changes = append(changes, BreakingChange{Argument: newArg.Name, Reason: fmt.Sprintf("changes type from %s to %s", oldType, newType)})
+		}
+	}
+
+	return changes
+}
+
+// ValidateUpgrade report breaking changes between two versions of arguments when the version is not bumped properly
+func ValidateUpgrade(name string, oldVersion string, newVersion string, oldArgs []arguments.ArgItem, newArgs []arguments.ArgItem) error {
+	oldV, err := ParseVersion(oldVersion)
+	if err != nil {
+		return common.NewTemplateDefinitionError(err.Error(), nil)
+	}
+	newV, err := ParseVersion(newVersion)
+	if err != nil {
+		return common.NewTemplateDefinitionError(err.Error(), nil)
+	}
+
+	if newV.IsBreakingUpgradeFrom(oldV) {
+		return nil
+	}
+
+	errs := common.Errors{}
+	for _, change := range DetectBreakingChanges(oldArgs, newArgs) {
+		errs = append(errs, common.NewTemplateDefinitionError(
+			fmt.Sprintf("%s %s -> %s: %s, major version should be bumped", name, oldVersion, newVersion, change), nil))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateVersions validate versions of all templates in catalog,
+// versions should be semantic versions, and breaking changes between adjacent versions should bump major version.
+func (catalog *TemplateCatalog) ValidateVersions() error {
+	errs := common.Errors{}
+
+	check := func(items []*CatalogItem, getArgs func(item *CatalogItem) ([]arguments.ArgItem, error)) {
+		byName := map[string][]*CatalogItem{}
+		names := []string{}
+		for _, item := range items {
+			if _, err := ParseVersion(item.Version); err != nil {
+				errs = append(errs, common.NewTemplateDefinitionError(fmt.Sprintf("%s(%s): %s", item.Name, item.File, err.Error()), nil))
+				continue
+			}
+			// templates of different scopes are versioned separately
+			name := item.scopedName(item.Name)
+			if _, ok := byName[name]; !ok {
+				names = append(names, name)
+			}
+			byName[name] = append(byName[name], item)
+		}
+
+		for _, name := range names {
+			versions := byName[name]
+			sortCatalogItems(versions)
+
+			for i := 1; i < len(versions); i++ {
+				older, newer := versions[i-1], versions[i]
+				if older.Version == newer.Version {
+					errs = append(errs, common.NewTemplateDefinitionError(fmt.Sprintf("%s is duplicated in %s and %s", newer.Key(), older.File, newer.File), nil))
+					continue
+				}
+
+				oldArgs, err := getArgs(older)
+				if err != nil {
+					continue
+				}
+				newArgs, err := getArgs(newer)
+				if err != nil {
+					continue
+				}
+				if err := ValidateUpgrade(name, older.Version, newer.Version, oldArgs, newArgs); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	check(catalog.PipelineTemplates, func(item *CatalogItem) ([]arguments.ArgItem, error) {
+		definition := JenkinsPipelineTemplateDefinition(*item.Kube)
+		spec, err := definition.PipelineTemplateSpec()
+		if err != nil {
+			return nil, err
+		}
+		if spec.IsExtended() {
+			if spec, err = spec.Expand(catalog.ResolvePipelineTemplate); err != nil {
+				return nil, err
+			}
+		}
+		return spec.Arguments.AllArgItems(), nil
+	})
+
+	check(catalog.TaskTemplates, func(item *CatalogItem) ([]arguments.ArgItem, error) {
+		definition := JenkinsPipelineTaskTemplateDefinition(*item.Kube)
+		spec, err := definition.PipelineTaskTemplateSpec()
+		if err != nil {
+			return nil, err
+		}
+		return spec.Arguments, nil
+	})
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// sortCatalogItems sort items by semantic version ascending, invalid versions are the lowest
+func sortCatalogItems(items []*CatalogItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		vi, erri := ParseVersion(items[i].Version)
+		vj, errj := ParseVersion(items[j].Version)
+		if erri != nil || errj != nil {
+			return erri != nil && errj == nil
+		}
+		return vi.Compare(vj) < 0
+	})
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/domain/arguments"
+	"github.com/otiszv/render/domain/common"
+)
+
+func compatibilityTestArg(name string, typ string, required bool, defaultValue interface{}) arguments.ArgItem {
+	return arguments.ArgItem{Name: name, Schema: &arguments.ArgItemSchema{Type: typ}, Required: required, Default: defaultValue}
+}
+
+func TestDetectBreakingChanges(t *testing.T) {
+	oldArgs := []arguments.ArgItem{
+		compatibilityTestArg("image", "string", true, nil),
+		compatibilityTestArg("tag", "string", false, nil),
+		compatibilityTestArg("replicas", "int", false, nil),
+		compatibilityTestArg("debug", "boolean", false, nil),
+	}
+	newArgs := []arguments.ArgItem{
+		compatibilityTestArg("image", "string", true, nil),
+		compatibilityTestArg("tag", "string", true, nil),
+		compatibilityTestArg("replicas", "string", false, nil),
+		compatibilityTestArg("timeout", "int", true, nil),
+		// required arguments with default value are compatible
+		compatibilityTestArg("registry", "string", true, "harbor"),
+	}
+
+	changes := []string{}
+	for _, change := range DetectBreakingChanges(oldArgs, newArgs) {
+		changes = append(changes, change.String())
+	}
+	expected := []string{
+		"argument debug is removed",
+		"argument tag becomes required without default value",
+		"argument replicas changes type from int to string",
+		"argument timeout is added as required argument without default value",
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, but got %v", expected, changes)
+	}
+
+	if changes := DetectBreakingChanges(oldArgs, oldArgs); len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+}
+
+func TestValidateUpgrade(t *testing.T) {
+	oldArgs := []arguments.ArgItem{compatibilityTestArg("image", "string", true, nil)}
+	newArgs := []arguments.ArgItem{}
+
+	cases := []struct {
+		oldVersion string
+		newVersion string
+		breaking   bool
+	}{
+		{"v1.2.0", "v1.3.0", true},
+		{"v1.2.0", "v2.0.0", false},
+		{"v0.1.0", "v0.1.1", true},
+		{"v0.1.0", "v0.2.0", false},
+		{"v0.0.1", "v0.0.2", false},
+		{"v0.0.1", "v0.0.1-beta", true},
+	}
+	for _, c := range cases {
+		t.Run(c.oldVersion+"->"+c.newVersion, func(t *testing.T) {
+			err := ValidateUpgrade("build", c.oldVersion, c.newVersion, oldArgs, newArgs)
+			if !c.breaking {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			errs := common.FlattenErrors(err)
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), "argument image is removed, major version should be bumped") {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+		})
+	}
+
+	if err := ValidateUpgrade("build", "latest", "v1.0.0", oldArgs, newArgs); err == nil {
+		t.Errorf("expected error of invalid version")
+	}
+}
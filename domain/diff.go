@@ -0,0 +1,314 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/otiszv/render/domain/arguments"
+)
+
+// kinds of TemplateChange
+const (
+	ChangeAdded     = "added"
+	ChangeRemoved   = "removed"
+	ChangeChanged   = "changed"
+	ChangeReordered = "reordered"
+	ChangeMoved     = "moved"
+)
+
+// TemplateChange a semantic change between two versions of template
+type TemplateChange struct {
+	Kind string      `json:"kind"`
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+func (change TemplateChange) String() string {
+	switch change.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s", change.Path)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s", change.Path)
+	case ChangeReordered:
+		return fmt.Sprintf("~ %s reordered: %s -> %s", change.Path, jsonString(change.Old), jsonString(change.New))
+	case ChangeMoved:
+		return fmt.Sprintf("~ %s moved: %s -> %s", change.Path, jsonString(change.Old), jsonString(change.New))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", change.Path, jsonString(change.Old), jsonString(change.New))
+}
+
+// DiffPipelineTemplateSpec compare two pipeline templates semantically
+func DiffPipelineTemplateSpec(source *PipelineTemplateSpec, target *PipelineTemplateSpec) []TemplateChange {
+	changes := []TemplateChange{}
+
+	changes = appendValueChange(changes, "engine", source.Engine, target.Engine)
+	changes = appendValueChange(changes, "withSCM", source.IsWithSCM(), target.IsWithSCM())
+	changes = appendValueChange(changes, "agent", source.Agent, target.Agent)
+	changes = appendValueChange(changes, "options", source.Options, target.Options)
+	changes = appendValueChange(changes, "environments", source.Environments, target.Environments)
+	changes = appendValueChange(changes, "extends", source.Extends, target.Extends)
+	changes = appendValueChange(changes, "patches", source.Patches, target.Patches)
+
+	// stages
+	oldStages, newStages := map[string]*Stage{}, map[string]*Stage{}
+	oldStageNames, newStageNames := []string{}, []string{}
+	for _, stage := range source.Stages {
+		oldStages[stage.Name] = stage
+		oldStageNames = append(oldStageNames, stage.Name)
+	}
+	for _, stage := range target.Stages {
+		newStages[stage.Name] = stage
+		newStageNames = append(newStageNames, stage.Name)
+	}
+	changes = append(changes, diffNames("stages", oldStageNames, newStageNames)...)
+	for _, name := range newStageNames {
+		if oldStage, ok := oldStages[name]; ok {
+			changes = appendValueChange(changes, fmt.Sprintf("stages/%s.conditions", name), oldStage.Conditions, newStages[name].Conditions)
+			changes = appendValueChange(changes, fmt.Sprintf("stages/%s.agent", name), oldStage.Agent, newStages[name].Agent)
+			changes = appendValueChange(changes, fmt.Sprintf("stages/%s.environments", name), oldStage.Environments, newStages[name].Environments)
+		}
+	}
+
+	// tasks
+	oldTasks, oldTaskStages := tasksIndex(source)
+	newTasks, newTaskStages := tasksIndex(target)
+	for _, stage := range target.Stages {
+		oldStage, ok := oldStages[stage.Name]
+		if !ok {
+			continue
+		}
+		oldNames, newNames := []string{}, []string{}
+		for _, task := range oldStage.Tasks {
+			if newTaskStages[task.Name] == stage.Name {
+				oldNames = append(oldNames, task.Name)
+			}
+		}
+		for _, task := range stage.Tasks {
+			if oldTaskStages[task.Name] == stage.Name {
+				newNames = append(newNames, task.Name)
+			}
+		}
+		changes = append(changes, diffNames(fmt.Sprintf("stages/%s/tasks", stage.Name), oldNames, newNames)...)
+	}
+	for _, task := range source.allTasks() {
+		if _, ok := newTasks[task.Name]; !ok {
+			changes = append(changes, TemplateChange{Kind: ChangeRemoved, Path: "tasks/" + task.Name})
+		}
+	}
+	for _, task := range target.allTasks() {
+		oldTask, ok := oldTasks[task.Name]
+		if !ok {
+			changes = append(changes, TemplateChange{Kind: ChangeAdded, Path: "tasks/" + task.Name, New: newTaskStages[task.Name]})
+			continue
+		}
+		if oldTaskStages[task.Name] != newTaskStages[task.Name] {
+			changes = append(changes, TemplateChange{Kind: ChangeMoved, Path: "tasks/" + task.Name, Old: oldTaskStages[task.Name], New: newTaskStages[task.Name]})
+		}
+		changes = append(changes, diffTask("tasks/"+task.Name, oldTask, task)...)
+	}
+
+	// post
+	conditions := sortedKeys(source.Post, target.Post)
+	for _, condition := range conditions {
+		path := "post/" + condition
+		oldPost, oldOk := source.Post[condition]
+		newPost, newOk := target.Post[condition]
+		if !newOk {
+			changes = append(changes, TemplateChange{Kind: ChangeRemoved, Path: path})
+			continue
+		}
+		if !oldOk {
+			changes = append(changes, TemplateChange{Kind: ChangeAdded, Path: path})
+			continue
+		}
+		oldNames, newNames := []string{}, []string{}
+		oldPostTasks := map[string]*Task{}
+		for _, task := range oldPost {
+			oldNames = append(oldNames, task.Name)
+			oldPostTasks[task.Name] = task
+		}
+		for _, task := range newPost {
+			newNames = append(newNames, task.Name)
+		}
+		changes = append(changes, diffNames(path, oldNames, newNames)...)
+		for _, task := range newPost {
+			if oldTask, ok := oldPostTasks[task.Name]; ok {
+				changes = append(changes, diffTask(path+"/"+task.Name, oldTask, task)...)
+			}
+		}
+	}
+
+	// arguments
+	changes = append(changes, DiffArguments("arguments", source.Arguments.AllArgItems(), target.Arguments.AllArgItems())...)
+
+	// const values
+	var oldValues, newValues map[string]*TaskConstValue
+	if source.ConstValues != nil {
+		oldValues = source.ConstValues.Tasks
+	}
+	if target.ConstValues != nil {
+		newValues = target.ConstValues.Tasks
+	}
+	for _, taskName := range sortedKeys(oldValues, newValues) {
+		changes = appendValueChange(changes, "values/"+taskName, oldValues[taskName], newValues[taskName])
+	}
+
+	return changes
+}
+
+// DiffTaskTemplateSpec compare two task templates semantically
+func DiffTaskTemplateSpec(source *TaskTemplateSpec, target *TaskTemplateSpec) []TemplateChange {
+	changes := []TemplateChange{}
+	changes = appendValueChange(changes, "engine", source.Engine, target.Engine)
+	changes = appendValueChange(changes, "agent", source.Agent, target.Agent)
+	if source.Body != target.Body {
+		changes = append(changes, TemplateChange{Kind: ChangeChanged, Path: "body", Old: source.Body, New: target.Body})
+	}
+	changes = append(changes, DiffArguments("arguments", source.Arguments, target.Arguments)...)
+	return changes
+}
+
+// DiffArguments compare two versions of arguments
+func DiffArguments(path string, oldArgs []arguments.ArgItem, newArgs []arguments.ArgItem) []TemplateChange {
+	changes := []TemplateChange{}
+
+	oldArgsMap := map[string]arguments.ArgItem{}
+	oldNames, newNames := []string{}, []string{}
+	for _, arg := range oldArgs {
+		oldArgsMap[arg.Name] = arg
+		oldNames = append(oldNames, arg.Name)
+	}
+	for _, arg := range newArgs {
+		newNames = append(newNames, arg.Name)
+	}
+	changes = append(changes, diffNames(path, oldNames, newNames)...)
+
+	for _, newArg := range newArgs {
+		oldArg, ok := oldArgsMap[newArg.Name]
+		if !ok {
+			continue
+		}
+		argPath := fmt.Sprintf("%s/%s", path, newArg.Name)
+		if argTypeName(&oldArg) != argTypeName(&newArg) {
+			changes = append(changes, TemplateChange{Kind: ChangeChanged, Path: argPath + ".type", Old: argTypeName(&oldArg), New: argTypeName(&newArg)})
+		}
+		changes = appendValueChange(changes, argPath+".required", oldArg.Required, newArg.Required)
+		changes = appendValueChange(changes, argPath+".default", oldArg.Default, newArg.Default)
+		changes = appendValueChange(changes, argPath+".binding", oldArg.Binding, newArg.Binding)
+		changes = appendValueChange(changes, argPath+".validation", oldArg.Validation, newArg.Validation)
+		changes = appendValueChange(changes, argPath+".display", oldArg.DisplayInfo, newArg.DisplayInfo)
+		changes = appendValueChange(changes, argPath+".relation", oldArg.Relation, newArg.Relation)
+	}
+	return changes
+}
+
+func diffTask(path string, oldTask *Task, newTask *Task) []TemplateChange {
+	changes := []TemplateChange{}
+	changes = appendValueChange(changes, path+".type", oldTask.Type, newTask.Type)
+	changes = appendValueChange(changes, path+".agent", oldTask.Agent, newTask.Agent)
+	changes = appendValueChange(changes, path+".options", oldTask.Options, newTask.Options)
+	changes = appendValueChange(changes, path+".conditions", oldTask.Conditions, newTask.Conditions)
+	changes = appendValueChange(changes, path+".approve", oldTask.Approve, newTask.Approve)
+	changes = appendValueChange(changes, path+".environments", oldTask.Environments, newTask.Environments)
+	changes = appendValueChange(changes, path+".relation", oldTask.Relation, newTask.Relation)
+	return changes
+}
+
+// diffNames report added, removed names and whether the order of common names is changed
+func diffNames(path string, oldNames []string, newNames []string) []TemplateChange {
+	changes := []TemplateChange{}
+
+	oldSet, newSet := map[string]struct{}{}, map[string]struct{}{}
+	for _, name := range oldNames {
+		oldSet[name] = struct{}{}
+	}
+	for _, name := range newNames {
+		newSet[name] = struct{}{}
+	}
+
+	oldCommon, newCommon := []string{}, []string{}
+	for _, name := range oldNames {
+		if _, ok := newSet[name]; !ok {
+			changes = append(changes, TemplateChange{Kind: ChangeRemoved, Path: fmt.Sprintf("%s/%s", path, name)})
+		} else {
+			oldCommon = append(oldCommon, name)
+		}
+	}
+	for _, name := range newNames {
+		if _, ok := oldSet[name]; !ok {
+			changes = append(changes, TemplateChange{Kind: ChangeAdded, Path: fmt.Sprintf("%s/%s", path, name)})
+		} else {
+			newCommon = append(newCommon, name)
+		}
+	}
+
+	if !jsonEqual(oldCommon, newCommon) {
+		changes = append(changes, TemplateChange{Kind: ChangeReordered, Path: path, Old: oldCommon, New: newCommon})
+	}
+	return changes
+}
+
+func tasksIndex(spec *PipelineTemplateSpec) (map[string]*Task, map[string]string) {
+	tasks := map[string]*Task{}
+	stages := map[string]string{}
+	for _, stage := range spec.Stages {
+		for _, task := range stage.Tasks {
+			tasks[task.Name] = task
+			stages[task.Name] = stage.Name
+		}
+	}
+	return tasks, stages
+}
+
+func appendValueChange(changes []TemplateChange, path string, oldValue interface{}, newValue interface{}) []TemplateChange {
+	if jsonEqual(oldValue, newValue) {
+		return changes
+	}
+	return append(changes, TemplateChange{Kind: ChangeChanged, Path: path, Old: oldValue, New: newValue})
+}
+
+// jsonEqual compare values by json, so pointers and different number types could be compared.
+// nil and empty values are equal.
+func jsonEqual(a interface{}, b interface{}) bool {
+	normalize := func(str string) string {
+		switch str {
+		case "[]", "{}", `""`:
+			return "null"
+		}
+		return str
+	}
+	return normalize(jsonString(a)) == normalize(jsonString(b))
+}
+
+func jsonString(v interface{}) string {
+	byts, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(byts)
+}
+
+func sortedKeys(maps ...interface{}) []string {
+	set := map[string]struct{}{}
+	for _, m := range maps {
+		switch typed := m.(type) {
+		case map[string][]*Task:
+			for key := range typed {
+				set[key] = struct{}{}
+			}
+		case map[string]*TaskConstValue:
+			for key := range typed {
+				set[key] = struct{}{}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
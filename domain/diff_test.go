@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/otiszv/render/domain/arguments"
+)
+
+func changeStrings(changes []TemplateChange) []string {
+	res := []string{}
+	for _, change := range changes {
+		res = append(res, change.String())
+	}
+	return res
+}
+
+func TestDiffPipelineTemplateSpec(t *testing.T) {
+	source := &PipelineTemplateSpec{
+		Stages: []*Stage{
+			{Name: "Build", Tasks: []*Task{{Name: "Compile", Type: "build"}, {Name: "Test", Type: "test"}}},
+			{Name: "Scan", Tasks: []*Task{{Name: "Scan", Type: "scan"}}},
+			{Name: "Deploy", Tasks: []*Task{{Name: "Deploy", Type: "deploy"}}},
+		},
+	}
+	target := &PipelineTemplateSpec{
+		Stages: []*Stage{
+			{Name: "Deploy", Tasks: []*Task{{Name: "Deploy", Type: "deploy@^2"}, {Name: "Test", Type: "test"}}},
+			{Name: "Build", Tasks: []*Task{{Name: "Compile", Type: "build"}}},
+			{Name: "Notify", Tasks: []*Task{{Name: "Notify", Type: "notify"}}},
+		},
+	}
+
+	expected := []string{
+		"- stages/Scan",
+		"+ stages/Notify",
+		`~ stages reordered: ["Build","Deploy"] -> ["Deploy","Build"]`,
+		"- tasks/Scan",
+		`~ tasks/Deploy.type: "deploy" -> "deploy@^2"`,
+		`~ tasks/Test moved: "Build" -> "Deploy"`,
+		`+ tasks/Notify`,
+	}
+	if actual := changeStrings(DiffPipelineTemplateSpec(source, target)); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected:\n%v\nbut got:\n%v", expected, actual)
+	}
+
+	if changes := DiffPipelineTemplateSpec(source, source); len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changeStrings(changes))
+	}
+}
+
+func TestDiffTasksReordered(t *testing.T) {
+	source := &PipelineTemplateSpec{Stages: []*Stage{{Name: "Build", Tasks: []*Task{{Name: "A"}, {Name: "B"}, {Name: "C"}}}}}
+	target := &PipelineTemplateSpec{Stages: []*Stage{{Name: "Build", Tasks: []*Task{{Name: "C"}, {Name: "A"}, {Name: "B"}}}}}
+
+	expected := []string{`~ stages/Build/tasks reordered: ["A","B","C"] -> ["C","A","B"]`}
+	if actual := changeStrings(DiffPipelineTemplateSpec(source, target)); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, but got %v", expected, actual)
+	}
+}
+
+func TestDiffArguments(t *testing.T) {
+	oldArgs := []arguments.ArgItem{
+		{Name: "image", Schema: &arguments.ArgItemSchema{Type: "string"}},
+		{Name: "tag", Schema: &arguments.ArgItemSchema{Type: "string"}, Default: "latest"},
+		{Name: "replicas", Schema: &arguments.ArgItemSchema{Type: "int"}},
+		{Name: "debug", Schema: &arguments.ArgItemSchema{Type: "boolean"}},
+	}
+	newArgs := []arguments.ArgItem{
+		{Name: "tag", Schema: &arguments.ArgItemSchema{Type: "string"}, Default: "v1"},
+		{Name: "image", Schema: &arguments.ArgItemSchema{Type: "string"}, Required: true},
+		{Name: "replicas", Schema: &arguments.ArgItemSchema{Type: "array", Items: &arguments.ArgItemSchemaItem{Type: "int"}}},
+		{Name: "timeout", Schema: &arguments.ArgItemSchema{Type: "int"}},
+	}
+
+	expected := []string{
+		"- arguments/debug",
+		"+ arguments/timeout",
+		`~ arguments reordered: ["image","tag","replicas"] -> ["tag","image","replicas"]`,
+		`~ arguments/tag.default: "latest" -> "v1"`,
+		"~ arguments/image.required: false -> true",
+		`~ arguments/replicas.type: "int" -> "array[int]"`,
+	}
+	if actual := changeStrings(DiffArguments("arguments", oldArgs, newArgs)); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected:\n%v\nbut got:\n%v", expected, actual)
+	}
+
+	// nil and empty values are the same
+	oldArgs = []arguments.ArgItem{{Name: "tags", Binding: nil, Default: ""}}
+	newArgs = []arguments.ArgItem{{Name: "tags", Binding: []string{}}}
+	if changes := DiffArguments("arguments", oldArgs, newArgs); len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changeStrings(changes))
+	}
+}
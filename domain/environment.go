@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/jenkinsfile"
+)
+
+var envVarNameRegx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnvironments validate env vars of a scope, eg: pipeline, stage or task.
+// names should be legal identifiers and unique in the scope
+func validateEnvironments(envs []jenkinsfile.EnvVar) error {
+	errs := common.Errors{}
+
+	names := map[string]struct{}{}
+	for i, env := range envs {
+		path := fmt.Sprintf("[%d]", i)
+		if !envVarNameRegx.MatchString(env.Name) {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("env name `%s` should be a legal identifier", env.Name), nil), path+".name"))
+		} else if _, ok := names[env.Name]; ok {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("env name %s should be unique", env.Name), nil), path+".name"))
+		}
+		names[env.Name] = struct{}{}
+
+		switch env.Type {
+		case "", jenkinsfile.EnvVarTypeLiteral:
+		case jenkinsfile.EnvVarTypeCredentials, jenkinsfile.EnvVarTypeRaw:
+			if value, ok := env.Value.(string); !ok || strings.TrimSpace(value) == "" {
+				errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("value of %s env %s should be a non-empty string", env.Type, env.Name), nil), path+".value"))
+			}
+		default:
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("env type %s is not support, it should be one of %s", env.Type, strings.Join(jenkinsfile.EnvVarTypes, "|")), nil), path+".type"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/jenkinsfile"
+)
+
+func TestValidateEnvironments(t *testing.T) {
+	cases := []struct {
+		name string
+		envs []jenkinsfile.EnvVar
+		// paths of errors
+		expected []string
+	}{
+		{
+			name: "legal",
+			envs: []jenkinsfile.EnvVar{
+				{Name: "APP", Value: "app"},
+				{Name: "_REPLICAS", Value: 3, Type: jenkinsfile.EnvVarTypeLiteral},
+				{Name: "TOKEN", Value: "token-id", Type: jenkinsfile.EnvVarTypeCredentials},
+				{Name: "COMMIT", Value: "sh(script: 'git rev-parse HEAD', returnStdout: true).trim()", Type: jenkinsfile.EnvVarTypeRaw},
+			},
+		},
+		{
+			name:     "duplicate names",
+			envs:     []jenkinsfile.EnvVar{{Name: "APP", Value: "a"}, {Name: "TAG", Value: "t"}, {Name: "APP", Value: "b"}},
+			expected: []string{"[2].name"},
+		},
+		{
+			name:     "illegal names",
+			envs:     []jenkinsfile.EnvVar{{Name: "1APP"}, {Name: "APP-NAME"}, {Name: ""}, {Name: "APP NAME"}},
+			expected: []string{"[0].name", "[1].name", "[2].name", "[3].name"},
+		},
+		{
+			name:     "empty credentials and raw",
+			envs:     []jenkinsfile.EnvVar{{Name: "TOKEN", Type: jenkinsfile.EnvVarTypeCredentials}, {Name: "COMMIT", Value: " ", Type: jenkinsfile.EnvVarTypeRaw}, {Name: "ID", Value: 1, Type: jenkinsfile.EnvVarTypeCredentials}},
+			expected: []string{"[0].value", "[1].value", "[2].value"},
+		},
+		{
+			name:     "unknown type",
+			envs:     []jenkinsfile.EnvVar{{Name: "APP", Value: "app", Type: "secret"}},
+			expected: []string{"[0].type"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			paths := []string{}
+			for _, err := range common.FlattenErrors(validateEnvironments(c.envs)) {
+				typed, ok := err.(common.Error)
+				if !ok || typed.Code != common.CodeTemplateError {
+					t.Errorf("unexpected error: %#v", err)
+					continue
+				}
+				paths = append(paths, typed.Path)
+			}
+			if len(paths) == 0 && len(c.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(paths, c.expected) {
+				t.Errorf("expected errors at %v, but got %v", c.expected, paths)
+			}
+		})
+	}
+}
@@ -1,39 +1,70 @@
 package domain
 
-import "github.com/otiszv/render/domain/common"
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/otiszv/render/domain/common"
+)
 
 type GlobalVar struct {
 	Name        string                `json:"name"`
 	Description common.MulitLangValue `json:"description"`
+	// SCMTypes var is only available when scm of pipeline is one of them, eg: GIT, empty means it does not depend on scm
+	SCMTypes []string `json:"scmTypes,omitempty"`
+	// ImageTriggered var is only available when pipeline is triggered by images
+	ImageTriggered bool `json:"imageTriggered,omitempty"`
+}
+
+// AvailableForSCM whether var is available in pipeline with scm, scm is nil when pipeline has no scm
+func (v GlobalVar) AvailableForSCM(scm *SCMInfo) bool {
+	if len(v.SCMTypes) == 0 {
+		return true
+	}
+	if scm == nil {
+		return false
+	}
+	for _, scmType := range v.SCMTypes {
+		if scmType == string(scm.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalizedDescription description of locale, see common.MulitLangValue.Localize
+func (v GlobalVar) LocalizedDescription(locale string) string {
+	return v.Description.Localize(locale)
 }
 
 var jenkinsVars = []GlobalVar{
 	GlobalVar{
 		Name: "BUILD_NUMBER",
 		Description: common.MulitLangValue{
-			ZH_CN: "当前构建的jenkins编号, 例如 153",
-			EN:    "The current build number, such as \"153\"",
+			common.LocaleZHCN: "当前构建的jenkins编号, 例如 153",
+			common.LocaleEN:   "The current build number, such as \"153\"",
 		},
 	},
 	GlobalVar{
 		Name: "JOB_NAME",
 		Description: common.MulitLangValue{
-			ZH_CN: "当前流水线的名称",
-			EN:    "Name of the project of this build",
+			common.LocaleZHCN: "当前流水线的名称",
+			common.LocaleEN:   "Name of the project of this build",
 		},
 	},
 	GlobalVar{
 		Name: "JOB_URL",
 		Description: common.MulitLangValue{
-			ZH_CN: "当前流水线所在Jenkins的地址, 例如http://server:port/jenkins/job/foo/ (需要在Jenkins配置Jenkins URL)",
-			EN:    "Full URL of this job, like http://server:port/jenkins/job/foo/ (Jenkins URL must be set)",
+			common.LocaleZHCN: "当前流水线所在Jenkins的地址, 例如http://server:port/jenkins/job/foo/ (需要在Jenkins配置Jenkins URL)",
+			common.LocaleEN:   "Full URL of this job, like http://server:port/jenkins/job/foo/ (Jenkins URL must be set)",
 		},
 	},
 	GlobalVar{
 		Name: "BUILD_URL",
 		Description: common.MulitLangValue{
-			ZH_CN: "当前构建所在Jenkins的地址, 例如http://server:port/jenkins/job/foo/15 (需要在Jenkins配置Jenkins URL)",
-			EN:    "Full URL of this build, like http://server:port/jenkins/job/foo/15 (Jenkins URL must be set)",
+			common.LocaleZHCN: "当前构建所在Jenkins的地址, 例如http://server:port/jenkins/job/foo/15 (需要在Jenkins配置Jenkins URL)",
+			common.LocaleEN:   "Full URL of this build, like http://server:port/jenkins/job/foo/15 (Jenkins URL must be set)",
 		},
 	},
 }
@@ -42,16 +73,18 @@ var gitVars = []GlobalVar{
 	GlobalVar{
 		Name: "GIT_COMMIT",
 		Description: common.MulitLangValue{
-			ZH_CN: "代码提交版本号, 例如: c68938922a3500a95b1f33883144196abc5a794d",
-			EN:    "GIT commit id of code repository, such as:\"c68938922a3500a95b1f33883144196abc5a794d\"",
+			common.LocaleZHCN: "代码提交版本号, 例如: c68938922a3500a95b1f33883144196abc5a794d",
+			common.LocaleEN:   "GIT commit id of code repository, such as:\"c68938922a3500a95b1f33883144196abc5a794d\"",
 		},
+		SCMTypes: []string{string(SCMTypeEnum.GIT)},
 	},
 	GlobalVar{
 		Name: "GIT_BRANCH",
 		Description: common.MulitLangValue{
-			ZH_CN: "代码提交分支名称",
-			EN:    "GIT branch nam of code repository",
+			common.LocaleZHCN: "代码提交分支名称",
+			common.LocaleEN:   "GIT branch nam of code repository",
 		},
+		SCMTypes: []string{string(SCMTypeEnum.GIT)},
 	},
 }
 
@@ -59,9 +92,10 @@ var repoVars = []GlobalVar{
 	GlobalVar{
 		Name: "REPOSITORY_PATH",
 		Description: common.MulitLangValue{
-			ZH_CN: "代码仓库地址",
-			EN:    "url of code repository",
+			common.LocaleZHCN: "代码仓库地址",
+			common.LocaleEN:   "url of code repository",
 		},
+		SCMTypes: []string{string(SCMTypeEnum.GIT), string(SCMTypeEnum.SVN), string(SCMTypeEnum.HG)},
 	},
 }
 
@@ -69,9 +103,10 @@ var svnVars = []GlobalVar{
 	GlobalVar{
 		Name: "SVN_REVISION",
 		Description: common.MulitLangValue{
-			ZH_CN: "svn 代码版本号,例如: 46",
-			EN:    "code version of svn repository, such as: \"46\"",
+			common.LocaleZHCN: "svn 代码版本号,例如: 46",
+			common.LocaleEN:   "code version of svn repository, such as: \"46\"",
 		},
+		SCMTypes: []string{string(SCMTypeEnum.SVN)},
 	},
 }
 
@@ -79,37 +114,140 @@ var imageVars = []GlobalVar{
 	GlobalVar{
 		Name: "IMAGE_REPOSITORY",
 		Description: common.MulitLangValue{
-			ZH_CN: "流水线被镜像触发时的镜像名称",
-			EN:    "repository of image when pipeline triggered by docker image",
+			common.LocaleZHCN: "流水线被镜像触发时的镜像名称",
+			common.LocaleEN:   "repository of image when pipeline triggered by docker image",
 		},
+		ImageTriggered: true,
 	},
 	GlobalVar{
 		Name: "IMAGE_TAG",
 		Description: common.MulitLangValue{
-			ZH_CN: "流水线被镜像触发时的镜像TAG",
-			EN:    "tag of image when pipeline triggered by docker image",
+			common.LocaleZHCN: "流水线被镜像触发时的镜像TAG",
+			common.LocaleEN:   "tag of image when pipeline triggered by docker image",
 		},
+		ImageTriggered: true,
 	},
 }
 
+// GlobalVarRegistry global vars that task templates could refer by `globalVar` template function, it is safe for concurrent use
+type GlobalVarRegistry struct {
+	mutex sync.RWMutex
+	vars  []GlobalVar
+	index map[string]int
+}
+
+// NewGlobalVarRegistry create an empty registry
+func NewGlobalVarRegistry() *GlobalVarRegistry {
+	return &GlobalVarRegistry{
+		vars:  []GlobalVar{},
+		index: map[string]int{},
+	}
+}
+
+var globalVarNameRegx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Register add vars to registry, var that has the same name is replaced
+func (registry *GlobalVarRegistry) Register(vars ...GlobalVar) error {
+	for _, v := range vars {
+		if !globalVarNameRegx.MatchString(v.Name) {
+			return common.NewValidateError(fmt.Sprintf("global var name `%s` should be a legal identifier", v.Name), nil)
+		}
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for _, v := range vars {
+		if i, ok := registry.index[v.Name]; ok {
+			registry.vars[i] = v
+			continue
+		}
+		registry.index[v.Name] = len(registry.vars)
+		registry.vars = append(registry.vars, v)
+	}
+	return nil
+}
+
+// Lookup find var by name
+func (registry *GlobalVarRegistry) Lookup(name string) (GlobalVar, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	i, ok := registry.index[name]
+	if !ok {
+		return GlobalVar{}, false
+	}
+	return registry.vars[i], true
+}
+
+// Vars vars that are available in pipeline with scm and images that trigger it, in the order they are registered
+func (registry *GlobalVarRegistry) Vars(scm *SCMInfo, imageRepositories []string) []GlobalVar {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	vars := []GlobalVar{}
+	for _, v := range registry.vars {
+		if !v.AvailableForSCM(scm) || (v.ImageTriggered && len(imageRepositories) == 0) {
+			continue
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// DefaultGlobalVarRegistry registry of global vars that jenkinsfilext support,
+// platforms could add their own global vars to it, see RegisterGlobalVars
+var DefaultGlobalVarRegistry = func() *GlobalVarRegistry {
+	registry := NewGlobalVarRegistry()
+	for _, vars := range [][]GlobalVar{jenkinsVars, repoVars, gitVars, svnVars, imageVars} {
+		if err := registry.Register(vars...); err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}()
+
+// RegisterGlobalVars add global vars to DefaultGlobalVarRegistry, eg: name of cluster that pipeline runs in
+func RegisterGlobalVars(vars ...GlobalVar) error {
+	return DefaultGlobalVarRegistry.Register(vars...)
+}
+
 //GetGlobalVars  get global vars that jenkinsfilext support
 func GetGlobalVars(scm *SCMInfo, imageRepositories []string) []GlobalVar {
-	var vars = []GlobalVar{}
-	vars = append(vars, jenkinsVars...)
+	return DefaultGlobalVarRegistry.Vars(scm, imageRepositories)
+}
+
+// ValidateGlobalVars validate global vars that task templates of pipeline template refer are available for scm of pipeline.
+// scm is nil when pipeline has no scm, taskTemplatesRef: task templates keyed by task type, same as Render
+func (spec *PipelineTemplateSpec) ValidateGlobalVars(taskTemplatesRef map[string]TaskTemplateSpec, scm *SCMInfo) error {
+	errs := common.Errors{}
 
+	scope := "pipeline without scm"
 	if scm != nil {
-		vars = append(vars, repoVars...)
-		if scm.Type == SCMTypeEnum.GIT {
-			vars = append(vars, gitVars...)
+		scope = fmt.Sprintf("%s pipeline", scm.Type)
+	}
+	taskPaths := spec.taskPaths()
+	for _, task := range spec.allTasksWithPost() {
+		taskTemplate, ok := lookupTaskTemplate(taskTemplatesRef, task.Type)
+		if !ok {
+			continue
 		}
-		if scm.Type == SCMTypeEnum.SVN {
-			vars = append(vars, svnVars...)
+		// errors of body are reported by definition of task template
+		refs, err := taskTemplate.GlobalVarRefs()
+		if err != nil {
+			continue
 		}
-	}
 
-	if len(imageRepositories) > 0 {
-		vars = append(vars, imageVars...)
+		for _, name := range refs {
+			v, ok := DefaultGlobalVarRegistry.Lookup(name)
+			if !ok || v.AvailableForSCM(scm) {
+				continue
+			}
+			err := common.NewValidateError(fmt.Sprintf("task %s refers to global var %s that is not available in %s", task.Name, name, scope), nil)
+			errs = append(errs, common.WithPath(common.WithTask(err, task.Name), taskPaths[task.Name]))
+		}
 	}
 
-	return vars
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
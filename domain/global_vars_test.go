@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+func globalVarNames(vars []GlobalVar) []string {
+	names := []string{}
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+func TestGlobalVarRegistry(t *testing.T) {
+	registry := NewGlobalVarRegistry()
+	err := registry.Register(
+		GlobalVar{Name: "BUILD_NUMBER"},
+		GlobalVar{Name: "GIT_COMMIT", SCMTypes: []string{string(SCMTypeEnum.GIT)}},
+		GlobalVar{Name: "SVN_REVISION", SCMTypes: []string{string(SCMTypeEnum.SVN)}},
+		GlobalVar{Name: "IMAGE_TAG", ImageTriggered: true},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// var with the same name is replaced in place
+	err = registry.Register(GlobalVar{Name: "GIT_COMMIT", Description: common.MulitLangValue{common.LocaleEN: "commit"}, SCMTypes: []string{string(SCMTypeEnum.GIT), string(SCMTypeEnum.HG)}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, ok := registry.Lookup("GIT_COMMIT")
+	if !ok || v.LocalizedDescription(common.LocaleEN) != "commit" {
+		t.Errorf("GIT_COMMIT should be overridden, but got %#v", v)
+	}
+	if _, ok := registry.Lookup("NOT_EXISTS"); ok {
+		t.Errorf("NOT_EXISTS should not be found")
+	}
+
+	if err := registry.Register(GlobalVar{Name: "CLUSTER"}, GlobalVar{Name: "not-legal"}); err == nil {
+		t.Errorf("expected error of illegal name")
+	}
+	if _, ok := registry.Lookup("CLUSTER"); ok {
+		t.Errorf("no var should be registered when any of them is illegal")
+	}
+
+	cases := []struct {
+		name     string
+		scm      *SCMInfo
+		images   []string
+		expected []string
+	}{
+		{"without scm", nil, nil, []string{"BUILD_NUMBER"}},
+		{"git", &SCMInfo{Type: SCMTypeEnum.GIT}, nil, []string{"BUILD_NUMBER", "GIT_COMMIT"}},
+		{"hg", &SCMInfo{Type: SCMTypeEnum.HG}, nil, []string{"BUILD_NUMBER", "GIT_COMMIT"}},
+		{"svn triggered by images", &SCMInfo{Type: SCMTypeEnum.SVN}, []string{"harbor/app"}, []string{"BUILD_NUMBER", "SVN_REVISION", "IMAGE_TAG"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := globalVarNames(registry.Vars(c.scm, c.images)); !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGlobalVarFuncs(t *testing.T) {
+	spec := &TaskTemplateSpec{Body: `sh "go build -o app-{{globalVar "GIT_COMMIT"}}"
+{{if .debug}}
+if ({{globalVarExpr "GIT_BRANCH"}} == 'master') { echo "{{globalVar "NOT_REGISTERED"}}" }
+{{end}}`}
+
+	refs, err := spec.GlobalVarRefs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"GIT_BRANCH", "GIT_COMMIT", "NOT_REGISTERED"}; !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected refs %v, but got %v", expected, refs)
+	}
+
+	errs := common.FlattenErrors(spec.ValidateGlobalVarRefs(DefaultGlobalVarRegistry))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "global var NOT_REGISTERED is not registered") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	// unregistered vars are not errors of definition, they could be registered at runtime
+	if err := spec.ValidateDefinition(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	spec.Body = `sh "go build -o app-{{globalVar "GIT_COMMIT"}}"
+if ({{globalVarExpr "GIT_BRANCH"}} == 'master') { echo 'master' }`
+	render, err := spec.gotplRender(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := `sh "go build -o app-${env.GIT_COMMIT}"
+if (env.GIT_BRANCH == 'master') { echo 'master' }`; render != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, render)
+	}
+
+	spec.Body = `{{globalVar "NOT_REGISTERED"}}`
+	if _, err := spec.gotplRender(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "global var NOT_REGISTERED is not registered") {
+		t.Errorf("expected error of unregistered var, but got %v", err)
+	}
+}
+
+func TestValidateGlobalVars(t *testing.T) {
+	spec := &PipelineTemplateSpec{Stages: []*Stage{{
+		Name: "Build",
+		Tasks: []*Task{
+			{Name: "Build", Type: "build@^1.0"},
+			{Name: "Version", Type: "version"},
+		},
+	}}}
+	taskTemplates := map[string]TaskTemplateSpec{
+		"build@^1.0": {Body: `sh "echo {{globalVar "SVN_REVISION"}} {{globalVar "BUILD_NUMBER"}}"`},
+		// task type without version is looked up by name
+		"version": {Body: `sh "echo {{globalVarExpr "GIT_COMMIT"}}"`},
+	}
+
+	cases := []struct {
+		name     string
+		scm      *SCMInfo
+		expected []string
+	}{
+		{"svn var in git pipeline", &SCMInfo{Type: SCMTypeEnum.GIT}, []string{"task Build refers to global var SVN_REVISION that is not available in GIT pipeline"}},
+		{"git var in svn pipeline", &SCMInfo{Type: SCMTypeEnum.SVN}, []string{"task Version refers to global var GIT_COMMIT that is not available in SVN pipeline"}},
+		{"pipeline without scm", nil, []string{
+			"task Build refers to global var SVN_REVISION that is not available in pipeline without scm",
+			"task Version refers to global var GIT_COMMIT that is not available in pipeline without scm",
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			errs := common.FlattenErrors(spec.ValidateGlobalVars(taskTemplates, c.scm))
+			if len(errs) != len(c.expected) {
+				t.Fatalf("expected %d errors, but got %v", len(c.expected), errs)
+			}
+			for i, err := range errs {
+				typed, ok := err.(common.Error)
+				if !ok || typed.Code != common.CodeValidateError || !strings.Contains(typed.Message, c.expected[i]) {
+					t.Errorf("expected error %q, but got %v", c.expected[i], err)
+				}
+				if typed.Path != "stages[0].tasks["+map[string]string{"Build": "0", "Version": "1"}[typed.Task]+"]" {
+					t.Errorf("unexpected path of %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/otiszv/render/domain/arguments"
+	"github.com/otiszv/render/domain/common"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// TemplateRef refers to a pipeline template by name and version
+type TemplateRef struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func (ref TemplateRef) String() string {
+	if ref.Version == "" {
+		return ref.Name
+	}
+	return fmt.Sprintf("%s@%s", ref.Name, ref.Version)
+}
+
+// operations of TemplatePatch
+const (
+	PatchOpOverride     = "override"
+	PatchOpInsertBefore = "insertBefore"
+	PatchOpInsertAfter  = "insertAfter"
+	PatchOpRemove       = "remove"
+	PatchOpAdd          = "add"
+)
+
+// TemplatePatch modify the parent template by name, target could be:
+//
+//	stages/<stage>          override, insertBefore, insertAfter, remove a stage
+//	stages                  add a stage at the end
+//	stages/<stage>/tasks    add a parallel task to stage
+//	tasks/<task>            override, insertBefore, insertAfter, remove a task
+//	arguments/<arg>         override, insertBefore, insertAfter, remove an argument
+//	arguments               add an argument to the last section
+//	values/<task>           override, remove const values of a task, add merges const values
+//	post/<condition>/<task> override, insertBefore, insertAfter, remove a post task
+//	post/<condition>        add a post task, remove the whole condition
+type TemplatePatch struct {
+	Op     string      `json:"op"`
+	Target string      `json:"target"`
+	Value  interface{} `json:"value,omitempty"`
+}
+
+func (patch *TemplatePatch) validateDefinition() error {
+	switch patch.Op {
+	case PatchOpOverride, PatchOpInsertBefore, PatchOpInsertAfter, PatchOpRemove, PatchOpAdd:
+	default:
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("patch op `%s` of target %s is not support", patch.Op, patch.Target), nil), "op")
+	}
+
+	if strings.TrimSpace(patch.Target) == "" {
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("patch target should not be empty for op %s", patch.Op), nil), "target")
+	}
+
+	if patch.Op != PatchOpRemove && patch.Value == nil {
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("patch value is required for op %s of target %s", patch.Op, patch.Target), nil), "value")
+	}
+	return nil
+}
+
+// PipelineTemplateResolver find the spec of pipeline template by name and version
+type PipelineTemplateResolver func(name string, version string) (*PipelineTemplateSpec, error)
+
+// maxInheritanceDepth avoid endless inheritance
+const maxInheritanceDepth = 16
+
+// IsExtended whether spec extends a parent template
+func (spec *PipelineTemplateSpec) IsExtended() bool {
+	return spec.Extends != nil
+}
+
+func (spec *PipelineTemplateSpec) validateExtendsDefinition() error {
+	errs := common.Errors{}
+	if strings.TrimSpace(spec.Extends.Name) == "" {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("extends.name should not be empty", nil), "extends.name"))
+	}
+
+	if err := ValidateAgent(spec.Agent); err != nil {
+		errs = append(errs, common.WithPath(err, "agent"))
+	}
+
+	// they are only modified by patches, or they would be ignored silently when merge
+	for _, field := range []struct {
+		name  string
+		isSet bool
+	}{
+		{"stages", len(spec.Stages) != 0},
+		{"arguments", len(spec.Arguments) != 0},
+		{"values", spec.ConstValues != nil},
+		{"post", len(spec.Post) != 0},
+	} {
+		if field.isSet {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("%s should not be set in template that extends %s, use patches to modify %s of it", field.name, spec.Extends, field.name), nil), field.name))
+		}
+	}
+
+	for i, patch := range spec.Patches {
+		if err := patch.validateDefinition(); err != nil {
+			errs = append(errs, common.WithPath(err, fmt.Sprintf("patches[%d]", i)))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Expand merge spec with its parents recursively, the result is a standalone template that has been validated.
+// it returns a copy of spec if spec does not extend any template.
+func (spec *PipelineTemplateSpec) Expand(resolve PipelineTemplateResolver) (*PipelineTemplateSpec, error) {
+	expanded, err := spec.expand(resolve, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = expanded.ValidateDefinition()
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+func (spec *PipelineTemplateSpec) expand(resolve PipelineTemplateResolver, chain []string) (*PipelineTemplateSpec, error) {
+	if !spec.IsExtended() {
+		return spec.deepCopy()
+	}
+
+	ref := spec.Extends.String()
+	for _, name := range chain {
+		if name == ref {
+			return nil, common.NewTemplateDefinitionError(fmt.Sprintf("circular template inheritance: %s -> %s", strings.Join(chain, " -> "), ref), nil)
+		}
+	}
+	chain = append(chain, ref)
+	if len(chain) > maxInheritanceDepth {
+		return nil, common.NewTemplateDefinitionError(fmt.Sprintf("template inheritance is too deep: %s", strings.Join(chain, " -> ")), nil)
+	}
+
+	if resolve == nil {
+		return nil, common.NewValidateError(fmt.Sprintf("require definition of pipeline template %s", ref), nil)
+	}
+	parent, err := resolve(spec.Extends.Name, spec.Extends.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := parent.expand(resolve, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	err = merged.merge(spec)
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// merge apply fields and patches of child to spec
+func (spec *PipelineTemplateSpec) merge(child *PipelineTemplateSpec) error {
+	override, err := child.deepCopy()
+	if err != nil {
+		return err
+	}
+
+	if override.Engine != "" {
+		spec.Engine = override.Engine
+	}
+	if override.Agent != nil {
+		spec.Agent = override.Agent
+	}
+	if override.Options != nil {
+		spec.Options = override.Options
+	}
+	if override.Environments != nil {
+		spec.Environments = override.Environments
+	}
+	if override.WithSCM != nil {
+		spec.WithSCM = override.WithSCM
+	}
+
+	errs := common.Errors{}
+	for _, patch := range override.Patches {
+		if err := spec.applyPatch(patch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (spec *PipelineTemplateSpec) applyPatch(patch *TemplatePatch) error {
+	if err := patch.validateDefinition(); err != nil {
+		return err
+	}
+
+	segments := strings.Split(patch.Target, "/")
+	switch {
+	case segments[0] == "stages" && len(segments) == 1:
+		return spec.patchAddStage(patch)
+	case segments[0] == "stages" && len(segments) == 2:
+		return spec.patchStage(patch, segments[1])
+	case segments[0] == "stages" && len(segments) == 3 && segments[2] == "tasks":
+		return spec.patchAddTask(patch, segments[1])
+	case segments[0] == "tasks" && len(segments) == 2:
+		return spec.patchTask(patch, segments[1])
+	case segments[0] == "arguments" && len(segments) <= 2:
+		return spec.patchArgument(patch, segments[1:])
+	case segments[0] == "values" && len(segments) == 2:
+		return spec.patchConstValue(patch, segments[1])
+	case segments[0] == "post" && len(segments) <= 3:
+		return spec.patchPost(patch, segments[1:])
+	}
+
+	return patchError(patch, "target is not support")
+}
+
+func patchError(patch *TemplatePatch, message string) error {
+	return common.NewTemplateDefinitionError(fmt.Sprintf("patch %s %s error: %s", patch.Op, patch.Target, message), nil)
+}
+
+func decodePatchValue(patch *TemplatePatch, out interface{}) error {
+	err := mapstructure.Decode(patch.Value, out)
+	if err != nil {
+		return patchError(patch, err.Error())
+	}
+	return nil
+}
+
+func (spec *PipelineTemplateSpec) patchAddStage(patch *TemplatePatch) error {
+	if patch.Op != PatchOpAdd {
+		return patchError(patch, "only add is support")
+	}
+	stage := &Stage{}
+	if err := decodePatchValue(patch, stage); err != nil {
+		return err
+	}
+	spec.Stages = append(spec.Stages, stage)
+	return nil
+}
+
+func (spec *PipelineTemplateSpec) patchStage(patch *TemplatePatch, name string) error {
+	index := -1
+	for i, stage := range spec.Stages {
+		if stage.Name == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return patchError(patch, fmt.Sprintf("stage %s is not found", name))
+	}
+
+	if patch.Op == PatchOpRemove {
+		spec.Stages = append(spec.Stages[:index], spec.Stages[index+1:]...)
+		return nil
+	}
+
+	stage := &Stage{}
+	if err := decodePatchValue(patch, stage); err != nil {
+		return err
+	}
+
+	switch patch.Op {
+	case PatchOpOverride:
+		spec.Stages[index] = stage
+	case PatchOpInsertBefore:
+		spec.Stages = append(spec.Stages[:index], append([]*Stage{stage}, spec.Stages[index:]...)...)
+	case PatchOpInsertAfter:
+		spec.Stages = append(spec.Stages[:index+1], append([]*Stage{stage}, spec.Stages[index+1:]...)...)
+	default:
+		return patchError(patch, "op is not support for stage")
+	}
+	return nil
+}
+
+func (spec *PipelineTemplateSpec) patchAddTask(patch *TemplatePatch, stageName string) error {
+	if patch.Op != PatchOpAdd {
+		return patchError(patch, "only add is support")
+	}
+	for _, stage := range spec.Stages {
+		if stage.Name == stageName {
+			task := &Task{}
+			if err := decodePatchValue(patch, task); err != nil {
+				return err
+			}
+			stage.Tasks = append(stage.Tasks, task)
+			return nil
+		}
+	}
+	return patchError(patch, fmt.Sprintf("stage %s is not found", stageName))
+}
+
+func (spec *PipelineTemplateSpec) patchTask(patch *TemplatePatch, name string) error {
+	for _, stage := range spec.Stages {
+		tasks, found, err := patchTaskList(patch, stage.Tasks, name)
+		if err != nil {
+			return err
+		}
+		if found {
+			stage.Tasks = tasks
+			return nil
+		}
+	}
+	return patchError(patch, fmt.Sprintf("task %s is not found", name))
+}
+
+func patchTaskList(patch *TemplatePatch, tasks []*Task, name string) ([]*Task, bool, error) {
+	index := -1
+	for i, task := range tasks {
+		if task.Name == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return tasks, false, nil
+	}
+
+	if patch.Op == PatchOpRemove {
+		return append(tasks[:index], tasks[index+1:]...), true, nil
+	}
+
+	task := &Task{}
+	if err := decodePatchValue(patch, task); err != nil {
+		return tasks, true, err
+	}
+
+	switch patch.Op {
+	case PatchOpOverride:
+		tasks[index] = task
+	case PatchOpInsertBefore:
+		tasks = append(tasks[:index], append([]*Task{task}, tasks[index:]...)...)
+	case PatchOpInsertAfter:
+		tasks = append(tasks[:index+1], append([]*Task{task}, tasks[index+1:]...)...)
+	default:
+		return tasks, true, patchError(patch, "op is not support for task")
+	}
+	return tasks, true, nil
+}
+
+func (spec *PipelineTemplateSpec) patchArgument(patch *TemplatePatch, segments []string) error {
+	if len(segments) == 0 {
+		if patch.Op != PatchOpAdd {
+			return patchError(patch, "only add is support")
+		}
+		argItem := arguments.ArgItem{}
+		if err := decodePatchValue(patch, &argItem); err != nil {
+			return err
+		}
+		if len(spec.Arguments) == 0 {
+			spec.Arguments = arguments.ArgSections{arguments.ArgSection{Items: []arguments.ArgItem{}}}
+		}
+		last := len(spec.Arguments) - 1
+		spec.Arguments[last].Items = append(spec.Arguments[last].Items, argItem)
+		return nil
+	}
+
+	name := segments[0]
+	for s := range spec.Arguments {
+		items := spec.Arguments[s].Items
+		for index, item := range items {
+			if item.Name != name {
+				continue
+			}
+
+			if patch.Op == PatchOpRemove {
+				spec.Arguments[s].Items = append(items[:index], items[index+1:]...)
+				return nil
+			}
+
+			argItem := arguments.ArgItem{}
+			if err := decodePatchValue(patch, &argItem); err != nil {
+				return err
+			}
+
+			switch patch.Op {
+			case PatchOpOverride:
+				items[index] = argItem
+			case PatchOpInsertBefore:
+				spec.Arguments[s].Items = append(items[:index], append([]arguments.ArgItem{argItem}, items[index:]...)...)
+			case PatchOpInsertAfter:
+				spec.Arguments[s].Items = append(items[:index+1], append([]arguments.ArgItem{argItem}, items[index+1:]...)...)
+			default:
+				return patchError(patch, "op is not support for argument")
+			}
+			return nil
+		}
+	}
+	return patchError(patch, fmt.Sprintf("argument %s is not found", name))
+}
+
+func (spec *PipelineTemplateSpec) patchConstValue(patch *TemplatePatch, taskName string) error {
+	if spec.ConstValues == nil {
+		spec.ConstValues = &ConstValues{}
+	}
+	if spec.ConstValues.Tasks == nil {
+		spec.ConstValues.Tasks = map[string]*TaskConstValue{}
+	}
+
+	switch patch.Op {
+	case PatchOpRemove:
+		if _, ok := spec.ConstValues.Tasks[taskName]; !ok {
+			return patchError(patch, fmt.Sprintf("values of task %s is not found", taskName))
+		}
+		delete(spec.ConstValues.Tasks, taskName)
+	case PatchOpOverride:
+		value := &TaskConstValue{}
+		if err := decodePatchValue(patch, value); err != nil {
+			return err
+		}
+		spec.ConstValues.Tasks[taskName] = value
+	case PatchOpAdd:
+		value := &TaskConstValue{}
+		if err := decodePatchValue(patch, value); err != nil {
+			return err
+		}
+		current, ok := spec.ConstValues.Tasks[taskName]
+		if !ok {
+			spec.ConstValues.Tasks[taskName] = value
+			return nil
+		}
+		if value.Options != nil {
+			current.Options = value.Options
+		}
+		if value.Approve != nil {
+			current.Approve = value.Approve
+		}
+		if current.Args == nil {
+			current.Args = map[string]interface{}{}
+		}
+		for key, v := range value.Args {
+			current.Args[key] = v
+		}
+	default:
+		return patchError(patch, "op is not support for values")
+	}
+	return nil
+}
+
+func (spec *PipelineTemplateSpec) patchPost(patch *TemplatePatch, segments []string) error {
+	if len(segments) == 0 {
+		return patchError(patch, "post condition is required")
+	}
+	condition := segments[0]
+
+	if len(segments) == 1 {
+		switch patch.Op {
+		case PatchOpAdd:
+			task := &Task{}
+			if err := decodePatchValue(patch, task); err != nil {
+				return err
+			}
+			if spec.Post == nil {
+				spec.Post = map[string][]*Task{}
+			}
+			spec.Post[condition] = append(spec.Post[condition], task)
+		case PatchOpRemove:
+			if _, ok := spec.Post[condition]; !ok {
+				return patchError(patch, fmt.Sprintf("post condition %s is not found", condition))
+			}
+			delete(spec.Post, condition)
+		default:
+			return patchError(patch, "only add and remove are support")
+		}
+		return nil
+	}
+
+	tasks, found, err := patchTaskList(patch, spec.Post[condition], segments[1])
+	if err != nil {
+		return err
+	}
+	if !found {
+		return patchError(patch, fmt.Sprintf("post task %s is not found in %s", segments[1], condition))
+	}
+	spec.Post[condition] = tasks
+	return nil
+}
+
+// deepCopy copy definition fields of spec, values of render will not be copied
+func (spec *PipelineTemplateSpec) deepCopy() (*PipelineTemplateSpec, error) {
+	byts, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := &PipelineTemplateSpec{}
+	decoder := json.NewDecoder(bytes.NewReader(byts))
+	decoder.UseNumber()
+	err = decoder.Decode(copied)
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/domain/arguments"
+	"github.com/otiszv/render/domain/common"
+)
+
+// newInheritanceTestCatalog catalog of template parent and child that extends it, withSCM is omitted when it is empty.
+// child does not have stages, they are only modified by patches
+func newInheritanceTestCatalog(t *testing.T, parentWithSCM, childWithSCM string) *TemplateCatalog {
+	template := `apiVersion: devops.windcloud/v1alpha1
+kind: PipelineTemplate
+metadata:
+  name: %s
+  annotations:
+    windcloud/version: v1.0.0
+spec:
+  %s
+  %s
+`
+	stages := `  stages:
+  - name: Build
+    tasks:
+    - name: Build
+      type: build
+`
+	withSCM := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return "withSCM: " + value
+	}
+	content := fmt.Sprintf(template, "parent", withSCM(parentWithSCM), "") + stages + "---\n" +
+		fmt.Sprintf(template, "child", withSCM(childWithSCM), "extends: {name: parent}")
+
+	catalog := NewTemplateCatalog()
+	for _, doc := range strings.Split(content, "---\n") {
+		kube := &Kubernete{}
+		if err := kube.LoadFromYaml(doc); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		catalog.Add(kube, "templates.yaml")
+	}
+	return catalog
+}
+
+func TestInheritWithSCM(t *testing.T) {
+	cases := []struct {
+		parent   string
+		child    string
+		expected bool
+	}{
+		{parent: "true", child: "", expected: true},
+		{parent: "true", child: "false", expected: false},
+		{parent: "false", child: "true", expected: true},
+		{parent: "", child: "", expected: false},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("parent %q child %q", c.parent, c.child), func(t *testing.T) {
+			catalog := newInheritanceTestCatalog(t, c.parent, c.child)
+			child, err := catalog.ResolvePipelineTemplate("child", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expanded, err := child.expand(catalog.ResolvePipelineTemplate, []string{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expanded.IsWithSCM() != c.expected {
+				t.Errorf("expected withSCM %t, but got %t", c.expected, expanded.IsWithSCM())
+			}
+		})
+	}
+}
+
+func TestValidateExtendsDefinition(t *testing.T) {
+	spec := &PipelineTemplateSpec{
+		Extends:     &TemplateRef{Name: "parent", Version: "^1.0"},
+		Stages:      []*Stage{{Name: "Build"}},
+		Arguments:   arguments.ArgSections{{Items: []arguments.ArgItem{{Name: "image"}}}},
+		ConstValues: &ConstValues{},
+		Post:        map[string][]*Task{"always": {{Name: "Clean"}}},
+	}
+
+	errs := common.FlattenErrors(spec.validateExtendsDefinition())
+	expected := []string{"stages", "arguments", "values", "post"}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, but got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		typed, ok := err.(common.Error)
+		if !ok || typed.Path != expected[i] || !strings.Contains(typed.Message, expected[i]+" should not be set in template that extends parent@^1.0") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	spec = &PipelineTemplateSpec{Extends: &TemplateRef{Name: "parent"}, Patches: []*TemplatePatch{{Op: PatchOpAdd, Target: "stages", Value: map[string]interface{}{"name": "Deploy"}}}}
+	if err := spec.validateExtendsDefinition(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -3,13 +3,13 @@ package domain
 // some structs definition about kubernetes
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"regexp"
 	"strings"
 
 	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/goutils"
 
 	"github.com/bitly/go-simplejson"
 	"github.com/ghodss/yaml"
@@ -19,29 +19,82 @@ import (
 type KuberneteKind string
 
 const (
-	KuberneteKindPipelineTemplate     KuberneteKind = "PipelineTemplate"
-	KuberneteKindPipelineTaskTemplate KuberneteKind = "PipelineTaskTemplate"
+	KuberneteKindPipelineTemplate            KuberneteKind = "PipelineTemplate"
+	KuberneteKindPipelineTaskTemplate        KuberneteKind = "PipelineTaskTemplate"
+	KuberneteKindClusterPipelineTemplate     KuberneteKind = "ClusterPipelineTemplate"
+	KuberneteKindClusterPipelineTaskTemplate KuberneteKind = "ClusterPipelineTaskTemplate"
+	KuberneteKindPipelineConfig              KuberneteKind = "PipelineConfig"
 )
 
 var SupportKinds = []KuberneteKind{
 	KuberneteKindPipelineTemplate, KuberneteKindPipelineTaskTemplate,
+	KuberneteKindClusterPipelineTemplate, KuberneteKindClusterPipelineTaskTemplate,
+	KuberneteKindPipelineConfig,
+}
+
+// IsClusterScoped whether kind is cluster scoped, metadata.namespace is not allowed for it
+func (kind KuberneteKind) IsClusterScoped() bool {
+	return kind == KuberneteKindClusterPipelineTemplate || kind == KuberneteKindClusterPipelineTaskTemplate
+}
+
+// TemplateKind the namespaced kind of cluster scoped kind, eg: ClusterPipelineTemplate -> PipelineTemplate
+func (kind KuberneteKind) TemplateKind() KuberneteKind {
+	switch kind {
+	case KuberneteKindClusterPipelineTemplate:
+		return KuberneteKindPipelineTemplate
+	case KuberneteKindClusterPipelineTaskTemplate:
+		return KuberneteKindPipelineTaskTemplate
+	}
+	return kind
 }
 
 const (
 	APIVersionV1Alpha1 = "devops.windcloud/v1alpha1"
+	APIVersionV1Beta1  = "devops.windcloud/v1beta1"
 )
 
+// SupportVersions v1beta1 shares the spec schema of v1alpha1, so documents of v1alpha1 are loaded as they are,
+// v1beta1 only introduces new kinds, see v1beta1OnlyKinds
 var SupportVersions = []string{
-	APIVersionV1Alpha1,
+	APIVersionV1Alpha1, APIVersionV1Beta1,
+}
+
+func isSupportVersion(apiVersion string) bool {
+	for _, version := range SupportVersions {
+		if apiVersion == version {
+			return true
+		}
+	}
+	return false
+}
+
+// kinds that are introduced by v1beta1
+var v1beta1OnlyKinds = []KuberneteKind{
+	KuberneteKindClusterPipelineTemplate,
+	KuberneteKindClusterPipelineTaskTemplate,
+	KuberneteKindPipelineConfig,
+}
+
+// validateKindVersion kinds introduced by v1beta1 are not allowed in v1alpha1
+func (kube *Kubernete) validateKindVersion() error {
+	if kube.ApiVersion != APIVersionV1Alpha1 {
+		return nil
+	}
+	for _, kind := range v1beta1OnlyKinds {
+		if kube.Kind == kind {
+			return common.NewTemplateDefinitionError(fmt.Sprintf("kind %s requires apiVersion %s", kind, APIVersionV1Beta1), nil)
+		}
+	}
+	return nil
 }
 
 type Kubernete struct {
 	ApiVersion string                 `json:"apiVersion"`
 	Kind       KuberneteKind          `json:"kind"`
 	Metadata   *simplejson.Json       `json:"metadata"`
-	Data       map[string]string      `json:"data,omitempy"`
+	Data       map[string]string      `json:"data,omitempty"`
 	Spec       *simplejson.Json       `json:"spec"`
-	Status     map[string]interface{} `json:"status,omitempy"`
+	Status     map[string]interface{} `json:"status,omitempty"`
 }
 
 func (kube *Kubernete) ValidateName() error {
@@ -56,48 +109,53 @@ func (kube *Kubernete) ValidateName() error {
 func (kube *Kubernete) GetName(defaultValue string) string {
 	val, err := kube.Metadata.Get("name").String()
 	if err != nil {
-		fmt.Printf("get name return error from %#v , error:%#v \n", kube, err)
+		goutils.Logger.Printf("get name return error from %#v , error:%#v \n", kube, err)
 		return defaultValue
 	}
 	return val
 }
 
-func (kube *Kubernete) LoadFromYaml(yamls string) error {
-	return yaml.Unmarshal([]byte(yamls), kube)
-}
-
-func (kube *Kubernete) LoadFromFile(path string) error {
-	_, err := os.Stat(path)
+// SetSpec replace spec by v, v will be converted via json
+func (kube *Kubernete) SetSpec(v interface{}) error {
+	byts, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
 
-	byts, err := ioutil.ReadFile(path)
+	spec, err := simplejson.NewJson(byts)
 	if err != nil {
 		return err
 	}
+	kube.Spec = spec
+	return nil
+}
+
+func (kube *Kubernete) LoadFromYaml(yamls string) error {
+	return yaml.Unmarshal([]byte(yamls), kube)
+}
 
-	if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
-		return kube.LoadFromYaml(string(byts))
+// LoadFromFile load kube from file that contains only one document, see LoadDocuments
+func (kube *Kubernete) LoadFromFile(path string) error {
+	docs, err := LoadDocuments(path)
+	if err != nil {
+		return err
 	}
 
-	return common.Error{
-		Message: "only support .yaml or .yml",
+	if len(docs) != 1 {
+		return common.NewLoadError(fmt.Sprintf("%s should contain only one document, but found %d", path, len(docs)), nil, map[string]interface{}{"file": path})
 	}
+	*kube = *docs[0].Kube
+	return nil
 }
 
 func (kube *Kubernete) ValidateDefinition() error {
 
-	support := false
-	for _, version := range SupportVersions {
-		if kube.ApiVersion == version {
-			support = true
-			break
-		}
+	if !isSupportVersion(kube.ApiVersion) {
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("apiVersion %s is not support now", kube.ApiVersion), nil), "apiVersion")
 	}
 
-	if !support {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("apiVersion %s is not support now", kube.ApiVersion), nil)
+	if err := kube.validateKindVersion(); err != nil {
+		return common.WithPath(err, "kind")
 	}
 
 	kindSupport := false
@@ -108,24 +166,28 @@ func (kube *Kubernete) ValidateDefinition() error {
 	}
 
 	if !kindSupport {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("kind %s is not support now", kube.Kind), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("kind %s is not support now", kube.Kind), nil), "kind")
 	}
 
 	if kube.Spec == nil {
-		return common.NewTemplateDefinitionError("spec should be required", nil)
+		return common.WithPath(common.NewTemplateDefinitionError("spec should be required", nil), "spec")
 	}
 
 	if kube.Metadata == nil {
-		return common.NewTemplateDefinitionError("metadata should not be empty", nil)
+		return common.WithPath(common.NewTemplateDefinitionError("metadata should not be empty", nil), "metadata")
 	}
 
 	err := kube.ValidateName()
 	if err != nil {
-		return common.NewTemplateDefinitionError(err.Error(), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(err.Error(), nil), "metadata.name")
+	}
+
+	if _, ok := kube.Metadata.CheckGet("namespace"); ok && kube.Kind.IsClusterScoped() {
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("metadata.namespace is not allowed for cluster scoped kind %s", kube.Kind), nil), "metadata.namespace")
 	}
 
 	var implementor k8sSpecialResource
-	switch kube.Kind {
+	switch kube.Kind.TemplateKind() {
 	case KuberneteKindPipelineTemplate:
 		{
 			define := JenkinsPipelineTemplateDefinition(*kube)
@@ -138,6 +200,12 @@ func (kube *Kubernete) ValidateDefinition() error {
 			implementor = &define
 			break
 		}
+	case KuberneteKindPipelineConfig:
+		{
+			define := JenkinsPipelineConfigDefinition(*kube)
+			implementor = &define
+			break
+		}
 	}
 
 	err = implementor.ValidateDefinition()
@@ -186,20 +254,20 @@ type JenkinsPipelineTemplateDefinition Kubernete
 func (definition *JenkinsPipelineTemplateDefinition) ValidateDefinition() error {
 	spec, err := definition.PipelineTemplateSpec()
 	if err != nil {
-		return err
+		return common.WithPath(common.NewTemplateDefinitionError(err.Error(), nil), "spec")
 	}
 	err = spec.ValidateDefinition()
 	if err != nil {
-		return err
+		return common.WithPath(err, "spec")
 	}
 
 	metadata, err := definition.PipelineTemplateMetadata()
 	if err != nil {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("Cannot get meta data from template: %s", err.Error()), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("Cannot get meta data from template: %s", err.Error()), nil), "metadata")
 	}
 	err = metadata.ValidateDefinition()
 	if err != nil {
-		return err
+		return common.WithPath(err, "metadata")
 	}
 	return nil
 }
@@ -233,7 +301,7 @@ func (definition *JenkinsPipelineTemplateDefinition) AppendStatus(statusLabels .
 	}
 
 	for _, statusLabel := range statusLabels {
-		val := fmt.Sprintf(metadata.GetLabelString(statusLabel.Label.Key, ""))
+		val := metadata.GetLabelString(statusLabel.Label.Key, "")
 		for _, v := range statusLabel.Label.InValues {
 			if v == val {
 				definition.Status[statusLabel.Status.Key] = statusLabel.Status.Value
@@ -247,21 +315,21 @@ type JenkinsPipelineTaskTemplateDefinition Kubernete
 func (definition *JenkinsPipelineTaskTemplateDefinition) ValidateDefinition() error {
 	spec, err := definition.PipelineTaskTemplateSpec()
 	if err != nil {
-		return err
+		return common.WithPath(common.NewTemplateDefinitionError(err.Error(), nil), "spec")
 	}
 	err = spec.ValidateDefinition()
 	if err != nil {
-		return err
+		return common.WithPath(err, "spec")
 	}
 
 	metadata, err := definition.PipelineTaskTemplateMetadata()
 	if err != nil {
-		return err
+		return common.WithPath(common.NewTemplateDefinitionError(err.Error(), nil), "metadata")
 	}
 
 	err = metadata.ValidateDefinition()
 	if err != nil {
-		return err
+		return common.WithPath(err, "metadata")
 	}
 	return nil
 }
@@ -290,7 +358,7 @@ func (definition *JenkinsPipelineTaskTemplateDefinition) AppendStatus(statusLabe
 		metadata.Labels = map[string]interface{}{}
 	}
 	for _, statusLabel := range statusLabels {
-		val := fmt.Sprintf(metadata.GetLabelString(statusLabel.Label.Key, ""))
+		val := metadata.GetLabelString(statusLabel.Label.Key, "")
 		for _, v := range statusLabel.Label.InValues {
 			if v == val {
 				metadata.Labels[statusLabel.Status.Key] = statusLabel.Status.Value
@@ -308,6 +376,10 @@ const (
 	AnnotationReadmeEN      = "windcloud/readme.en"
 	AnnotationVersion       = "windcloud/version"
 	AnnotationStype         = "windcloud/style.icon"
+
+	AnnotationDisplayNamePrefix = "windcloud/displayName."
+	AnnotationDescriptionPrefix = "windcloud/description."
+	AnnotationReadmePrefix      = "windcloud/readme."
 )
 
 type PipelineTemplateMetadata struct {
@@ -336,29 +408,68 @@ func (metadata *PipelineTemplateMetadata) GetLabelString(name string, defaultV s
 	return res
 }
 
+// DisplayName display name in all languages from annotations `windcloud/displayName.<locale>`
+func (metadata *PipelineTemplateMetadata) DisplayName() common.MulitLangValue {
+	return common.MulitLangValueFromPrefix(metadata.Annotations, AnnotationDisplayNamePrefix)
+}
+
+// Description description in all languages from annotations `windcloud/description.<locale>`
+func (metadata *PipelineTemplateMetadata) Description() common.MulitLangValue {
+	return common.MulitLangValueFromPrefix(metadata.Annotations, AnnotationDescriptionPrefix)
+}
+
+// Readme readme in all languages from annotations `windcloud/readme.<locale>`
+func (metadata *PipelineTemplateMetadata) Readme() common.MulitLangValue {
+	return common.MulitLangValueFromPrefix(metadata.Annotations, AnnotationReadmePrefix)
+}
+
+// LocalizedDisplayName display name of locale, see common.MulitLangValue.Localize
+func (metadata *PipelineTemplateMetadata) LocalizedDisplayName(locale string) string {
+	return metadata.DisplayName().Localize(locale)
+}
+
+// LocalizedDescription description of locale, see common.MulitLangValue.Localize
+func (metadata *PipelineTemplateMetadata) LocalizedDescription(locale string) string {
+	return metadata.Description().Localize(locale)
+}
+
+// LocalizedReadme readme of locale, see common.MulitLangValue.Localize
+func (metadata *PipelineTemplateMetadata) LocalizedReadme(locale string) string {
+	return metadata.Readme().Localize(locale)
+}
+
 // ValidateDefinition validate PipelineTemplateMetadata definition
 func (metadata *PipelineTemplateMetadata) ValidateDefinition() error {
 	if strings.TrimSpace(metadata.Name) == "" {
-		return common.NewTemplateDefinitionError("metadata.name should be required", nil)
+		return common.WithPath(common.NewTemplateDefinitionError("metadata.name should be required", nil), "name")
 	}
 
 	var requiredAnnotations = []string{
-		AnnotationDisplayNameCN,
-		AnnotationDisplayNameEN,
 		AnnotationVersion,
 	}
+	for _, locale := range common.GetLocaleConfig().Required {
+		requiredAnnotations = append(requiredAnnotations, AnnotationDisplayNamePrefix+locale)
+	}
 	errs := common.Errors{}
 	for _, name := range requiredAnnotations {
 		if strings.TrimSpace(metadata.GetAnnotation(name)) == "" {
-			errs = append(errs, common.NewTemplateDefinitionError(fmt.Sprintf("metadata.annotations.[%s] is required", name), nil))
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("metadata.annotations.[%s] is required", name), nil), annotationPath(name)))
 		}
 	}
 
 	version := metadata.GetAnnotation(AnnotationVersion)
 	if version != "" && !strings.HasPrefix(version, "v") {
-		errs = append(errs,
+		errs = append(errs, common.WithPath(
 			common.NewTemplateDefinitionError(fmt.Sprintf("metadata.annotations.[%s] should start with \"v\" ", AnnotationVersion), nil),
-		)
+			annotationPath(AnnotationVersion),
+		))
+	} else if version != "" {
+		if _, err := ParseVersion(version); err != nil {
+			errs = append(errs, common.WithPath(
+				common.NewTemplateDefinitionError(fmt.Sprintf("metadata.annotations.[%s]: %s", AnnotationVersion, err.Error()), nil),
+				annotationPath(AnnotationVersion),
+			))
+		}
 	}
 
 	if len(errs) == 0 {
@@ -367,3 +478,8 @@ func (metadata *PipelineTemplateMetadata) ValidateDefinition() error {
 
 	return errs
 }
+
+// annotationPath path of annotation relative to metadata, eg: annotations["windcloud/version"]
+func annotationPath(name string) string {
+	return common.JoinPath("annotations", common.PathKey(name))
+}
@@ -0,0 +1,346 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/otiszv/render/domain/common"
+)
+
+// StdinPath path that means loading documents from stdin
+const StdinPath = "-"
+
+// KuberneteDocument a kubernete resource loaded from file or stdin
+type KuberneteDocument struct {
+	File string
+	// Index index of document in file, starts from 1
+	Index int
+	// Item index in items of List kind, starts from 1, 0 means it is not an item of List
+	Item int
+	// Line line number that document starts at
+	Line int
+	Kube *Kubernete
+
+	// positions of fields keyed by path, only for yaml documents
+	positions map[string]Position
+}
+
+// Name file name for the first document, and `file#index` or `file#index.item` for others
+func (doc *KuberneteDocument) Name() string {
+	switch {
+	case doc.Item > 0:
+		return fmt.Sprintf("%s#%d.%d", doc.File, doc.Index, doc.Item)
+	case doc.Index > 1:
+		return fmt.Sprintf("%s#%d", doc.File, doc.Index)
+	}
+	return doc.File
+}
+
+// LoadDocuments load all kubernete documents from file, path `-` means stdin.
+// multi-document yaml, json and List kinds are supported
+func LoadDocuments(path string) ([]*KuberneteDocument, error) {
+	if path == StdinPath {
+		return LoadDocumentsFromReader("<stdin>", os.Stdin)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, common.NewLoadError(fmt.Sprintf("%s: only support .yaml, .yml or .json", path), nil, map[string]interface{}{"file": path})
+	}
+
+	byts, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, common.NewLoadError(err.Error(), err, map[string]interface{}{"file": path})
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return loadJSONDocuments(path, byts)
+	}
+	return LoadDocumentsFromBytes(path, byts)
+}
+
+// LoadDocumentsFromReader load all kubernete documents from reader, name is used in errors
+func LoadDocumentsFromReader(name string, reader io.Reader) ([]*KuberneteDocument, error) {
+	byts, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDocumentsFromBytes(name, byts)
+}
+
+// LoadDocumentsFromBytes load all kubernete documents from yaml or json content, name is used in errors
+func LoadDocumentsFromBytes(name string, byts []byte) ([]*KuberneteDocument, error) {
+	trimed := bytes.TrimSpace(byts)
+	if len(trimed) > 0 && (trimed[0] == '{' || trimed[0] == '[') {
+		return loadJSONDocuments(name, byts)
+	}
+	return loadYAMLDocuments(name, byts)
+}
+
+var yamlErrorLineRegx = regexp.MustCompile(`line (\d+)`)
+
+func loadYAMLDocuments(name string, byts []byte) ([]*KuberneteDocument, error) {
+	docs := []*KuberneteDocument{}
+	errs := common.Errors{}
+
+	index := 0
+	for _, source := range splitYAMLDocuments(string(byts)) {
+		index++
+		raw, err := yaml.YAMLToJSON([]byte(source.content))
+		if err != nil {
+			// line numbers in yaml errors are relative to document
+			line := source.line
+			message := yamlErrorLineRegx.ReplaceAllStringFunc(err.Error(), func(match string) string {
+				n, _ := strconv.Atoi(strings.TrimPrefix(match, "line "))
+				line = n + source.line - 1
+				return fmt.Sprintf("line %d", line)
+			})
+			errs = append(errs, newLoadError(name, index, line, message))
+			continue
+		}
+
+		loaded, err := decodeDocument(name, index, source.line, raw, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		positions := indexYAMLPositions(source.content, source.line)
+		for _, doc := range loaded {
+			doc.positions = positions
+			if position, ok := doc.Position(""); ok && doc.Item > 0 {
+				doc.Line = position.Line
+			}
+		}
+		docs = append(docs, loaded...)
+	}
+
+	if len(errs) == 0 {
+		return docs, nil
+	}
+	return docs, errs
+}
+
+type yamlDocumentSource struct {
+	content string
+	line    int
+}
+
+// splitYAMLDocuments split yaml stream by `---`, documents that only contain comments are ignored
+func splitYAMLDocuments(content string) []yamlDocumentSource {
+	sources := []yamlDocumentSource{}
+	lines := strings.Split(content, "\n")
+
+	current := []string{}
+	start := 1
+	flush := func() {
+		for _, line := range current {
+			trimed := strings.TrimSpace(line)
+			if trimed != "" && !strings.HasPrefix(trimed, "#") {
+				sources = append(sources, yamlDocumentSource{content: strings.Join(current, "\n"), line: start})
+				break
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimRight(line, " \t\r") == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t") {
+			flush()
+			current = []string{}
+			start = i + 2
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return sources
+}
+
+func loadJSONDocuments(name string, byts []byte) ([]*KuberneteDocument, error) {
+	docs := []*KuberneteDocument{}
+	errs := common.Errors{}
+
+	decoder := json.NewDecoder(bytes.NewReader(byts))
+	index := 0
+	for {
+		offset := decoder.InputOffset()
+		raw := json.RawMessage{}
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				offset = syntaxErr.Offset
+			}
+			errs = append(errs, newLoadError(name, index+1, lineOfOffset(byts, offset), err.Error()))
+			break
+		}
+
+		// skip whitespaces before the value
+		start := offset + skipJSONSpaces(byts[offset:], false)
+		line := lineOfOffset(byts, start)
+		lineOf := func(offset int64) int {
+			return lineOfOffset(byts, start+offset)
+		}
+		// top level array, each element is a document
+		elements := []json.RawMessage{raw}
+		elementOffsets := []int64{0}
+		if trimed := bytes.TrimSpace(raw); len(trimed) > 0 && trimed[0] == '[' {
+			elements = []json.RawMessage{}
+			if err = json.Unmarshal(raw, &elements); err != nil {
+				errs = append(errs, newLoadError(name, index+1, line, err.Error()))
+				continue
+			}
+			elementOffsets = jsonArrayOffsets(raw)
+		}
+
+		for i, element := range elements {
+			index++
+			elementOffset := elementOffsets[i]
+			loaded, err := decodeDocument(name, index, lineOf(elementOffset), element, func(offset int64) int {
+				return lineOf(elementOffset + offset)
+			})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			docs = append(docs, loaded...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return docs, nil
+	}
+	return docs, errs
+}
+
+// decodeDocument decode json of document to kubernetes, items of List kind will be decoded to multi documents.
+// lineOf return line of offset in raw, it is nil when raw is converted from yaml, items of List start at line then
+func decodeDocument(name string, index int, line int, raw []byte, lineOf func(offset int64) int) ([]*KuberneteDocument, error) {
+	list := struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}{}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, newLoadError(name, index, line, err.Error())
+	}
+
+	if !isListKind(list.Kind) {
+		kube := &Kubernete{}
+		if err := json.Unmarshal(raw, kube); err != nil {
+			return nil, newLoadError(name, index, line, err.Error())
+		}
+		return []*KuberneteDocument{{File: name, Index: index, Line: line, Kube: kube}}, nil
+	}
+
+	var itemOffsets []int64
+	if lineOf != nil {
+		itemOffsets = jsonItemsOffsets(raw)
+	}
+	docs := []*KuberneteDocument{}
+	errs := common.Errors{}
+	for i, item := range list.Items {
+		itemLine := line
+		if i < len(itemOffsets) {
+			itemLine = lineOf(itemOffsets[i])
+		}
+		kube := &Kubernete{}
+		if err := json.Unmarshal(item, kube); err != nil {
+			errs = append(errs, newLoadError(name, index, itemLine, fmt.Sprintf("items[%d]: %s", i, err.Error())))
+			continue
+		}
+		docs = append(docs, &KuberneteDocument{File: name, Index: index, Item: i + 1, Line: itemLine, Kube: kube})
+	}
+	if len(errs) == 0 {
+		return docs, nil
+	}
+	return docs, errs
+}
+
+// jsonItemsOffsets offsets of elements of `items` in json object raw, nil if they could not be found
+func jsonItemsOffsets(raw []byte) []int64 {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		if key == "items" {
+			return decodeJSONArrayOffsets(decoder, raw)
+		}
+		value := json.RawMessage{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// jsonArrayOffsets offsets of elements in json array raw, nil if raw is not an array
+func jsonArrayOffsets(raw []byte) []int64 {
+	return decodeJSONArrayOffsets(json.NewDecoder(bytes.NewReader(raw)), raw)
+}
+
+// decodeJSONArrayOffsets decode the next array of decoder and return offsets of its elements in raw that decoder reads
+func decodeJSONArrayOffsets(decoder *json.Decoder, raw []byte) []int64 {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil
+	}
+	offsets := []int64{}
+	for decoder.More() {
+		offset := decoder.InputOffset()
+		// the separator of previous element is not consumed yet
+		offset += skipJSONSpaces(raw[offset:], true)
+		element := json.RawMessage{}
+		if err := decoder.Decode(&element); err != nil {
+			return nil
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// skipJSONSpaces count of leading whitespaces, and commas if withComma
+func skipJSONSpaces(byts []byte, withComma bool) int64 {
+	cutset := " \t\r\n"
+	if withComma {
+		cutset += ","
+	}
+	return int64(len(byts) - len(bytes.TrimLeft(byts, cutset)))
+}
+
+// isListKind List or kinds like PipelineTemplateList
+func isListKind(kind string) bool {
+	return strings.HasSuffix(kind, "List")
+}
+
+func lineOfOffset(byts []byte, offset int64) int {
+	if offset > int64(len(byts)) {
+		offset = int64(len(byts))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return bytes.Count(byts[:offset], []byte("\n")) + 1
+}
+
+func newLoadError(file string, index int, line int, message string) error {
+	err := common.NewLoadError(
+		fmt.Sprintf("%s:%d: document #%d: %s", file, line, index, message), nil,
+		map[string]interface{}{"file": file, "document": index},
+	).(common.Error)
+	err.Line = line
+	return err
+}
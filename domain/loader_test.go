@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+func TestLoadDocumentsLines(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		// names and lines of documents
+		expected map[string]int
+	}{
+		{
+			name: "json list",
+			content: `{
+  "kind": "List",
+  "items": [
+    {"kind": "PipelineTemplate", "metadata": {"name": "a"}},
+
+    {
+      "kind": "PipelineTaskTemplate",
+      "metadata": {"name": "b"}
+    }
+  ]
+}`,
+			expected: map[string]int{"file#1.1": 4, "file#1.2": 6},
+		},
+		{
+			name: "json array and list in it",
+			content: `[
+  {"kind": "PipelineTemplate", "metadata": {"name": "a"}},
+  {"kind": "List", "items": [
+    {"kind": "PipelineTaskTemplate", "metadata": {"name": "b"}}, {"kind": "PipelineTaskTemplate", "metadata": {"name": "c"}},
+    {"kind": "PipelineTaskTemplate", "metadata": {"name": "d"}}
+  ]}
+]`,
+			expected: map[string]int{"file": 2, "file#2.1": 4, "file#2.2": 4, "file#2.3": 5},
+		},
+		{
+			name: "yaml list",
+			content: `kind: List
+items:
+- kind: PipelineTemplate
+  metadata:
+    name: a
+- kind: PipelineTaskTemplate
+  metadata:
+    name: b
+---
+kind: PipelineTemplate
+metadata:
+  name: c
+`,
+			expected: map[string]int{"file#1.1": 3, "file#1.2": 6, "file#2": 10},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			docs, err := LoadDocumentsFromBytes("file", []byte(c.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(docs) != len(c.expected) {
+				t.Fatalf("expected %d documents, but got %d", len(c.expected), len(docs))
+			}
+			for _, doc := range docs {
+				if line, ok := c.expected[doc.Name()]; !ok || doc.Line != line {
+					t.Errorf("%s: expected line %d, but got %d", doc.Name(), line, doc.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDocumentsItemErrorLine(t *testing.T) {
+	_, err := LoadDocumentsFromBytes("file", []byte(`{"kind": "List", "items": [
+  {"kind": "PipelineTemplate"},
+  {"kind": 1}
+]}`))
+	errs := common.FlattenErrors(err)
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, but got %v", err)
+	}
+	if typed, ok := errs[0].(common.Error); !ok || typed.Line != 3 {
+		t.Errorf("expected error at line 3, but got %v", errs[0])
+	}
+}
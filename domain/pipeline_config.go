@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/jenkinsfile"
+)
+
+// PipelineConfigSpec binds a pipeline template with arguments values and scm, so it could be rendered directly
+type PipelineConfigSpec struct {
+	Template  TemplateRef            `json:"template"`
+	Arguments map[string]interface{} `json:"arguments"`
+	SCM       *SCMInfo               `json:"scm"`
+}
+
+// ValidateDefinition validate PipelineConfigSpec definition, values are validated when render
+func (spec *PipelineConfigSpec) ValidateDefinition() error {
+	errs := common.Errors{}
+
+	if strings.TrimSpace(spec.Template.Name) == "" {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("template.name should be required", nil), "template.name"))
+	}
+	if _, err := ParseVersionConstraint(spec.Template.Version); err != nil {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("template.version: %s", err.Error()), nil), "template.version"))
+	}
+
+	if spec.SCM != nil {
+		if err := spec.SCM.Validate(); err != nil {
+			errs = append(errs, common.WithPath(err, "scm"))
+		}
+	}
+
+	if system, ok := spec.Arguments[SystemArgKey]; ok {
+		if _, err := ParseSystemContext(system); err != nil {
+			errs = append(errs, common.WithPath(err, common.JoinPath("arguments", SystemArgKey)))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Render render the pipeline template that spec refers in catalog to jenkinsfile
+func (spec *PipelineConfigSpec) Render(catalog *TemplateCatalog) (string, error) {
+	pipeline, err := spec.Pipeline(catalog)
+	if err != nil {
+		return "", err
+	}
+	return pipeline.RenderAndFormat()
+}
+
+// Pipeline parse the pipeline template that spec refers in catalog to jenkinsfile pipeline, it is not rendered yet
+func (spec *PipelineConfigSpec) Pipeline(catalog *TemplateCatalog) (*jenkinsfile.Pipeline, error) {
+	template, err := catalog.ResolvePipelineTemplate(spec.Template.Name, spec.Template.Version)
+	if err != nil {
+		return nil, err
+	}
+	if template.IsExtended() {
+		template, err = template.Expand(catalog.ResolvePipelineTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	taskTemplates, err := catalog.ResolveTaskTemplates(template)
+	if err != nil {
+		return nil, err
+	}
+	return template.Pipeline(taskTemplates, spec.Arguments, spec.SCM)
+}
+
+type JenkinsPipelineConfigDefinition Kubernete
+
+func (definition *JenkinsPipelineConfigDefinition) ValidateDefinition() error {
+	spec, err := definition.PipelineConfigSpec()
+	if err != nil {
+		return common.WithPath(common.NewTemplateDefinitionError(err.Error(), nil), "spec")
+	}
+	err = spec.ValidateDefinition()
+	if err != nil {
+		return common.WithPath(err, "spec")
+	}
+
+	metadata, err := definition.PipelineConfigMetadata()
+	if err != nil {
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("Cannot get meta data from config: %s", err.Error()), nil), "metadata")
+	}
+	if strings.TrimSpace(metadata.Name) == "" {
+		return common.WithPath(common.NewTemplateDefinitionError("metadata.name should be required", nil), "metadata.name")
+	}
+	return nil
+}
+
+func (definition *JenkinsPipelineConfigDefinition) PipelineConfigSpec() (*PipelineConfigSpec, error) {
+	spec := PipelineConfigSpec{}
+	err := mapstructure.Decode(definition.Spec.MustMap(), &spec)
+	if err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (definition *JenkinsPipelineConfigDefinition) PipelineConfigMetadata() (*PipelineTemplateMetadata, error) {
+	metadata := PipelineTemplateMetadata{}
+	err := mapstructure.Decode(definition.Metadata.MustMap(), &metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+func (definition *JenkinsPipelineConfigDefinition) AppendStatus(statusLabels ...statusResourceLabel) {
+	metadata, _ := definition.PipelineConfigMetadata()
+	if definition.Status == nil {
+		definition.Status = map[string]interface{}{}
+	}
+	for _, statusLabel := range statusLabels {
+		val := metadata.GetLabelString(statusLabel.Label.Key, "")
+		for _, v := range statusLabel.Label.InValues {
+			if v == val {
+				definition.Status[statusLabel.Status.Key] = statusLabel.Status.Value
+			}
+		}
+	}
+}
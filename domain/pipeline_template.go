@@ -2,6 +2,7 @@ package domain
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/otiszv/render/domain/arguments"
@@ -14,7 +15,7 @@ import (
 
 type PipelineTemplateSpec struct {
 	Engine       string                `json:"engine"`
-	WithSCM      bool                  `json:"withSCM"  mapstructure:"withSCM" yaml:"withSCM"`
+	WithSCM      *bool                 `json:"withSCM"  mapstructure:"withSCM" yaml:"withSCM"`
 	Agent        interface{}           `json:"agent" mapstructure:"agent" yaml:"agent"`
 	Stages       []*Stage              `json:"stages"`
 	Post         map[string][]*Task    `json:"post"`
@@ -22,24 +23,10 @@ type PipelineTemplateSpec struct {
 	Options      *jenkinsfile.Options  `json:"options"`
 	Arguments    arguments.ArgSections `json:"arguments"`
 	Environments []jenkinsfile.EnvVar  `json:"environments"`
-}
-
-type SCMInfo struct {
-	Type           scmType
-	RepositoryPath string
-	CredentialsID  string
-	Branch         string
-}
 
-type scmType string
-
-// SCMTypeEnum enum of SCMType
-var SCMTypeEnum = struct {
-	GIT scmType
-	SVN scmType
-}{
-	GIT: "GIT",
-	SVN: "SVN",
+	// Extends parent template, Patches will be applied to it, see Expand
+	Extends *TemplateRef     `json:"extends,omitempty"`
+	Patches []*TemplatePatch `json:"patches,omitempty"`
 }
 
 type ConstValues struct {
@@ -53,18 +40,29 @@ type TaskConstValue struct {
 }
 
 type Stage struct {
-	Name       string            `json:"string"`
-	Conditions *jenkinsfile.When `json:"conditions"`
-	Tasks      []*Task           `json:"tasks"`
+	Name string `json:"name"`
+	// Agent default agent of tasks in stage, task that sets its own agent overrides it
+	Agent        interface{}          `json:"agent"`
+	Conditions   *jenkinsfile.When    `json:"conditions"`
+	Environments []jenkinsfile.EnvVar `json:"environments"`
+	Tasks        []*Task              `json:"tasks"`
 }
 
 func (s *Stage) validateDefinition() error {
 	if strings.TrimSpace(s.Name) == "" {
-		return common.NewTemplateDefinitionError("stage.name should not be empty", nil)
+		return common.WithPath(common.NewTemplateDefinitionError("stage.name should not be empty", nil), "name")
 	}
 
 	if s.Tasks == nil || len(s.Tasks) == 0 {
-		return common.NewTemplateDefinitionError(fmt.Sprintf("stage `%s`'s tasks should be one at least", s.Name), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("stage `%s`'s tasks should be one at least", s.Name), nil), "tasks")
+	}
+
+	if err := ValidateAgent(s.Agent); err != nil {
+		return common.WithPath(err, "agent")
+	}
+
+	if err := validateEnvironments(s.Environments); err != nil {
+		return common.WithPath(err, "environments")
 	}
 	return nil
 }
@@ -86,25 +84,39 @@ type Task struct {
 
 func (task *Task) IsMeaningful(argumentsValues map[string]interface{}) bool {
 	meaningful := task.Relation.IsMathcShowAction(argumentsValues)
-	fmt.Printf("task `%s` meaningful = %t \n", task.Name, meaningful)
+	goutils.Logger.Printf("task `%s` meaningful = %t \n", task.Name, meaningful)
 	return meaningful
 }
 
 func (t *Task) validateDefinition() error {
 	errs := common.Errors{}
 	if t.Name == "" {
-		errs = append(errs, common.NewTemplateDefinitionError("task %s.name shoule not be empty", nil))
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("task.name shoule not be empty", nil), "name"))
 	}
 	if t.Type == "" {
-		errs = append(errs, common.NewTemplateDefinitionError("task %s.type shoule not be empty", nil))
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("task %s.type shoule not be empty", t.Name), nil), "type"))
 	}
 
 	if err := ValidateAgent(t.Agent); err != nil {
-		errs = append(errs, err)
+		errs = append(errs, common.WithPath(err, "agent"))
+	}
+
+	if err := validateEnvironments(t.Environments); err != nil {
+		errs = append(errs, common.WithPath(err, "environments"))
 	}
 
 	if strings.Index(t.Name, ".") >= 0 { // name 不能含 .
-		errs = append(errs, common.NewTemplateDefinitionError(fmt.Sprintf("task name :%s should not contains dot ", t.Name), nil))
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("task name :%s should not contains dot ", t.Name), nil), "name"))
+	}
+
+	if _, constraint := SplitTaskType(t.Type); constraint != "" {
+		if _, err := ParseVersionConstraint(constraint); err != nil {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("task %s.type: %s", t.Name, err.Error()), nil), "type"))
+		}
+	}
+
+	if err := t.Relation.ValidateDefinition(); err != nil {
+		errs = append(errs, common.WithPath(err, "relation"))
 	}
 
 	if len(errs) == 0 {
@@ -114,12 +126,19 @@ func (t *Task) validateDefinition() error {
 	return errs
 }
 
+// unboxToInt values decoded from json are float64, they are accepted when they are integral
 func unboxToInt(value interface{}) (int, error) {
-	v, ok := value.(int)
-	if !ok {
-		return 0, fmt.Errorf("%v is not int", value)
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
 	}
-	return v, nil
+	return 0, fmt.Errorf("%v is not int", value)
 }
 
 func (t *Task) applyConstValue(constValues *TaskConstValue) {
@@ -164,44 +183,65 @@ func (t *Task) assignSystemArgValue(systemArg interface{}) {
 }
 
 func (t *Task) assignArgValues(argValues map[string]interface{}) error {
+	errs := common.Errors{}
 	for path, value := range argValues {
 		err := t.assignArgValueByPath(path, value)
 		if err != nil {
-			return err
+			errs = append(errs, common.WithTask(err, t.Name))
 		}
 	}
-	return nil
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
 
+// assignArgValueByPath assign value to field of task, path is one of bindingTaskFields, value nil means it is not set
 func (t *Task) assignArgValueByPath(path string, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
 	switch path {
 	case "options.timeout":
 		v, err := unboxToInt(value)
 		if err != nil {
 			return common.NewValidateError(fmt.Sprintf("%s's value %v should be int, but got %T", path, value, value), nil)
 		}
+		if t.Options == nil {
+			t.Options = &jenkinsfile.Options{}
+		}
 		t.Options.Timeout = v
 	case "approve.timeout":
 		v, err := unboxToInt(value)
 		if err != nil {
-			return err
+			return common.NewValidateError(fmt.Sprintf("%s's value %v should be int, but got %T", path, value, value), nil)
 		}
-		t.Options.Timeout = v
+		if t.Approve == nil {
+			t.Approve = &jenkinsfile.Approve{}
+		}
+		t.Approve.Timeout = v
 	}
 	return nil
 }
 
-func (t *Task) toJenkinsfileStage() (*jenkinsfile.Stage, error) {
+// toJenkinsfileStage render task to stage, stageAgent is agent of the stage that task belongs to
+func (t *Task) toJenkinsfileStage(stageAgent interface{}) (*jenkinsfile.Stage, error) {
 	taskScriptBody, err := t.taskTemplateSpec.Render(t.taskTemplateArgValues)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// agents set in pipeline template are preferred: task, then stage, then task template
 	var agent = t.Agent
+	if agent == nil {
+		agent = stageAgent
+	}
 	if agent == nil {
 		agent = t.taskTemplateSpec.Agent
-	} // if set agent in pipeline template , just use it. if not set in pipeline template but set in task template, use that.
+	}
 
 	jenkinsStage := &jenkinsfile.Stage{
 		Name:         t.Name,
@@ -219,12 +259,22 @@ func (t *Task) toJenkinsfileStage() (*jenkinsfile.Stage, error) {
 }
 
 // ValidateDefinition validate template define
+// template that extends another template only validate itself, you should validate the result of Expand
 func (spec *PipelineTemplateSpec) ValidateDefinition() error {
+	if spec.IsExtended() {
+		return spec.validateExtendsDefinition()
+	}
+
 	errs := common.Errors{}
 
 	err := ValidateAgent(spec.Agent)
 	if err != nil {
-		errs = append(errs, err)
+		errs = append(errs, common.WithPath(err, "agent"))
+	}
+
+	err = validateEnvironments(spec.Environments)
+	if err != nil {
+		errs = append(errs, common.WithPath(err, "environments"))
 	}
 
 	err = spec.validateStagesDefinition()
@@ -237,11 +287,14 @@ func (spec *PipelineTemplateSpec) ValidateDefinition() error {
 		errs = append(errs, err)
 	}
 
-	for _, argItem := range spec.Arguments.AllArgItems() {
-		err = argItem.ValidateDefinition()
-		if err != nil {
-			errs = append(errs, err)
-		}
+	err = spec.Arguments.ValidateDefinition()
+	if err != nil {
+		errs = append(errs, common.WithPath(err, "arguments"))
+	}
+
+	err = spec.validateRelationsDefinition()
+	if err != nil {
+		errs = append(errs, err)
 	}
 
 	if len(errs) > 0 {
@@ -250,17 +303,61 @@ func (spec *PipelineTemplateSpec) ValidateDefinition() error {
 	return nil
 }
 
+func (spec *PipelineTemplateSpec) validateRelationsDefinition() error {
+	errs := common.Errors{}
+
+	err := spec.Arguments.ValidateRelations()
+	if err != nil {
+		errs = append(errs, common.WithPath(err, "arguments"))
+	}
+
+	names := map[string]struct{}{}
+	for _, argItem := range spec.Arguments.AllArgItems() {
+		names[argItem.Name] = struct{}{}
+	}
+	taskPaths := spec.taskPaths()
+	for _, task := range spec.allTasksWithPost() {
+		for _, ref := range task.Relation.ReferredNames() {
+			if _, ok := arguments.ResolveReferredName(names, ref); !ok && !strings.HasPrefix(ref, "_") {
+				err := common.NewTemplateDefinitionError(fmt.Sprintf("task %s.relation refers to argument `%s` that is not exists", task.Name, ref), nil)
+				err = common.WithArgument(common.WithTask(err, task.Name), ref)
+				errs = append(errs, common.WithPath(err, common.JoinPath(taskPaths[task.Name], "relation")))
+			}
+			if isUnknownSystemArgRef(ref) {
+				err := common.NewTemplateDefinitionError(fmt.Sprintf("task %s.relation refers to `%s` that is not a field of system context", task.Name, ref), nil)
+				errs = append(errs, common.WithPath(common.WithTask(err, task.Name), common.JoinPath(taskPaths[task.Name], "relation")))
+			}
+		}
+	}
+
+	// references to unknown arguments are reported by ValidateRelations, but the ones to system context are not
+	for _, argItem := range spec.Arguments.AllArgItems() {
+		for _, ref := range argItem.Relation.ReferredNames() {
+			if isUnknownSystemArgRef(ref) {
+				err := common.NewTemplateDefinitionError(fmt.Sprintf("%s.relation refers to `%s` that is not a field of system context", argItem.Name, ref), nil)
+				err = common.WithArgument(err, argItem.Name)
+				errs = append(errs, common.WithPath(err, common.JoinPath("arguments", spec.Arguments.ArgPath(argItem.Name), "relation")))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
 func (spec *PipelineTemplateSpec) validateStagesDefinition() error {
 	errs := common.Errors{}
 
 	if spec.Stages == nil || len(spec.Stages) == 0 {
-		return common.NewTemplateDefinitionError(fmt.Sprint("stages should be one at least"), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprint("stages should be one at least"), nil), "stages")
 	}
 
-	for _, stage := range spec.Stages {
+	for i, stage := range spec.Stages {
 		err := stage.validateDefinition()
 		if err != nil {
-			errs = append(errs, err)
+			errs = append(errs, common.WithPath(err, fmt.Sprintf("stages[%d]", i)))
 		}
 	}
 
@@ -277,18 +374,22 @@ func (spec *PipelineTemplateSpec) validateTasksDefinition() error {
 
 	tasks := spec.allTasks()
 	if tasks == nil || len(tasks) == 0 {
-		return common.NewTemplateDefinitionError(fmt.Sprint("tasks should be one at least"), nil)
+		return common.WithPath(common.NewTemplateDefinitionError(fmt.Sprint("tasks should be one at least"), nil), "stages")
 	}
 
-	for _, task := range tasks {
-		if _, ok := nameMap[task.Name]; ok {
-			errs = append(errs, common.NewTemplateDefinitionError(fmt.Sprintf("task name :%s should be unique", task.Name), nil))
-		} else {
-			nameMap[task.Name] = struct{}{}
-		}
-		err := task.validateDefinition()
-		if err != nil {
-			errs = append(errs, err)
+	for i, stage := range spec.Stages {
+		for j, task := range stage.Tasks {
+			path := fmt.Sprintf("stages[%d].tasks[%d]", i, j)
+			if _, ok := nameMap[task.Name]; ok {
+				err := common.WithTask(common.NewTemplateDefinitionError(fmt.Sprintf("task name :%s should be unique", task.Name), nil), task.Name)
+				errs = append(errs, common.WithPath(err, path+".name"))
+			} else {
+				nameMap[task.Name] = struct{}{}
+			}
+			err := task.validateDefinition()
+			if err != nil {
+				errs = append(errs, common.WithPath(common.WithTask(err, task.Name), path))
+			}
 		}
 	}
 
@@ -306,17 +407,20 @@ func (spec *PipelineTemplateSpec) ValidateValue(argumentsValues map[string]inter
 		argItemsMap[argItem.Name] = argItem
 	}
 
+	meaningfulArgs := spec.Arguments.MeaningfulArgs(argumentsValues)
+
 	errs := common.Errors{}
 	for argName, value := range argumentsValues {
 		if argItem, ok := argItemsMap[argName]; ok {
-			if !argItem.IsMeaningful(argumentsValues) {
-				fmt.Printf("arg `%s` is not meaningful , skip validate value \n", argItem.Name)
+			if !meaningfulArgs[argItem.Name] {
+				goutils.Logger.Printf("arg `%s` is not meaningful , skip validate value \n", argItem.Name)
 				continue
 			}
 
 			err := argItem.ValidateValue(value)
 			if err != nil {
-				errs = append(errs, err)
+				err = common.WithArgument(err, argName)
+				errs = append(errs, common.WithPath(err, common.PathKey(argName)))
 			}
 		}
 	}
@@ -330,47 +434,81 @@ func (spec *PipelineTemplateSpec) ValidateValue(argumentsValues map[string]inter
 //Render redner PipelineTemplateSpec to jenkinsfile content
 // taskTemplatesRef: you must add `clone` template refs
 func (spec *PipelineTemplateSpec) Render(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (string, error) {
-	return spec.getRenderEngine()(taskTemplatesRef, argumentsValues, scm)
+	pipeline, err := spec.getRenderEngine()(taskTemplatesRef, argumentsValues, scm)
+	if err != nil {
+		return "", err
+	}
+	return pipeline.Render()
+}
+
+// RenderAndFormat render and format it in the style of options, the default style is used when options are omitted
+func (spec *PipelineTemplateSpec) RenderAndFormat(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo, options ...formatter.Options) (string, error) {
+	pipeline, err := spec.getRenderEngine()(taskTemplatesRef, argumentsValues, scm)
+	if err != nil {
+		return "", err
+	}
+	return pipeline.RenderAndFormat(options...)
 }
 
-func (spec *PipelineTemplateSpec) RenderAndFormat(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (string, error) {
+// RenderAndFormatTo render and format it in the style of options, and write it to w while it is rendered
+func (spec *PipelineTemplateSpec) RenderAndFormatTo(w io.Writer, taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo, options ...formatter.Options) error {
 	pipeline, err := spec.getRenderEngine()(taskTemplatesRef, argumentsValues, scm)
+	if err != nil {
+		return err
+	}
+	return pipeline.RenderAndFormatTo(w, options...)
+}
 
-	return formatter.Format(pipeline), err
+// Pipeline parse spec to jenkinsfile pipeline with values, it is not rendered yet
+func (spec *PipelineTemplateSpec) Pipeline(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (*jenkinsfile.Pipeline, error) {
+	return spec.getRenderEngine()(taskTemplatesRef, argumentsValues, scm)
 }
 
-type pipelineTemplateRenderEngine func(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (string, error)
+type pipelineTemplateRenderEngine func(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (*jenkinsfile.Pipeline, error)
 
 func (spec *PipelineTemplateSpec) getRenderEngine() pipelineTemplateRenderEngine {
 	switch spec.Engine {
 
 	default: //default is graph
 		{
-			return func(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (string, error) {
+			return func(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (*jenkinsfile.Pipeline, error) {
 				return spec.graphRender(taskTemplatesRef, argumentsValues, scm)
 			}
 		}
 	}
 }
 
-func (spec *PipelineTemplateSpec) graphRender(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (string, error) {
+func (spec *PipelineTemplateSpec) graphRender(taskTemplatesRef map[string]TaskTemplateSpec, argumentsValues map[string]interface{}, scm *SCMInfo) (*jenkinsfile.Pipeline, error) {
 
 	err := spec.ValidateDefinition()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// merge default values to argumentsValue
 	defaultValues := spec.getDefaultValues()
 	argumentsValues = goutils.MergeMap(defaultValues, argumentsValues)
 
+	system, err := ParseSystemContext(argumentsValues[SystemArgKey])
+	if err != nil {
+		return nil, common.WithPath(err, SystemArgKey)
+	}
+	if system != nil {
+		argumentsValues[SystemArgKey] = system.Values()
+	}
+
 	err = spec.ValidateValue(argumentsValues)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	//scm is fixex information
-	if spec.WithSCM {
+	if spec.IsWithSCM() {
+		if scm != nil {
+			if err := scm.Validate(); err != nil {
+				return nil, common.WithPath(err, "scm")
+			}
+		}
 		argumentsValues[CloneTaskTemplateArgName] = scm
 		spec.addSCMArg()
 	}
@@ -378,25 +516,35 @@ func (spec *PipelineTemplateSpec) graphRender(taskTemplatesRef map[string]TaskTe
 	// append task template spec reference
 	err = spec.appendTaskTemplateSpecRef(taskTemplatesRef)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// apply const values
 	spec.applyConstValues()
 
 	// assign value to all tasks
-	spec.assignValuesToEachTask(argumentsValues)
+	err = spec.assignValuesToEachTask(argumentsValues)
+	if err != nil {
+		return nil, err
+	}
 
 	// mark the task that meaningful
 	spec.markMeaningfulTask(argumentsValues)
 
 	pipeline, err := spec.parseToJenkinsfilePipeline()
 	if err != nil {
-		fmt.Printf("parse to jenkinsfile pipeline error:%#v", err)
-		return "", err
+		goutils.Logger.Printf("parse to jenkinsfile pipeline error:%#v", err)
+		return nil, err
 	}
+	pipeline.Options = system.PipelineOptions(pipeline.Options)
 
-	return pipeline.Render()
+	return pipeline, nil
+}
+
+// IsWithSCM whether pipeline has scm, WithSCM is nil when it is not set, and it is inherited from parent template if there is.
+// breaking change: WithSCM used to be bool, go callers should read it by IsWithSCM and set it by a pointer
+func (spec *PipelineTemplateSpec) IsWithSCM() bool {
+	return spec.WithSCM != nil && *spec.WithSCM
 }
 
 func (spec *PipelineTemplateSpec) addSCMArg() {
@@ -413,12 +561,13 @@ func (spec *PipelineTemplateSpec) addSCMArg() {
 		Schema: &arguments.ArgItemSchema{
 			Type: "object",
 		},
-		Binding: []string{
-			"Clone.args.SCM",
-		},
 		Required: true,
 	}
 
+	for _, taskName := range spec.scmBoundTasks() {
+		scmArgItem.Binding = append(scmArgItem.Binding, fmt.Sprintf("%s.%s.%s", taskName, bindingScopeArgs, CloneTaskTemplateArgName))
+	}
+
 	spec.Arguments[0].Items = append(spec.Arguments[0].Items, scmArgItem)
 }
 
@@ -452,12 +601,12 @@ func (spec *PipelineTemplateSpec) applyConstValues() {
 func (spec *PipelineTemplateSpec) parseToJenkinsfilePipeline() (*jenkinsfile.Pipeline, error) {
 	jenkinsfileStages, err := spec.getJenkinsfileStages()
 	if err != nil {
-		fmt.Printf("parse task template script body error :%v\n", err)
+		goutils.Logger.Printf("parse task template script body error :%v\n", err)
 		return nil, err
 	}
 	jenkinsfilePost, err := spec.getJenkinsfilePost()
 	if err != nil {
-		fmt.Printf("parse task template script body in post error :%v\n", err)
+		goutils.Logger.Printf("parse task template script body in post error :%v\n", err)
 		return nil, err
 	}
 
@@ -475,44 +624,40 @@ func (spec *PipelineTemplateSpec) getJenkinsfileStages() ([]*jenkinsfile.Stage,
 
 	jenkinsStages := []*jenkinsfile.Stage{}
 	for _, stage := range spec.Stages {
-		if len(stage.Tasks) == 1 {
-			if stage.Tasks[0].meaningfull == false {
-				fmt.Printf("task %s is not meaningful, will skip to render it\n", stage.Tasks[0].Name)
+		taskStages := []*jenkinsfile.Stage{}
+		for _, task := range stage.Tasks {
+			if task.meaningfull == false {
+				goutils.Logger.Printf("task %s is not meaningful, will skip to render it\n", task.Name)
 				continue
 			}
 
-			jenkinsStage, err := stage.Tasks[0].toJenkinsfileStage()
+			taskStage, err := task.toJenkinsfileStage(stage.Agent)
 			if err != nil {
-				fmt.Printf("render task %s script body error:%#v", stage.Tasks[0].Name, err)
-				errs = append(errs, err)
+				goutils.Logger.Printf("render task %s script body error:%#v", task.Name, err)
+				errs = append(errs, common.WithTask(err, task.Name))
 				continue
 			}
-			jenkinsStages = append(jenkinsStages, jenkinsStage)
+			taskStages = append(taskStages, taskStage)
 		}
 
-		if len(stage.Tasks) > 1 {
-			jenkinsStage := &jenkinsfile.Stage{
-				Name:   stage.Name,
-				When:   stage.Conditions,
-				Stages: []*jenkinsfile.Stage{},
-			}
-
-			for _, parallelTask := range stage.Tasks {
-				if parallelTask.meaningfull == false {
-					fmt.Printf("task %s is not meaningful, will skip to render it\n", parallelTask.Name)
-					continue
-				}
-
-				pStage, err := parallelTask.toJenkinsfileStage()
-				if err != nil {
-					fmt.Printf("render task %s script body error:%#v", parallelTask.Name, err)
-					errs = append(errs, err)
-					continue
-				}
-				jenkinsStage.Stages = append(jenkinsStage.Stages, pStage)
-			}
-
-			jenkinsStages = append(jenkinsStages, jenkinsStage)
+		switch len(taskStages) {
+		case 0:
+			// all tasks are not meaningful, the stage is dropped
+			goutils.Logger.Printf("stage %s has no meaningful task, will skip to render it\n", stage.Name)
+		case 1:
+			// the only task is rendered as a plain stage, it runs only when conditions of stage are matched too
+			taskStage := taskStages[0]
+			taskStage.When = jenkinsfile.MergeWhen(stage.Conditions, taskStage.When)
+			taskStage.Environments = jenkinsfile.MergeEnvVars(stage.Environments, taskStage.Environments)
+			jenkinsStages = append(jenkinsStages, taskStage)
+		default:
+			// stage that has parallel stages could not have agent in declarative pipeline, agent of stage is inherited by its tasks instead
+			jenkinsStages = append(jenkinsStages, &jenkinsfile.Stage{
+				Name:         stage.Name,
+				When:         stage.Conditions,
+				Environments: stage.Environments,
+				Stages:       taskStages,
+			})
 		}
 	}
 
@@ -532,8 +677,8 @@ func (spec *PipelineTemplateSpec) getJenkinsfilePost() ([]*jenkinsfile.PostCondi
 		for _, task := range tasks {
 			taskScriptBody, err := task.taskTemplateSpec.Render(task.taskTemplateArgValues)
 			if err != nil {
-				fmt.Printf("render task %s script body error:%#v", task.Name, err)
-				errs = append(errs, err)
+				goutils.Logger.Printf("render task %s script body error:%#v", task.Name, err)
+				errs = append(errs, common.WithTask(err, task.Name))
 				continue
 			}
 			scripts += taskScriptBody
@@ -575,19 +720,6 @@ type taskValues struct {
 	argValues map[string]interface{}
 }
 
-var SystemArgKey = "_system_"
-
-// arguments divide to two fields
-// 1. system related: project name, namespace, cluster name. in key=_system_
-// 2. not implement yet. _jenkins_ : parellel build, history num.
-func getSystemArgumentsValues(argumentsValues map[string]interface{}) interface{} {
-	v, ok := argumentsValues[SystemArgKey]
-	if !ok {
-		return nil
-	}
-	return v
-}
-
 func (spec *PipelineTemplateSpec) assignValuesToEachTask(argumentsValues map[string]interface{}) error {
 
 	var allArgItems = []arguments.ArgItem{}
@@ -598,14 +730,13 @@ func (spec *PipelineTemplateSpec) assignValuesToEachTask(argumentsValues map[str
 	var tasksValuesMap = map[string]taskValues{}
 
 	for _, argItem := range allArgItems {
-		for _, binding := range argItem.Binding {
-			segments := strings.Split(binding, ".")
-
-			if len(segments) <= 1 {
-				return common.NewTemplateDefinitionError(fmt.Sprintf("Pipeline template error, %s's Binding format:%s error", argItem.Name, binding), nil)
+		for _, rawBinding := range argItem.Binding {
+			binding, err := parseBinding(argItem.Name, rawBinding)
+			if err != nil {
+				return err
 			}
 
-			var taskName = segments[0]
+			var taskName = binding.Task
 			if _, ok := tasksValuesMap[taskName]; !ok {
 				tasksValuesMap[taskName] = taskValues{
 					templateArgValues: map[string]interface{}{},
@@ -613,32 +744,33 @@ func (spec *PipelineTemplateSpec) assignValuesToEachTask(argumentsValues map[str
 				}
 			}
 
-			scope := segments[1]
-			switch scope {
-			case "args":
+			switch binding.Scope {
+			case bindingScopeArgs:
 				{
-					fieldName := segments[2]
 					//debug it
-					//fmt.Printf("task name :%s , fieldName:%s value:%v\n", taskName, fieldName, argumentsValues[argItem.Name])
-					tasksValuesMap[taskName].templateArgValues[fieldName] = argumentsValues[argItem.Name]
+					//fmt.Printf("task name :%s , fieldName:%s value:%v\n", taskName, binding.Field, argumentsValues[argItem.Name])
+					tasksValuesMap[taskName].templateArgValues[binding.Field] = argumentsValues[argItem.Name]
 				}
 			default:
 				{
-					fieldPath := strings.Join(segments[1:], ".")
-					tasksValuesMap[taskName].argValues[fieldPath] = argumentsValues[argItem.Name]
+					tasksValuesMap[taskName].argValues[binding.Field] = argumentsValues[argItem.Name]
 				}
 			}
 		}
 	}
 
-	systemValue := getSystemArgumentsValues(argumentsValues)
+	// system context has been parsed to values in graphRender, see SystemContext.Values
+	systemValue := argumentsValues[SystemArgKey]
+	errs := common.Errors{}
 	// assignValues
 	for _, stage := range spec.Stages {
 		for _, task := range stage.Tasks {
 			// fmt.Printf("%s templateArgValues is %#v\n", task.Name, tasksValuesMap[task.Name].templateArgValues)
 			task.assignTemplateArgValues(tasksValuesMap[task.Name].templateArgValues)
 			task.assignSystemArgValue(systemValue)
-			task.assignArgValues(tasksValuesMap[task.Name].argValues)
+			if err := task.assignArgValues(tasksValuesMap[task.Name].argValues); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 
@@ -647,10 +779,15 @@ func (spec *PipelineTemplateSpec) assignValuesToEachTask(argumentsValues map[str
 			// fmt.Printf("%s templateArgValues is %#v\n", task.Name, tasksValuesMap[task.Name].templateArgValues)
 			task.assignTemplateArgValues(tasksValuesMap[task.Name].templateArgValues)
 			task.assignSystemArgValue(systemValue)
-			task.assignArgValues(tasksValuesMap[task.Name].argValues)
+			if err := task.assignArgValues(tasksValuesMap[task.Name].argValues); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
@@ -666,6 +803,22 @@ func (spec *PipelineTemplateSpec) findTask(taskName string) *Task {
 	return nil
 }
 
+// taskPaths paths of tasks relative to spec keyed by task name, eg: stages[0].tasks[1], post.always[0]
+func (spec *PipelineTemplateSpec) taskPaths() map[string]string {
+	paths := map[string]string{}
+	for i, stage := range spec.Stages {
+		for j, task := range stage.Tasks {
+			paths[task.Name] = fmt.Sprintf("stages[%d].tasks[%d]", i, j)
+		}
+	}
+	for condition, tasks := range spec.Post {
+		for i, task := range tasks {
+			paths[task.Name] = common.JoinPath("post", common.PathKey(condition), fmt.Sprintf("[%d]", i))
+		}
+	}
+	return paths
+}
+
 func (spec *PipelineTemplateSpec) allTasks() []*Task {
 	tasks := []*Task{}
 
@@ -677,6 +830,26 @@ func (spec *PipelineTemplateSpec) allTasks() []*Task {
 	return tasks
 }
 
+// SkippedTasks names of tasks in stages that are not meaningful, they are skipped in the last rendering
+func (spec *PipelineTemplateSpec) SkippedTasks() []string {
+	names := []string{}
+	for _, task := range spec.allTasks() {
+		if !task.meaningfull {
+			names = append(names, task.Name)
+		}
+	}
+	return names
+}
+
+func (spec *PipelineTemplateSpec) allTasksWithPost() []*Task {
+	tasks := spec.allTasks()
+
+	for _, tasksInPost := range spec.Post {
+		tasks = append(tasks, tasksInPost...)
+	}
+	return tasks
+}
+
 func (spec *PipelineTemplateSpec) AllTaskTypes() []string {
 	var names = []string{}
 
@@ -689,27 +862,37 @@ func (spec *PipelineTemplateSpec) AllTaskTypes() []string {
 	return names
 }
 
+// lookupTaskTemplate find task template by task type like `build@^1.2`, fallback to template name `build`
+func lookupTaskTemplate(taskTemlateRefs map[string]TaskTemplateSpec, taskType string) (TaskTemplateSpec, bool) {
+	if taskTemplateSpec, ok := taskTemlateRefs[taskType]; ok {
+		return taskTemplateSpec, true
+	}
+	name, _ := SplitTaskType(taskType)
+	taskTemplateSpec, ok := taskTemlateRefs[name]
+	return taskTemplateSpec, ok
+}
+
 func (spec *PipelineTemplateSpec) appendTaskTemplateSpecRef(taskTemlateRefs map[string]TaskTemplateSpec) error {
 
 	errs := common.Errors{}
 	for _, stage := range spec.Stages {
 		for _, task := range stage.Tasks {
-			if _, ok := taskTemlateRefs[task.Type]; !ok {
+			taskTemplateSpec, ok := lookupTaskTemplate(taskTemlateRefs, task.Type)
+			if !ok {
 				errs = append(errs, common.NewValidateError(fmt.Sprintf("require definition of task template named:%s", task.Type), nil))
 				continue
 			}
-			taskTemplateSpec := taskTemlateRefs[task.Type]
 			task.taskTemplateSpec = &taskTemplateSpec
 		}
 	}
 
 	for _, tasks := range spec.Post {
 		for _, task := range tasks {
-			if _, ok := taskTemlateRefs[task.Type]; !ok {
+			taskTemplateSpec, ok := lookupTaskTemplate(taskTemlateRefs, task.Type)
+			if !ok {
 				errs = append(errs, common.NewValidateError(fmt.Sprintf("require definition of task template named:%s", task.Type), nil))
 				continue
 			}
-			taskTemplateSpec := taskTemlateRefs[task.Type]
 			task.taskTemplateSpec = &taskTemplateSpec
 		}
 	}
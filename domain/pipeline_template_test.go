@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/jenkinsfile"
+)
+
+func newStageTestTask(name string, agent interface{}, relation *common.Relation) *Task {
+	return &Task{
+		Name:             name,
+		Type:             "sh",
+		Agent:            agent,
+		Relation:         relation,
+		taskTemplateSpec: &TaskTemplateSpec{Body: "sh 'echo " + name + "'", Agent: map[string]interface{}{"label": "template"}},
+	}
+}
+
+// showWhen relation that shows task only when argument name is true
+func showWhen(name string) *common.Relation {
+	return &common.Relation{
+		common.RelationItem{Action: common.RelationActionSHOW, When: &common.RelationWhen{Name: name, Value: true}},
+	}
+}
+
+func TestGetJenkinsfileStages(t *testing.T) {
+	golang := map[string]interface{}{"label": "golang"}
+	java := map[string]interface{}{"label": "java"}
+	template := map[string]interface{}{"label": "template"}
+
+	type expectedStage struct {
+		name   string
+		agent  interface{}
+		when   *jenkinsfile.When
+		envs   []string
+		stages []expectedStage
+	}
+
+	cases := []struct {
+		name     string
+		stage    *Stage
+		values   map[string]interface{}
+		expected []expectedStage
+	}{
+		{
+			name: "stage is dropped when all tasks are hidden",
+			stage: &Stage{
+				Name:  "Build",
+				Tasks: []*Task{newStageTestTask("Build", nil, showWhen("doBuild")), newStageTestTask("Test", nil, showWhen("doBuild"))},
+			},
+			values:   map[string]interface{}{"doBuild": false},
+			expected: []expectedStage{},
+		},
+		{
+			name: "stage is collapsed when siblings are hidden",
+			stage: &Stage{
+				Name:         "Build",
+				Agent:        golang,
+				Conditions:   &jenkinsfile.When{jenkinsfile.WhenAll: []string{"a"}},
+				Environments: []jenkinsfile.EnvVar{{Name: "STAGE", Value: "s"}},
+				Tasks: []*Task{
+					func() *Task {
+						task := newStageTestTask("Build", nil, nil)
+						task.Conditions = &jenkinsfile.When{jenkinsfile.WhenAll: []string{"b"}}
+						task.Environments = []jenkinsfile.EnvVar{{Name: "TASK", Value: "t"}}
+						return task
+					}(),
+					newStageTestTask("Test", nil, showWhen("doTest")),
+				},
+			},
+			values: map[string]interface{}{"doTest": false},
+			expected: []expectedStage{
+				{name: "Build", agent: golang, when: &jenkinsfile.When{jenkinsfile.WhenAll: []string{"a", "b"}}, envs: []string{"STAGE", "TASK"}},
+			},
+		},
+		{
+			name: "tasks are wrapped in parallel stage",
+			stage: &Stage{
+				Name:         "Build",
+				Agent:        golang,
+				Conditions:   &jenkinsfile.When{jenkinsfile.WhenAll: []string{"a"}},
+				Environments: []jenkinsfile.EnvVar{{Name: "STAGE", Value: "s"}},
+				Tasks:        []*Task{newStageTestTask("Build", java, nil), newStageTestTask("Test", nil, showWhen("doTest"))},
+			},
+			values: map[string]interface{}{"doTest": true},
+			expected: []expectedStage{
+				{name: "Build", when: &jenkinsfile.When{jenkinsfile.WhenAll: []string{"a"}}, envs: []string{"STAGE"}, stages: []expectedStage{
+					{name: "Build", agent: java},
+					{name: "Test", agent: golang},
+				}},
+			},
+		},
+		{
+			name: "agent of task template is used when stage has no agent",
+			stage: &Stage{
+				Name:  "Build",
+				Tasks: []*Task{newStageTestTask("Build", nil, nil)},
+			},
+			expected: []expectedStage{
+				{name: "Build", agent: template},
+			},
+		},
+	}
+
+	var check func(t *testing.T, actual []*jenkinsfile.Stage, expected []expectedStage)
+	check = func(t *testing.T, actual []*jenkinsfile.Stage, expected []expectedStage) {
+		if len(actual) != len(expected) {
+			t.Fatalf("expected %d stages, but got %d", len(expected), len(actual))
+		}
+		for i, stage := range actual {
+			e := expected[i]
+			if stage.Name != e.name {
+				t.Errorf("expected stage %s, but got %s", e.name, stage.Name)
+			}
+			if !reflect.DeepEqual(stage.Agent, e.agent) {
+				t.Errorf("stage %s: expected agent %v, but got %v", stage.Name, e.agent, stage.Agent)
+			}
+			if !reflect.DeepEqual(stage.When, e.when) {
+				t.Errorf("stage %s: expected when %v, but got %v", stage.Name, e.when, stage.When)
+			}
+			envs := []string{}
+			for _, env := range stage.Environments {
+				envs = append(envs, env.Name)
+			}
+			if len(envs) != len(e.envs) || (len(envs) > 0 && !reflect.DeepEqual(envs, e.envs)) {
+				t.Errorf("stage %s: expected envs %v, but got %v", stage.Name, e.envs, envs)
+			}
+			if len(e.stages) == 0 && stage.Steps == nil {
+				t.Errorf("stage %s: steps should not be nil", stage.Name)
+			}
+			check(t, stage.Stages, e.stages)
+		}
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spec := &PipelineTemplateSpec{Stages: []*Stage{c.stage}}
+			spec.markMeaningfulTask(c.values)
+
+			stages, err := spec.getJenkinsfileStages()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			check(t, stages, c.expected)
+		})
+	}
+}
+
+// TestRenderStageWithHiddenSibling the collapsed stage used to be rendered as a parallel wrapper with nil steps, which panics
+func TestRenderStageWithHiddenSibling(t *testing.T) {
+	spec := &PipelineTemplateSpec{Stages: []*Stage{{
+		Name:  "Build",
+		Tasks: []*Task{newStageTestTask("Build", nil, nil), newStageTestTask("Test", nil, showWhen("doTest"))},
+	}}}
+	spec.markMeaningfulTask(map[string]interface{}{"doTest": false})
+
+	stages, err := spec.getJenkinsfileStages()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	render, err := (&jenkinsfile.Pipeline{Stages: stages}).Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(render, "parallel") || !strings.Contains(render, "sh 'echo Build'") || strings.Contains(render, "echo Test") {
+		t.Errorf("stage should be rendered as a plain stage, got:\n%s", render)
+	}
+}
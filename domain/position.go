@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+// Position line and column of a field in source file, both start from 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+type yamlPositionFrame struct {
+	indent int
+	path   string
+	// whether it is a sequence item, otherwise it is a mapping key
+	isItem bool
+	// count of sequence items under this key
+	items int
+}
+
+// indexYAMLPositions index positions of keys and sequence items of block style yaml by path,
+// eg: spec.stages[2].tasks[0].agent. contents in flow style or block scalars are not indexed.
+// firstLine is the line number of the first line of content in source file.
+func indexYAMLPositions(content string, firstLine int) map[string]Position {
+	positions := map[string]Position{}
+	stack := []*yamlPositionFrame{}
+	blockScalarIndent := -1
+
+	for i, line := range strings.Split(content, "\n") {
+		trimed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimed)
+		trimed = strings.TrimRight(trimed, " \t\r")
+		if trimed == "" || strings.HasPrefix(trimed, "#") {
+			continue
+		}
+		if blockScalarIndent >= 0 {
+			if indent > blockScalarIndent {
+				continue
+			}
+			blockScalarIndent = -1
+		}
+
+		for trimed != "" {
+			isItem := trimed == "-" || strings.HasPrefix(trimed, "- ")
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				// sequence could be at the same indent as its key
+				if isItem && !top.isItem && top.indent == indent {
+					break
+				}
+				if top.indent < indent {
+					break
+				}
+				stack = stack[:len(stack)-1]
+			}
+
+			parent := ""
+			var parentFrame *yamlPositionFrame
+			if len(stack) > 0 {
+				parentFrame = stack[len(stack)-1]
+				parent = parentFrame.path
+			}
+
+			if isItem {
+				index := 0
+				if parentFrame != nil {
+					index = parentFrame.items
+					parentFrame.items++
+				}
+				path := common.JoinPath(parent, fmt.Sprintf("[%d]", index))
+				positions[path] = Position{Line: firstLine + i, Column: indent + 1}
+				stack = append(stack, &yamlPositionFrame{indent: indent, path: path, isItem: true})
+
+				// the content after `- ` is a nested node
+				rest := strings.TrimLeft(strings.TrimPrefix(trimed, "-"), " ")
+				indent += len(trimed) - len(rest)
+				trimed = rest
+				continue
+			}
+
+			key, value, ok := splitYAMLKey(trimed)
+			if !ok {
+				break
+			}
+			path := common.JoinPath(parent, common.PathKey(key))
+			positions[path] = Position{Line: firstLine + i, Column: indent + 1}
+			stack = append(stack, &yamlPositionFrame{indent: indent, path: path})
+
+			if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+				blockScalarIndent = indent
+			}
+			break
+		}
+	}
+	return positions
+}
+
+// splitYAMLKey split `key: value` into key and value, quoted keys are unquoted
+func splitYAMLKey(line string) (key string, value string, ok bool) {
+	if strings.HasPrefix(line, `"`) || strings.HasPrefix(line, `'`) {
+		quote := line[:1]
+		end := strings.Index(line[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		key = line[1 : end+1]
+		rest := line[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+
+	if strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") {
+		return "", "", false
+	}
+	index := strings.Index(line, ": ")
+	if index < 0 {
+		if !strings.HasSuffix(line, ":") {
+			return "", "", false
+		}
+		return strings.TrimSpace(strings.TrimSuffix(line, ":")), "", true
+	}
+	return strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+2:]), true
+}
+
+// Position position of path in document, the nearest parent is used when path is not indexed
+func (doc *KuberneteDocument) Position(path string) (Position, bool) {
+	if doc.positions == nil {
+		return Position{}, false
+	}
+	if doc.Item > 0 {
+		path = common.JoinPath(fmt.Sprintf("items[%d]", doc.Item-1), path)
+	}
+
+	for path != "" {
+		if position, ok := doc.positions[path]; ok {
+			return position, true
+		}
+		path = parentPath(path)
+	}
+	return Position{}, false
+}
+
+// Locate fill line and column of errors by their path, see common.Locate
+func (doc *KuberneteDocument) Locate(err error) error {
+	if err == nil {
+		return nil
+	}
+	return common.Locate(err, func(path string) (int, int, bool) {
+		position, ok := doc.Position(path)
+		return position.Line, position.Column, ok
+	})
+}
+
+// parentPath eg: spec.stages[0].name -> spec.stages[0] -> spec.stages -> spec
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		if index := strings.LastIndex(path, "["); index >= 0 {
+			// quoted key may contain `[`
+			if quoted := strings.LastIndex(path, `["`); quoted >= 0 && strings.HasSuffix(path, `"]`) {
+				index = quoted
+			}
+			return path[:index]
+		}
+	}
+	if index := strings.LastIndex(path, "."); index >= 0 {
+		return path[:index]
+	}
+	return ""
+}
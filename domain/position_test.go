@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+func TestIndexYAMLPositions(t *testing.T) {
+	content := `metadata:
+  annotations:
+    "windcloud/version": v1.0.0
+spec:
+  # comment
+  body: |
+    stages:
+    - name: NotIndexed
+  stages:
+  - name: Build
+    tasks:
+    - name: Compile
+      agent: {label: golang}
+    -   name: Test
+  - name: Deploy
+  values: [a, b]
+`
+	positions := indexYAMLPositions(content, 3)
+	expected := map[string]Position{
+		"metadata": {Line: 3, Column: 1},
+		`metadata.annotations["windcloud/version"]`: {Line: 5, Column: 5},
+		"spec.body":                     {Line: 8, Column: 3},
+		"spec.stages":                   {Line: 11, Column: 3},
+		"spec.stages[0]":                {Line: 12, Column: 3},
+		"spec.stages[0].name":           {Line: 12, Column: 5},
+		"spec.stages[0].tasks[0].agent": {Line: 15, Column: 7},
+		"spec.stages[0].tasks[1].name":  {Line: 16, Column: 9},
+		"spec.stages[1].name":           {Line: 17, Column: 5},
+		"spec.values":                   {Line: 18, Column: 3},
+	}
+	for path, position := range expected {
+		if actual, ok := positions[path]; !ok || actual != position {
+			t.Errorf("%s: expected %v, but got %v", path, position, actual)
+		}
+	}
+	// contents of block scalars and flow style are not indexed
+	for _, path := range []string{"spec.body.stages", "spec.stages[2]", "spec.stages[0].tasks[0].agent.label", "spec.values[0]"} {
+		if _, ok := positions[path]; ok {
+			t.Errorf("%s should not be indexed", path)
+		}
+	}
+}
+
+func TestDocumentLocate(t *testing.T) {
+	docs, err := LoadDocumentsFromBytes("pipe.yaml", []byte(`kind: List
+items:
+- kind: PipelineTemplate
+  spec:
+    stages:
+    - name: Build
+      tasks:
+      - name: Compile
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = docs[0].Locate(common.Errors{
+		common.WithPath(common.NewValidateError("a", nil), "spec.stages[0].tasks[0].name"),
+		// the nearest parent is used
+		common.WithPath(common.NewValidateError("b", nil), "spec.stages[0].tasks[0].agent.label"),
+		// it is located at the item at least
+		common.WithPath(common.NewValidateError("c", nil), "status"),
+	})
+	expected := [][2]int{{8, 9}, {8, 7}, {3, 1}}
+	for i, item := range common.FlattenErrors(err) {
+		typed := item.(common.Error)
+		if typed.Line != expected[i][0] || typed.Column != expected[i][1] {
+			t.Errorf("%s: expected %v, but got %d:%d", typed.Path, expected[i], typed.Line, typed.Column)
+		}
+	}
+}
+
+func TestParentPath(t *testing.T) {
+	cases := map[string]string{
+		"spec.stages[0].name":            "spec.stages[0]",
+		"spec.stages[0]":                 "spec.stages",
+		"spec":                           "",
+		`metadata.annotations["a[0].b"]`: "metadata.annotations",
+		`metadata.annotations["a"].b`:    `metadata.annotations["a"]`,
+	}
+	for path, expected := range cases {
+		if actual := parentPath(path); actual != expected {
+			t.Errorf("%s: expected %s, but got %s", path, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/jenkinsfile"
+)
+
+// SCMInfo code repository that pipeline checks out, it is passed to clone task template as argument SCM.
+// task templates could render the checkout step by `checkout` template function, eg: {{checkout .SCM}}
+type SCMInfo struct {
+	Type           scmType
+	RepositoryPath string
+	CredentialsID  string
+	// Branch branch of GIT and HG, DefaultGitBranch and DefaultHGBranch are used when it is empty
+	Branch string
+
+	// Git options of GIT repository
+	Git *GitSCMOptions
+	// SVN options of SVN repository
+	SVN *SVNSCMOptions
+}
+
+type scmType string
+
+// SCMTypeEnum enum of SCMType
+var SCMTypeEnum = struct {
+	GIT scmType
+	SVN scmType
+	HG  scmType
+	// GENERIC checkout scm that is configured in jenkins job, RepositoryPath is not required
+	GENERIC scmType
+}{
+	GIT:     "GIT",
+	SVN:     "SVN",
+	HG:      "HG",
+	GENERIC: "GENERIC",
+}
+
+// SCMTypes all types of SCMInfo
+var SCMTypes = []scmType{SCMTypeEnum.GIT, SCMTypeEnum.SVN, SCMTypeEnum.HG, SCMTypeEnum.GENERIC}
+
+// default branches that are checked out when SCMInfo.Branch is empty
+const (
+	DefaultGitBranch = "master"
+	DefaultHGBranch  = "default"
+)
+
+// GitSCMOptions options of GIT repository
+type GitSCMOptions struct {
+	// Refspec refspec of origin, eg: +refs/heads/*:refs/remotes/origin/*
+	Refspec string `json:"refspec,omitempty"`
+	// Depth depth of shallow clone, 0 means full clone
+	Depth      int  `json:"depth,omitempty"`
+	Submodules bool `json:"submodules,omitempty"`
+	LFS        bool `json:"lfs,omitempty"`
+	// SparseCheckoutPaths only these paths are checked out when it is not empty
+	SparseCheckoutPaths []string `json:"sparseCheckoutPaths,omitempty"`
+	// Remotes remotes besides origin that RepositoryPath refers
+	Remotes []GitRemote `json:"remotes,omitempty"`
+}
+
+// GitRemote additional remote of GIT repository
+type GitRemote struct {
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	CredentialsID string `json:"credentialsID,omitempty"`
+	Refspec       string `json:"refspec,omitempty"`
+}
+
+// SVNSCMOptions options of SVN repository
+type SVNSCMOptions struct {
+	// Depth depth of checkout, one of SVNDepths, SVNDepthInfinity is used when it is empty
+	Depth string `json:"depth,omitempty"`
+	// IgnoreExternals externals are not checked out
+	IgnoreExternals bool `json:"ignoreExternals,omitempty"`
+	// Locations locations besides RepositoryPath that are checked out to sub directories of workspace
+	Locations []SVNLocation `json:"locations,omitempty"`
+}
+
+// SVNLocation additional location of SVN repository
+type SVNLocation struct {
+	URL           string `json:"url"`
+	CredentialsID string `json:"credentialsID,omitempty"`
+	// Local sub directory of workspace that location is checked out to
+	Local string `json:"local"`
+	// Depth depth of location, the depth of SVNSCMOptions is used when it is empty
+	Depth string `json:"depth,omitempty"`
+}
+
+// depths of SVN checkout
+const (
+	SVNDepthInfinity   = "infinity"
+	SVNDepthEmpty      = "empty"
+	SVNDepthFiles      = "files"
+	SVNDepthImmediates = "immediates"
+)
+
+// SVNDepths all depths of SVN checkout
+var SVNDepths = []string{SVNDepthInfinity, SVNDepthEmpty, SVNDepthFiles, SVNDepthImmediates}
+
+var gitRemoteNameRegx = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Validate validate scm, paths of errors are relative to scm
+func (scm *SCMInfo) Validate() error {
+	errs := common.Errors{}
+
+	if !isSCMType(scm.Type) {
+		types := []string{}
+		for _, t := range SCMTypes {
+			types = append(types, string(t))
+		}
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("scm.type %s is not support now, it should be one of %s", scm.Type, strings.Join(types, "|")), nil), "type"))
+	}
+	if scm.Type != SCMTypeEnum.GENERIC && strings.TrimSpace(scm.RepositoryPath) == "" {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("scm.repositoryPath should be required", nil), "repositoryPath"))
+	}
+
+	if scm.Git != nil {
+		if scm.Type != SCMTypeEnum.GIT {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("scm.git is only for %s scm", SCMTypeEnum.GIT), nil), "git"))
+		} else if err := scm.Git.validate(); err != nil {
+			errs = append(errs, common.WithPath(err, "git"))
+		}
+	}
+	if scm.SVN != nil {
+		if scm.Type != SCMTypeEnum.SVN {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("scm.svn is only for %s scm", SCMTypeEnum.SVN), nil), "svn"))
+		} else if err := scm.SVN.validate(); err != nil {
+			errs = append(errs, common.WithPath(err, "svn"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func isSCMType(t scmType) bool {
+	for _, scmType := range SCMTypes {
+		if scmType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (options *GitSCMOptions) validate() error {
+	errs := common.Errors{}
+
+	if options.Depth < 0 {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("scm.git.depth should not be negative", nil), "depth"))
+	}
+	for i, path := range options.SparseCheckoutPaths {
+		if strings.TrimSpace(path) == "" {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("sparse checkout path should not be empty", nil), fmt.Sprintf("sparseCheckoutPaths[%d]", i)))
+		}
+	}
+
+	names := map[string]struct{}{"origin": struct{}{}}
+	for i, remote := range options.Remotes {
+		path := fmt.Sprintf("remotes[%d]", i)
+		if !gitRemoteNameRegx.MatchString(remote.Name) {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("remote name `%s` is invalid", remote.Name), nil), path+".name"))
+		} else if _, ok := names[remote.Name]; ok {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("remote name %s should be unique, origin is the remote of repositoryPath", remote.Name), nil), path+".name"))
+		}
+		names[remote.Name] = struct{}{}
+
+		if strings.TrimSpace(remote.URL) == "" {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("url of remote %s should be required", remote.Name), nil), path+".url"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (options *SVNSCMOptions) validate() error {
+	errs := common.Errors{}
+
+	if options.Depth != "" && !isSVNDepth(options.Depth) {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("scm.svn.depth %s is not support, it should be one of %s", options.Depth, strings.Join(SVNDepths, "|")), nil), "depth"))
+	}
+
+	locals := map[string]struct{}{}
+	for i, location := range options.Locations {
+		path := fmt.Sprintf("locations[%d]", i)
+		if strings.TrimSpace(location.URL) == "" {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("url of location should be required", nil), path+".url"))
+		}
+
+		// workspace is taken by location of repositoryPath
+		local := strings.Trim(strings.TrimSpace(location.Local), "/")
+		if local == "" || local == "." {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("local of location should be a sub directory of workspace", nil), path+".local"))
+		} else if _, ok := locals[local]; ok {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("local %s of location should be unique", location.Local), nil), path+".local"))
+		}
+		locals[local] = struct{}{}
+
+		if location.Depth != "" && !isSVNDepth(location.Depth) {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("depth %s of location is not support, it should be one of %s", location.Depth, strings.Join(SVNDepths, "|")), nil), path+".depth"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func isSVNDepth(depth string) bool {
+	for _, d := range SVNDepths {
+		if d == depth {
+			return true
+		}
+	}
+	return false
+}
+
+// Checkout groovy step that checks out scm, eg: checkout([$class: 'GitSCM', ...]), checkout scm
+func (scm *SCMInfo) Checkout() (string, error) {
+	if err := scm.Validate(); err != nil {
+		return "", err
+	}
+
+	switch scm.Type {
+	case SCMTypeEnum.GIT:
+		return scm.gitCheckout(), nil
+	case SCMTypeEnum.SVN:
+		return scm.svnCheckout(), nil
+	case SCMTypeEnum.HG:
+		return scm.hgCheckout(), nil
+	default:
+		return "checkout scm", nil
+	}
+}
+
+func (scm *SCMInfo) gitCheckout() string {
+	options := scm.Git
+	if options == nil {
+		options = &GitSCMOptions{}
+	}
+
+	branch := scm.Branch
+	if branch == "" {
+		branch = DefaultGitBranch
+	}
+
+	remotes := []string{gitRemoteConfig(GitRemote{Name: "origin", URL: scm.RepositoryPath, CredentialsID: scm.CredentialsID, Refspec: options.Refspec})}
+	for _, remote := range options.Remotes {
+		remotes = append(remotes, gitRemoteConfig(remote))
+	}
+
+	extensions := []string{}
+	if options.Depth > 0 {
+		extensions = append(extensions, fmt.Sprintf("[$class: 'CloneOption', shallow: true, depth: %d, noTags: false, honorRefspec: true]", options.Depth))
+	}
+	if options.Submodules {
+		extensions = append(extensions, "[$class: 'SubmoduleOption', recursiveSubmodules: true, parentCredentials: true, shallow: "+fmt.Sprint(options.Depth > 0)+"]")
+	}
+	if options.LFS {
+		extensions = append(extensions, "[$class: 'GitLFSPull']")
+	}
+	if len(options.SparseCheckoutPaths) > 0 {
+		paths := []string{}
+		for _, path := range options.SparseCheckoutPaths {
+			paths = append(paths, fmt.Sprintf("[path: %s]", jenkinsfile.SingleQuoted(path)))
+		}
+		extensions = append(extensions, fmt.Sprintf("[$class: 'SparseCheckoutPaths', sparseCheckoutPaths: [%s]]", strings.Join(paths, ", ")))
+	}
+
+	return fmt.Sprintf("checkout([$class: 'GitSCM', branches: [[name: %s]], userRemoteConfigs: [%s], extensions: [%s]])",
+		jenkinsfile.SingleQuoted(branch), strings.Join(remotes, ", "), strings.Join(extensions, ", "))
+}
+
+func gitRemoteConfig(remote GitRemote) string {
+	config := []string{"name: " + jenkinsfile.SingleQuoted(remote.Name), "url: " + jenkinsfile.SingleQuoted(remote.URL)}
+	if remote.CredentialsID != "" {
+		config = append(config, "credentialsId: "+jenkinsfile.SingleQuoted(remote.CredentialsID))
+	}
+	if remote.Refspec != "" {
+		config = append(config, "refspec: "+jenkinsfile.SingleQuoted(remote.Refspec))
+	}
+	return "[" + strings.Join(config, ", ") + "]"
+}
+
+func (scm *SCMInfo) svnCheckout() string {
+	options := scm.SVN
+	if options == nil {
+		options = &SVNSCMOptions{}
+	}
+
+	depth := options.Depth
+	if depth == "" {
+		depth = SVNDepthInfinity
+	}
+
+	locations := []string{svnLocation(SVNLocation{URL: scm.RepositoryPath, CredentialsID: scm.CredentialsID, Local: ".", Depth: depth}, options.IgnoreExternals)}
+	for _, location := range options.Locations {
+		if location.Depth == "" {
+			location.Depth = depth
+		}
+		locations = append(locations, svnLocation(location, options.IgnoreExternals))
+	}
+
+	return fmt.Sprintf("checkout([$class: 'SubversionSCM', locations: [%s], workspaceUpdater: [$class: 'UpdateUpdater']])", strings.Join(locations, ", "))
+}
+
+func svnLocation(location SVNLocation, ignoreExternals bool) string {
+	config := []string{"remote: " + jenkinsfile.SingleQuoted(location.URL)}
+	if location.CredentialsID != "" {
+		config = append(config, "credentialsId: "+jenkinsfile.SingleQuoted(location.CredentialsID))
+	}
+	config = append(config,
+		"local: "+jenkinsfile.SingleQuoted(location.Local),
+		"depthOption: "+jenkinsfile.SingleQuoted(location.Depth),
+		fmt.Sprintf("ignoreExternalsOption: %t", ignoreExternals),
+	)
+	return "[" + strings.Join(config, ", ") + "]"
+}
+
+func (scm *SCMInfo) hgCheckout() string {
+	branch := scm.Branch
+	if branch == "" {
+		branch = DefaultHGBranch
+	}
+
+	config := []string{"$class: 'MercurialSCM'", "source: " + jenkinsfile.SingleQuoted(scm.RepositoryPath), "revision: " + jenkinsfile.SingleQuoted(branch), "revisionType: 'BRANCH'", "clean: true"}
+	if scm.CredentialsID != "" {
+		config = append(config, "credentialsId: "+jenkinsfile.SingleQuoted(scm.CredentialsID))
+	}
+	return "checkout([" + strings.Join(config, ", ") + "])"
+}
+
+// checkout template function of task templates, scm is the SCM argument of clone task template
+func checkout(scm interface{}) (string, error) {
+	switch v := scm.(type) {
+	case *SCMInfo:
+		if v == nil {
+			return "", fmt.Errorf("scm is required by checkout")
+		}
+		return v.Checkout()
+	case SCMInfo:
+		return v.Checkout()
+	case map[string]interface{}:
+		info := &SCMInfo{}
+		if err := mapstructure.Decode(v, info); err != nil {
+			return "", err
+		}
+		return info.Checkout()
+	case nil:
+		return "", fmt.Errorf("scm is required by checkout")
+	}
+	return "", fmt.Errorf("checkout requires scm, but got %T", scm)
+}
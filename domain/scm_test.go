@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSCMCheckout(t *testing.T) {
+	cases := []struct {
+		name     string
+		scm      *SCMInfo
+		expected string
+	}{
+		{
+			name:     "git with default branch",
+			scm:      &SCMInfo{Type: SCMTypeEnum.GIT, RepositoryPath: "http://git/app.git", CredentialsID: "git-token"},
+			expected: `checkout([$class: 'GitSCM', branches: [[name: 'master']], userRemoteConfigs: [[name: 'origin', url: 'http://git/app.git', credentialsId: 'git-token']], extensions: []])`,
+		},
+		{
+			name: "git with options",
+			scm: &SCMInfo{
+				Type:           SCMTypeEnum.GIT,
+				RepositoryPath: "http://git/app.git",
+				Branch:         "release",
+				Git: &GitSCMOptions{
+					Refspec:             "+refs/heads/*:refs/remotes/origin/*",
+					Depth:               1,
+					Submodules:          true,
+					LFS:                 true,
+					SparseCheckoutPaths: []string{"src"},
+					Remotes:             []GitRemote{{Name: "upstream", URL: "http://git/upstream.git"}},
+				},
+			},
+			expected: `checkout([$class: 'GitSCM', branches: [[name: 'release']], ` +
+				`userRemoteConfigs: [[name: 'origin', url: 'http://git/app.git', refspec: '+refs/heads/*:refs/remotes/origin/*'], [name: 'upstream', url: 'http://git/upstream.git']], ` +
+				`extensions: [[$class: 'CloneOption', shallow: true, depth: 1, noTags: false, honorRefspec: true], ` +
+				`[$class: 'SubmoduleOption', recursiveSubmodules: true, parentCredentials: true, shallow: true], ` +
+				`[$class: 'GitLFSPull'], [$class: 'SparseCheckoutPaths', sparseCheckoutPaths: [[path: 'src']]]]])`,
+		},
+		{
+			name:     "git escapes quotes and backslashes",
+			scm:      &SCMInfo{Type: SCMTypeEnum.GIT, RepositoryPath: `http://git/it's\app.git`, Branch: "${env.BRANCH}'"},
+			expected: `checkout([$class: 'GitSCM', branches: [[name: '${env.BRANCH}\'']], userRemoteConfigs: [[name: 'origin', url: 'http://git/it\'s\\app.git']], extensions: []])`,
+		},
+		{
+			name: "svn with locations",
+			scm: &SCMInfo{
+				Type:           SCMTypeEnum.SVN,
+				RepositoryPath: "http://svn/app/trunk",
+				CredentialsID:  "svn-token",
+				SVN: &SVNSCMOptions{
+					Depth:           SVNDepthFiles,
+					IgnoreExternals: true,
+					Locations:       []SVNLocation{{URL: "http://svn/lib/trunk", Local: "lib"}},
+				},
+			},
+			expected: `checkout([$class: 'SubversionSCM', locations: [` +
+				`[remote: 'http://svn/app/trunk', credentialsId: 'svn-token', local: '.', depthOption: 'files', ignoreExternalsOption: true], ` +
+				`[remote: 'http://svn/lib/trunk', local: 'lib', depthOption: 'files', ignoreExternalsOption: true]], ` +
+				`workspaceUpdater: [$class: 'UpdateUpdater']])`,
+		},
+		{
+			name:     "svn escapes quotes",
+			scm:      &SCMInfo{Type: SCMTypeEnum.SVN, RepositoryPath: `http://svn/it's`, SVN: &SVNSCMOptions{Locations: []SVNLocation{{URL: "http://svn/lib", Local: `l'ib`}}}},
+			expected: `checkout([$class: 'SubversionSCM', locations: [[remote: 'http://svn/it\'s', local: '.', depthOption: 'infinity', ignoreExternalsOption: false], [remote: 'http://svn/lib', local: 'l\'ib', depthOption: 'infinity', ignoreExternalsOption: false]], workspaceUpdater: [$class: 'UpdateUpdater']])`,
+		},
+		{
+			name:     "hg with default branch",
+			scm:      &SCMInfo{Type: SCMTypeEnum.HG, RepositoryPath: "http://hg/app", CredentialsID: "hg-token"},
+			expected: `checkout([$class: 'MercurialSCM', source: 'http://hg/app', revision: 'default', revisionType: 'BRANCH', clean: true, credentialsId: 'hg-token'])`,
+		},
+		{
+			name:     "hg escapes quotes and backslashes",
+			scm:      &SCMInfo{Type: SCMTypeEnum.HG, RepositoryPath: `http://hg/it's\app`, Branch: "stable"},
+			expected: `checkout([$class: 'MercurialSCM', source: 'http://hg/it\'s\\app', revision: 'stable', revisionType: 'BRANCH', clean: true])`,
+		},
+		{
+			name:     "generic",
+			scm:      &SCMInfo{Type: SCMTypeEnum.GENERIC},
+			expected: `checkout scm`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := c.scm.Checkout()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSCMCheckoutInvalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		scm     *SCMInfo
+		message string
+	}{
+		{"unknown type", &SCMInfo{Type: "CVS", RepositoryPath: "http://cvs/app"}, "scm.type CVS is not support now"},
+		{"repository path is required", &SCMInfo{Type: SCMTypeEnum.HG}, "scm.repositoryPath should be required"},
+		{"git options of svn", &SCMInfo{Type: SCMTypeEnum.SVN, RepositoryPath: "http://svn/app", Git: &GitSCMOptions{}}, "scm.git is only for GIT scm"},
+		{"duplicate remote", &SCMInfo{Type: SCMTypeEnum.GIT, RepositoryPath: "http://git/app", Git: &GitSCMOptions{Remotes: []GitRemote{{Name: "origin", URL: "http://git/fork"}}}}, "remote name origin should be unique"},
+		{"svn location in workspace", &SCMInfo{Type: SCMTypeEnum.SVN, RepositoryPath: "http://svn/app", SVN: &SVNSCMOptions{Locations: []SVNLocation{{URL: "http://svn/lib", Local: "./"}}}}, "local of location should be a sub directory of workspace"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.scm.Checkout()
+			if err == nil || !strings.Contains(err.Error(), c.message) {
+				t.Errorf("expected error contains %q, but got %v", c.message, err)
+			}
+		})
+	}
+}
+
+func TestRepositoryPathOfHG(t *testing.T) {
+	for _, v := range repoVars {
+		if v.Name == "REPOSITORY_PATH" && !v.AvailableForSCM(&SCMInfo{Type: SCMTypeEnum.HG}) {
+			t.Errorf("REPOSITORY_PATH should be available for HG")
+		}
+	}
+}
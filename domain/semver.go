@@ -0,0 +1,293 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version semantic version of template, see https://semver.org
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+var versionRegx = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// ParseVersion parse version like v1.2.3, v1.2.3-beta.1+build.2, prefix `v` is optional
+func ParseVersion(version string) (Version, error) {
+	matches := versionRegx.FindStringSubmatch(strings.TrimSpace(version))
+	if matches == nil {
+		return Version{}, fmt.Errorf("version %s is not a valid semantic version", version)
+	}
+
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	patch, _ := strconv.Atoi(matches[3])
+	return Version{
+		Major:      major,
+		Minor:      minor,
+		Patch:      patch,
+		Prerelease: matches[4],
+		Build:      matches[5],
+	}, nil
+}
+
+func (v Version) String() string {
+	res := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		res += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		res += "+" + v.Build
+	}
+	return res
+}
+
+// Compare return -1, 0, 1 when v is less than, equal to, greater than other, build metadata is ignored
+func (v Version) Compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	// version without prerelease has higher precedence
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		ai, aErr := strconv.Atoi(as[i])
+		bi, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if ai != bi {
+				if ai < bi {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// IsBreakingUpgradeFrom whether upgrade from old to v is allowed to contain breaking changes, that is v is out of ^old.
+// major version should be bumped, minor version for 0.x and patch version for 0.0.x
+func (v Version) IsBreakingUpgradeFrom(old Version) bool {
+	if v.Major != old.Major {
+		return v.Major > old.Major
+	}
+	if v.Major != 0 {
+		return false
+	}
+	if v.Minor != old.Minor {
+		return v.Minor > old.Minor
+	}
+	return v.Minor == 0 && v.Patch > old.Patch
+}
+
+// caretUpper the lowest version that is out of ^v
+func (v Version) caretUpper() Version {
+	switch {
+	case v.Major != 0:
+		return Version{Major: v.Major + 1}
+	case v.Minor != 0:
+		return Version{Minor: v.Minor + 1}
+	}
+	return Version{Patch: v.Patch + 1}
+}
+
+// VersionConstraint constraint of version, eg: ^1.2, ~1.2.3, >=1.0.0 <2.0.0, 1.x, v1.2.3 || ^2
+type VersionConstraint struct {
+	source string
+	// any of the groups matches, all of the comparators in a group should match
+	groups [][]versionComparator
+}
+
+type versionComparator struct {
+	operator string
+	version  Version
+}
+
+// ParseVersionConstraint parse constraint, empty or `*` matches any version, but groups split by `||` should not be empty
+func ParseVersionConstraint(constraint string) (*VersionConstraint, error) {
+	result := &VersionConstraint{source: constraint, groups: [][]versionComparator{}}
+	if strings.TrimSpace(constraint) == "" {
+		result.groups = append(result.groups, []versionComparator{})
+		return result, nil
+	}
+
+	for _, group := range strings.Split(constraint, "||") {
+		items := strings.Fields(group)
+		if len(items) == 0 {
+			return nil, fmt.Errorf("version constraint `%s` is invalid: empty group of `||`", constraint)
+		}
+		comparators := []versionComparator{}
+		for _, item := range items {
+			parsed, err := parseVersionComparator(item)
+			if err != nil {
+				return nil, fmt.Errorf("version constraint `%s` is invalid: %s", constraint, err.Error())
+			}
+			comparators = append(comparators, parsed...)
+		}
+		result.groups = append(result.groups, comparators)
+	}
+	return result, nil
+}
+
+func parseVersionComparator(item string) ([]versionComparator, error) {
+	operator := ""
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(item, op) {
+			operator = op
+			item = strings.TrimPrefix(item, op)
+			break
+		}
+	}
+
+	if item == "*" || item == "x" || item == "X" {
+		return []versionComparator{}, nil
+	}
+
+	if version, err := ParseVersion(item); err == nil {
+		switch operator {
+		case "", "=":
+			return []versionComparator{{"=", version}}, nil
+		case "^":
+			return []versionComparator{{">=", version}, {"<", version.caretUpper()}}, nil
+		case "~":
+			return []versionComparator{{">=", version}, {"<", Version{Major: version.Major, Minor: version.Minor + 1}}}, nil
+		}
+		return []versionComparator{{operator, version}}, nil
+	}
+
+	// partial version: 1, 1.2, 1.x, 1.2.x
+	segments := strings.Split(strings.TrimPrefix(item, "v"), ".")
+	if len(segments) > 3 {
+		return nil, fmt.Errorf("%s is not a valid version", item)
+	}
+	numbers := []int{}
+	wildcard := false
+	for _, segment := range segments {
+		if segment == "x" || segment == "X" || segment == "*" {
+			wildcard = true
+			continue
+		}
+		n, err := strconv.Atoi(segment)
+		if err != nil || wildcard {
+			return nil, fmt.Errorf("%s is not a valid version", item)
+		}
+		numbers = append(numbers, n)
+	}
+	if len(numbers) == 0 || len(numbers) > 2 {
+		return nil, fmt.Errorf("%s is not a valid version", item)
+	}
+
+	// partial version is a range
+	lower := Version{Major: numbers[0]}
+	upper := Version{Major: numbers[0] + 1}
+	if len(numbers) == 2 {
+		lower.Minor = numbers[1]
+		upper = Version{Major: numbers[0], Minor: numbers[1] + 1}
+		if operator == "^" && numbers[0] != 0 {
+			upper = Version{Major: numbers[0] + 1}
+		}
+	}
+	switch operator {
+	case "", "=", "^", "~":
+		return []versionComparator{{">=", lower}, {"<", upper}}, nil
+	case ">":
+		return []versionComparator{{">=", upper}}, nil
+	case ">=":
+		return []versionComparator{{">=", lower}}, nil
+	case "<":
+		return []versionComparator{{"<", lower}}, nil
+	case "<=":
+		return []versionComparator{{"<", upper}}, nil
+	}
+	return nil, fmt.Errorf("%s is not a valid version", item)
+}
+
+// Check whether version matches constraint
+func (constraint *VersionConstraint) Check(version Version) bool {
+	for _, group := range constraint.groups {
+		matched := true
+		for _, comparator := range group {
+			if !comparator.check(version) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (constraint *VersionConstraint) String() string {
+	return constraint.source
+}
+
+func (comparator versionComparator) check(version Version) bool {
+	c := version.Compare(comparator.version)
+	switch comparator.operator {
+	case "=":
+		return c == 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// SplitTaskType split task type like `build@^1.2` to template name and version constraint
+func SplitTaskType(taskType string) (name string, constraint string) {
+	index := strings.Index(taskType, "@")
+	if index < 0 {
+		return taskType, ""
+	}
+	return taskType[:index], taskType[index+1:]
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected Version
+		invalid  bool
+	}{
+		{version: "v1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}},
+		{version: "1.2.3-beta.1+build.2", expected: Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "beta.1", Build: "build.2"}},
+		{version: "v1.2", invalid: true},
+		{version: "v01.2.3", invalid: true},
+		{version: "latest", invalid: true},
+	}
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			version, err := ParseVersion(c.version)
+			if c.invalid {
+				if err == nil {
+					t.Errorf("expected error, but got %v", version)
+				}
+				return
+			}
+			if err != nil || version != c.expected {
+				t.Errorf("expected %v, but got %v, error: %v", c.expected, version, err)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// versions in ascending order
+	versions := []string{"v0.9.9", "v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-alpha.beta", "v1.0.0-beta.2", "v1.0.0-beta.11", "v1.0.0", "v1.0.1", "v1.10.0", "v2.0.0"}
+	for i := 1; i < len(versions); i++ {
+		lower, _ := ParseVersion(versions[i-1])
+		higher, _ := ParseVersion(versions[i])
+		if lower.Compare(higher) != -1 || higher.Compare(lower) != 1 {
+			t.Errorf("%s should be lower than %s", versions[i-1], versions[i])
+		}
+	}
+
+	a, _ := ParseVersion("v1.0.0+build.1")
+	b, _ := ParseVersion("v1.0.0+build.2")
+	if a.Compare(b) != 0 {
+		t.Errorf("build metadata should be ignored")
+	}
+}
+
+func TestVersionConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		matched    []string
+		unmatched  []string
+	}{
+		{"", []string{"v0.0.1", "v3.0.0"}, nil},
+		{"*", []string{"v0.0.1", "v3.0.0"}, nil},
+		{"v1.2.3", []string{"v1.2.3"}, []string{"v1.2.4"}},
+		{"^1.2", []string{"v1.2.0", "v1.9.0"}, []string{"v1.1.9", "v2.0.0"}},
+		{"^1.2.3", []string{"v1.2.3", "v1.3.0"}, []string{"v1.2.2", "v2.0.0"}},
+		{"^0.2.3", []string{"v0.2.3", "v0.2.9"}, []string{"v0.3.0"}},
+		{"^0.0.3", []string{"v0.0.3"}, []string{"v0.0.4", "v0.1.0"}},
+		{"^0.0", []string{"v0.0.9"}, []string{"v0.1.0"}},
+		{"~1.2.3", []string{"v1.2.9"}, []string{"v1.3.0"}},
+		{"1.x", []string{"v1.0.0", "v1.9.9"}, []string{"v2.0.0"}},
+		{"1.2.x", []string{"v1.2.9"}, []string{"v1.3.0"}},
+		{">=1.0.0 <2.0.0", []string{"v1.0.0", "v1.9.9"}, []string{"v0.9.9", "v2.0.0"}},
+		{">1", []string{"v2.0.0"}, []string{"v1.9.9"}},
+		{"<=1.2", []string{"v1.2.9"}, []string{"v1.3.0"}},
+		{"^1.2 || ^3", []string{"v1.2.0", "v3.1.0"}, []string{"v2.0.0"}},
+	}
+	for _, c := range cases {
+		t.Run(c.constraint, func(t *testing.T) {
+			constraint, err := ParseVersionConstraint(c.constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, v := range c.matched {
+				version, _ := ParseVersion(v)
+				if !constraint.Check(version) {
+					t.Errorf("%s should match %s", v, c.constraint)
+				}
+			}
+			for _, v := range c.unmatched {
+				version, _ := ParseVersion(v)
+				if constraint.Check(version) {
+					t.Errorf("%s should not match %s", v, c.constraint)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionConstraintInvalid(t *testing.T) {
+	cases := map[string]string{
+		"^1.2 ||":    "empty group",
+		"|| ^1.2":    "empty group",
+		"^1 || || 2": "empty group",
+		"1.2.3.4":    "is not a valid version",
+		"x.1":        "is not a valid version",
+		"abc":        "is not a valid version",
+	}
+	for constraint, message := range cases {
+		t.Run(constraint, func(t *testing.T) {
+			_, err := ParseVersionConstraint(constraint)
+			if err == nil || !strings.Contains(err.Error(), message) {
+				t.Errorf("expected error contains %q, but got %v", message, err)
+			}
+		})
+	}
+}
+
+func TestSplitTaskType(t *testing.T) {
+	for taskType, expected := range map[string][2]string{
+		"build":      {"build", ""},
+		"build@^1.2": {"build", "^1.2"},
+	} {
+		if name, constraint := SplitTaskType(taskType); name != expected[0] || constraint != expected[1] {
+			t.Errorf("%s: expected %v, but got %s %s", taskType, expected, name, constraint)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/jenkinsfile"
+)
+
+// SystemArgKey key of system context in arguments values, eg: _system_.namespace
+var SystemArgKey = "_system_"
+
+// SystemContext context of platform that pipeline runs in, it is passed in arguments values by key SystemArgKey.
+// it is validated when render, task templates and relations could refer it by paths, eg: {{._system_.namespace}}, see GetSystemArgs
+type SystemContext struct {
+	Project      string `json:"project,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Cluster      string `json:"cluster,omitempty"`
+	PipelineName string `json:"pipelineName,omitempty"`
+	// Trigger source that triggers the build, one of SystemTriggers
+	Trigger string           `json:"trigger,omitempty"`
+	Jenkins *JenkinsSettings `json:"jenkins,omitempty"`
+}
+
+// JenkinsSettings settings of jenkins job, they are rendered as options of pipeline
+type JenkinsSettings struct {
+	// HistoryCount number of builds that are kept, 0 means jenkinsfile.DefaultHistoryCount
+	HistoryCount int `json:"historyCount,omitempty"`
+	// HistoryDays days that builds are kept, 0 means builds are not discarded by days
+	HistoryDays int `json:"historyDays,omitempty"`
+	// Concurrent builds could run concurrently
+	Concurrent bool `json:"concurrent,omitempty"`
+}
+
+// trigger sources of SystemContext
+const (
+	SystemTriggerManual = "manual"
+	SystemTriggerSCM    = "scm"
+	SystemTriggerImage  = "image"
+	SystemTriggerCron   = "cron"
+	SystemTriggerAPI    = "api"
+)
+
+// SystemTriggers all trigger sources of SystemContext
+var SystemTriggers = []string{SystemTriggerManual, SystemTriggerSCM, SystemTriggerImage, SystemTriggerCron, SystemTriggerAPI}
+
+// SystemArg describes a field of system context, Name is the path that task templates and relations refer
+type SystemArg struct {
+	Name        string                `json:"name"`
+	Type        string                `json:"type"`
+	Description common.MulitLangValue `json:"description"`
+}
+
+// LocalizedDescription description of locale, see common.MulitLangValue.Localize
+func (arg SystemArg) LocalizedDescription(locale string) string {
+	return arg.Description.Localize(locale)
+}
+
+var systemArgs = []SystemArg{
+	SystemArg{
+		Name: "_system_.project",
+		Type: "string",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "流水线所属项目的名称",
+			common.LocaleEN:   "name of project that pipeline belongs to",
+		},
+	},
+	SystemArg{
+		Name: "_system_.namespace",
+		Type: "string",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "流水线所在的命名空间",
+			common.LocaleEN:   "namespace of pipeline",
+		},
+	},
+	SystemArg{
+		Name: "_system_.cluster",
+		Type: "string",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "流水线所在集群的名称",
+			common.LocaleEN:   "name of cluster that pipeline runs in",
+		},
+	},
+	SystemArg{
+		Name: "_system_.pipelineName",
+		Type: "string",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "流水线的名称",
+			common.LocaleEN:   "name of pipeline",
+		},
+	},
+	SystemArg{
+		Name: "_system_.trigger",
+		Type: "string",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "触发构建的来源, 可选值: " + strings.Join(SystemTriggers, ", "),
+			common.LocaleEN:   "source that triggers the build, one of " + strings.Join(SystemTriggers, ", "),
+		},
+	},
+	SystemArg{
+		Name: "_system_.jenkins.historyCount",
+		Type: "integer",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: fmt.Sprintf("保留的构建记录数, 默认为 %d", jenkinsfile.DefaultHistoryCount),
+			common.LocaleEN:   fmt.Sprintf("number of builds that are kept, %d by default", jenkinsfile.DefaultHistoryCount),
+		},
+	},
+	SystemArg{
+		Name: "_system_.jenkins.historyDays",
+		Type: "integer",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "构建记录保留的天数, 默认不按天数清理",
+			common.LocaleEN:   "days that builds are kept, builds are not discarded by days by default",
+		},
+	},
+	SystemArg{
+		Name: "_system_.jenkins.concurrent",
+		Type: "boolean",
+		Description: common.MulitLangValue{
+			common.LocaleZHCN: "是否允许并发构建, 默认不允许",
+			common.LocaleEN:   "whether builds could run concurrently, they are disabled by default",
+		},
+	},
+}
+
+// GetSystemArgs get fields of system context that task templates and relations could refer
+func GetSystemArgs() []SystemArg {
+	return append([]SystemArg{}, systemArgs...)
+}
+
+// IsSystemArgRef whether ref refers to system context or a field of it, eg: _system_.jenkins, _system_.namespace
+func IsSystemArgRef(ref string) bool {
+	if ref == SystemArgKey {
+		return true
+	}
+	for _, arg := range systemArgs {
+		if arg.Name == ref || strings.HasPrefix(arg.Name, ref+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnknownSystemArgRef(ref string) bool {
+	return (ref == SystemArgKey || strings.HasPrefix(ref, SystemArgKey+".")) && !IsSystemArgRef(ref)
+}
+
+// fields that system context could be decoded from
+var (
+	systemContextFields   = []string{"project", "namespace", "cluster", "pipelineName", "trigger", "jenkins"}
+	jenkinsSettingsFields = []string{"historyCount", "historyDays", "concurrent"}
+)
+
+// ParseSystemContext parse system context from value of SystemArgKey in arguments values, it returns nil when value is nil.
+// unknown fields are reported, so typos do not pass silently
+func ParseSystemContext(value interface{}) (*SystemContext, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *SystemContext:
+		return v, v.Validate()
+	case SystemContext:
+		return &v, v.Validate()
+	case map[string]interface{}:
+		errs := common.Errors{}
+		errs = append(errs, unknownFieldErrors(v, systemContextFields, "")...)
+		if jenkins, ok := v["jenkins"].(map[string]interface{}); ok {
+			errs = append(errs, unknownFieldErrors(jenkins, jenkinsSettingsFields, "jenkins")...)
+		}
+
+		ctx := &SystemContext{}
+		if err := mapstructure.Decode(v, ctx); err != nil {
+			return nil, common.NewValidateError(fmt.Sprintf("%s is invalid: %s", SystemArgKey, err.Error()), nil)
+		}
+		if err := ctx.Validate(); err != nil {
+			errs = append(errs, err.(common.Errors)...)
+		}
+		if len(errs) > 0 {
+			return ctx, errs
+		}
+		return ctx, nil
+	}
+	return nil, common.NewValidateError(fmt.Sprintf("%s should be an object, but got %T", SystemArgKey, value), nil)
+}
+
+func unknownFieldErrors(values map[string]interface{}, fields []string, path string) common.Errors {
+	known := map[string]struct{}{}
+	for _, field := range fields {
+		known[field] = struct{}{}
+	}
+
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := common.Errors{}
+	for _, key := range keys {
+		if _, ok := known[key]; !ok {
+			err := common.NewValidateError(fmt.Sprintf("%s field %s is not support, it should be one of %s", SystemArgKey, key, strings.Join(fields, "|")), nil)
+			errs = append(errs, common.WithPath(err, common.JoinPath(path, common.PathKey(key))))
+		}
+	}
+	return errs
+}
+
+var systemNameRegx = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Validate names should be legal kubernetes names, trigger should be one of SystemTriggers
+func (ctx *SystemContext) Validate() error {
+	errs := common.Errors{}
+
+	names := []struct {
+		field string
+		value string
+	}{
+		{"project", ctx.Project},
+		{"namespace", ctx.Namespace},
+		{"cluster", ctx.Cluster},
+	}
+	for _, name := range names {
+		if name.value != "" && (len(name.value) > 63 || !systemNameRegx.MatchString(name.value)) {
+			err := common.NewValidateError(fmt.Sprintf("%s.%s `%s` should be a legal kubernetes name", SystemArgKey, name.field, name.value), nil)
+			errs = append(errs, common.WithPath(err, name.field))
+		}
+	}
+
+	if ctx.Trigger != "" && !isSystemTrigger(ctx.Trigger) {
+		err := common.NewValidateError(fmt.Sprintf("%s.trigger %s is not support, it should be one of %s", SystemArgKey, ctx.Trigger, strings.Join(SystemTriggers, "|")), nil)
+		errs = append(errs, common.WithPath(err, "trigger"))
+	}
+
+	if ctx.Jenkins != nil {
+		if ctx.Jenkins.HistoryCount < 0 {
+			errs = append(errs, common.WithPath(common.NewValidateError(fmt.Sprintf("%s.jenkins.historyCount should not be negative", SystemArgKey), nil), "jenkins.historyCount"))
+		}
+		if ctx.Jenkins.HistoryDays < 0 {
+			errs = append(errs, common.WithPath(common.NewValidateError(fmt.Sprintf("%s.jenkins.historyDays should not be negative", SystemArgKey), nil), "jenkins.historyDays"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func isSystemTrigger(trigger string) bool {
+	for _, t := range SystemTriggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// Values system context as arguments values, keys are the same as GetSystemArgs, so all the fields could be referred even if they are not set
+func (ctx *SystemContext) Values() map[string]interface{} {
+	jenkins := JenkinsSettings{}
+	if ctx.Jenkins != nil {
+		jenkins = *ctx.Jenkins
+	}
+	return map[string]interface{}{
+		"project":      ctx.Project,
+		"namespace":    ctx.Namespace,
+		"cluster":      ctx.Cluster,
+		"pipelineName": ctx.PipelineName,
+		"trigger":      ctx.Trigger,
+		"jenkins": map[string]interface{}{
+			"historyCount": jenkins.HistoryCount,
+			"historyDays":  jenkins.HistoryDays,
+			"concurrent":   jenkins.Concurrent,
+		},
+	}
+}
+
+// PipelineOptions options of pipeline that jenkins settings are applied to, options is not changed
+func (ctx *SystemContext) PipelineOptions(options *jenkinsfile.Options) *jenkinsfile.Options {
+	if ctx == nil || ctx.Jenkins == nil {
+		return options
+	}
+
+	applied := jenkinsfile.Options{}
+	if options != nil {
+		applied = *options
+	}
+	if ctx.Jenkins.HistoryCount > 0 {
+		applied.HistoryCount = ctx.Jenkins.HistoryCount
+	}
+	if ctx.Jenkins.HistoryDays > 0 {
+		applied.HistoryDays = ctx.Jenkins.HistoryDays
+	}
+	applied.Concurrent = ctx.Jenkins.Concurrent
+	return &applied
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/otiszv/render/domain/common"
+)
+
+func TestParseSystemContext(t *testing.T) {
+	value := map[string]interface{}{
+		"project":   "devops",
+		"namespace": "devops-dev",
+		"trigger":   "scm",
+		"jenkins":   map[string]interface{}{"historyCount": float64(20), "concurrent": true},
+	}
+	ctx, err := ParseSystemContext(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &SystemContext{Project: "devops", Namespace: "devops-dev", Trigger: "scm", Jenkins: &JenkinsSettings{HistoryCount: 20, Concurrent: true}}
+	if !reflect.DeepEqual(ctx, expected) {
+		t.Errorf("expected %#v, but got %#v", expected, ctx)
+	}
+
+	if ctx, err := ParseSystemContext(nil); ctx != nil || err != nil {
+		t.Errorf("expected nil, but got %v, %v", ctx, err)
+	}
+	if _, err := ParseSystemContext("devops"); err == nil {
+		t.Errorf("expected error of value that is not an object")
+	}
+}
+
+func TestParseSystemContextInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		value map[string]interface{}
+		// paths of errors
+		expected []string
+	}{
+		{
+			name:     "unknown fields",
+			value:    map[string]interface{}{"namespce": "devops", "project": "devops", "Cluster ": "c"},
+			expected: []string{`["Cluster "]`, "namespce"},
+		},
+		{
+			name:     "unknown fields of jenkins",
+			value:    map[string]interface{}{"jenkins": map[string]interface{}{"historyCount": 1, "keepDays": 7}},
+			expected: []string{"jenkins.keepDays"},
+		},
+		{
+			name:     "unknown fields and invalid values",
+			value:    map[string]interface{}{"triger": "scm", "trigger": "webhook", "namespace": "Devops", "jenkins": map[string]interface{}{"historyDays": -1}},
+			expected: []string{"triger", "namespace", "trigger", "jenkins.historyDays"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseSystemContext(c.value)
+			paths := []string{}
+			for _, item := range common.FlattenErrors(err) {
+				typed, ok := item.(common.Error)
+				if !ok || typed.Code != common.CodeValidateError {
+					t.Errorf("unexpected error: %v", item)
+					continue
+				}
+				paths = append(paths, typed.Path)
+			}
+			if !reflect.DeepEqual(paths, c.expected) {
+				t.Errorf("expected errors at %v, but got %v", c.expected, paths)
+			}
+		})
+	}
+}
@@ -3,10 +3,13 @@ package domain
 import (
 	"github.com/otiszv/render/domain/arguments"
 	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/goutils"
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"text/template"
+	"text/template/parse"
 )
 
 const CloneTaskTemplateArgName = "SCM"
@@ -22,20 +25,32 @@ type TaskTemplateSpec struct {
 func (spec *TaskTemplateSpec) ValidateDefinition() error {
 	errs := common.Errors{}
 	if strings.TrimSpace(spec.Body) == "" {
-		errs = append(errs, common.NewTemplateDefinitionError(fmt.Sprintf("TaskTemplateSpec.Body should not be empty"), nil))
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("TaskTemplateSpec.Body should not be empty"), nil), "body"))
 	}
 
 	if err := ValidateAgent(spec.Agent); err != nil {
-		errs = append(errs, err)
+		errs = append(errs, common.WithPath(err, "agent"))
 	}
 
-	for _, argItem := range spec.Arguments {
+	for i, argItem := range spec.Arguments {
 		err := argItem.ValidateDefinition()
 		if err != nil {
-			errs = append(errs, err)
+			err = common.WithArgument(err, argItem.Name)
+			errs = append(errs, common.WithPath(err, fmt.Sprintf("arguments[%d]", i)))
 		}
 	}
 
+	if _, err := spec.GlobalVarRefs(); err != nil {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("parse task template body error: %s", err.Error()), nil), "body"))
+	}
+
+	if err := spec.argSections().ValidateRelations(); err != nil {
+		// arguments of task template are in the only one section
+		errs = append(errs, common.MapPath(err, func(path string) string {
+			return "arguments" + strings.TrimPrefix(path, "[0].items")
+		}))
+	}
+
 	if len(errs) == 0 {
 		return nil
 	}
@@ -45,20 +60,22 @@ func (spec *TaskTemplateSpec) ValidateDefinition() error {
 func (spec *TaskTemplateSpec) ValidateValue(templateArgValues map[string]interface{}) error {
 	errs := common.Errors{}
 
+	meaningfulArgs := spec.argSections().MeaningfulArgs(templateArgValues)
 	for _, arg := range spec.Arguments {
 		v, ok := templateArgValues[arg.Name]
 		if ok == false {
 			v = nil
 		}
 
-		if !arg.IsMeaningful(templateArgValues) {
-			fmt.Printf("arg `%s` is not meaningful , skip validate value \n", arg.Name)
+		if !meaningfulArgs[arg.Name] {
+			goutils.Logger.Printf("arg `%s` is not meaningful , skip validate value \n", arg.Name)
 			continue
 		}
 
 		err := arg.ValidateValue(v)
 		if err != nil {
-			errs = append(errs, err)
+			err = common.WithArgument(err, arg.Name)
+			errs = append(errs, common.WithPath(err, common.PathKey(arg.Name)))
 			continue
 		}
 	}
@@ -69,6 +86,12 @@ func (spec *TaskTemplateSpec) ValidateValue(templateArgValues map[string]interfa
 	return errs
 }
 
+func (spec *TaskTemplateSpec) argSections() arguments.ArgSections {
+	return arguments.ArgSections{
+		arguments.ArgSection{Items: spec.Arguments},
+	}
+}
+
 func (spec *TaskTemplateSpec) GetValues(templateArgValues map[string]interface{}) map[string]interface{} {
 	values := make(map[string]interface{}, len(spec.Arguments))
 	for _, arg := range spec.Arguments {
@@ -113,21 +136,133 @@ func (spec *TaskTemplateSpec) getRenderEngine() taskTemplateRenderEngine {
 	}
 }
 
+// taskTemplateFuncs functions that could be used in body of task template
+var taskTemplateFuncs = template.FuncMap{
+	"split":         strings.Split,
+	"replace":       strings.Replace,
+	"globalVar":     globalVar,
+	"globalVarExpr": globalVarExpr,
+	"checkout":      checkout,
+}
+
+// globalVarFuncs template funcs that refer global vars by their first argument
+var globalVarFuncs = map[string]struct{}{"globalVar": {}, "globalVarExpr": {}}
+
+// globalVar template func that refers a registered global var in double quoted groovy string,
+// eg: sh "echo {{globalVar "BUILD_NUMBER"}}" -> sh "echo ${env.BUILD_NUMBER}"
+func globalVar(name string) (string, error) {
+	expression, err := globalVarExpr(name)
+	if err != nil {
+		return "", err
+	}
+	return "${" + expression + "}", nil
+}
+
+// globalVarExpr template func that refers a registered global var as groovy expression,
+// eg: if ({{globalVarExpr "GIT_BRANCH"}} == 'master') -> if (env.GIT_BRANCH == 'master')
+func globalVarExpr(name string) (string, error) {
+	if _, ok := DefaultGlobalVarRegistry.Lookup(name); !ok {
+		return "", fmt.Errorf("global var %s is not registered", name)
+	}
+	return "env." + name, nil
+}
+
+// ValidateGlobalVarRefs validate global vars that body refers are registered in registry,
+// they could be registered by platforms at runtime, so errors returned should be treated as warnings
+func (spec *TaskTemplateSpec) ValidateGlobalVarRefs(registry *GlobalVarRegistry) error {
+	refs, err := spec.GlobalVarRefs()
+	if err != nil {
+		// errors of body are reported by ValidateDefinition
+		return nil
+	}
+
+	errs := common.Errors{}
+	for _, name := range refs {
+		if _, ok := registry.Lookup(name); !ok {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("global var %s is not registered", name), nil), "body"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// GlobalVarRefs sorted names of global vars that body refers by globalVar or globalVarExpr func with constant names
+func (spec *TaskTemplateSpec) GlobalVarRefs() ([]string, error) {
+	t, err := template.New("gotpl-tasktemplate").Funcs(taskTemplateFuncs).Parse(spec.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[string]struct{}{}
+	if t.Tree != nil {
+		collectGlobalVarRefs(t.Tree.Root, refs)
+	}
+	names := []string{}
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func collectGlobalVarRefs(node parse.Node, refs map[string]struct{}) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			collectGlobalVarRefs(child, refs)
+		}
+	case *parse.ActionNode:
+		collectGlobalVarRefs(node.Pipe, refs)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, cmd := range node.Cmds {
+			collectGlobalVarRefs(cmd, refs)
+		}
+	case *parse.CommandNode:
+		for i, arg := range node.Args {
+			if identifier, ok := arg.(*parse.IdentifierNode); ok && i+1 < len(node.Args) {
+				_, isGlobalVarFunc := globalVarFuncs[identifier.Ident]
+				if name, ok := node.Args[i+1].(*parse.StringNode); ok && isGlobalVarFunc {
+					refs[name.Text] = struct{}{}
+				}
+			}
+			collectGlobalVarRefs(arg, refs)
+		}
+	case *parse.IfNode:
+		collectGlobalVarRefs(&node.BranchNode, refs)
+	case *parse.RangeNode:
+		collectGlobalVarRefs(&node.BranchNode, refs)
+	case *parse.WithNode:
+		collectGlobalVarRefs(&node.BranchNode, refs)
+	case *parse.BranchNode:
+		collectGlobalVarRefs(node.Pipe, refs)
+		collectGlobalVarRefs(node.List, refs)
+		collectGlobalVarRefs(node.ElseList, refs)
+	case *parse.TemplateNode:
+		collectGlobalVarRefs(node.Pipe, refs)
+	}
+}
+
 // gotplRender Render steps script block by task template and values
 func (spec *TaskTemplateSpec) gotplRender(values map[string]interface{}) (string, error) {
-	t, err := template.New("gotpl-tasktemplate").Funcs(template.FuncMap{
-		"split":   strings.Split,
-		"replace": strings.Replace,
-	}).Parse(spec.Body)
+	t, err := template.New("gotpl-tasktemplate").Funcs(taskTemplateFuncs).Parse(spec.Body)
 	if err != nil {
-		fmt.Printf("parse task template script body error:%#v\n", err)
+		goutils.Logger.Printf("parse task template script body error:%#v\n", err)
 		return "", common.NewTemplateRenderError(err.Error(), err, nil)
 	}
 
 	buffer := bytes.NewBufferString("")
 	err = t.Execute(buffer, values)
 	if err != nil {
-		fmt.Printf("parse task template script body execute error , body is \n %s , valus is \n %#v error is: %#v\n", spec.Body, values, err)
+		goutils.Logger.Printf("parse task template script body execute error , body is \n %s , valus is \n %#v error is: %#v\n", spec.Body, values, err)
 		return "", common.NewTemplateRenderError(fmt.Sprintf("parse task template script body execute error %s", err), err, nil)
 	}
 
@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/otiszv/render/domain/common"
+	"github.com/otiszv/render/goutils"
+)
+
+// TemplateTestSuffixes suffixes of files that contain test cases of templates, they are not templates
+var TemplateTestSuffixes = []string{".test.yaml", ".test.yml"}
+
+// IsTemplateTestFile whether file is a test suite file
+func IsTemplateTestFile(file string) bool {
+	for _, suffix := range TemplateTestSuffixes {
+		if strings.HasSuffix(file, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// match modes of expected jenkinsfile
+const (
+	TemplateTestMatchWhitespace = "whitespace"
+	TemplateTestMatchExact      = "exact"
+)
+
+// TemplateTestSuite test cases of a pipeline template, it is stored next to templates, eg: golang.test.yaml
+type TemplateTestSuite struct {
+	// File path of suite file, golden files are relative to it
+	File     string             `json:"-"`
+	Template TemplateRef        `json:"template"`
+	Cases    []TemplateTestCase `json:"cases"`
+}
+
+// TemplateTestCase render pipeline template with values, and expect jenkinsfile or errors
+type TemplateTestCase struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+	SCM       *SCMInfo               `json:"scm"`
+
+	// Jenkinsfile golden file of expected jenkinsfile, relative to suite file.
+	// default is `<suite>.<case>.Jenkinsfile`, eg: golang.build-with-tag.Jenkinsfile
+	Jenkinsfile string `json:"jenkinsfile"`
+	// Match whitespace(default) ignores differences of whitespaces, exact requires the same content
+	Match string `json:"match"`
+	// Errors render should fail with errors that contain these messages
+	Errors []string `json:"errors"`
+	// SkippedTasks tasks that are not meaningful and not rendered, it is not checked when nil
+	SkippedTasks []string `json:"skippedTasks"`
+}
+
+// TemplateTestResult result of running a test case
+type TemplateTestResult struct {
+	Case *TemplateTestCase
+	// Jenkinsfile rendered jenkinsfile
+	Jenkinsfile string
+	// Expected content of golden file, empty when golden file is not exists
+	Expected     string
+	SkippedTasks []string
+	// Err error of render
+	Err error
+	// Failures reasons that case is failed, empty means passed
+	Failures []string
+	// Diff unified diff between golden file and rendered jenkinsfile
+	Diff string
+}
+
+// Passed whether case is passed
+func (result *TemplateTestResult) Passed() bool {
+	return len(result.Failures) == 0
+}
+
+// LoadTemplateTestSuite load suite from file
+func LoadTemplateTestSuite(file string) (*TemplateTestSuite, error) {
+	byts, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, common.NewLoadError(err.Error(), err, map[string]interface{}{"file": file})
+	}
+
+	suite := &TemplateTestSuite{}
+	if err = yaml.Unmarshal(byts, suite); err != nil {
+		return nil, common.NewLoadError(fmt.Sprintf("%s: %s", file, err.Error()), err, map[string]interface{}{"file": file})
+	}
+	suite.File = file
+	return suite, suite.validate()
+}
+
+func (suite *TemplateTestSuite) validate() error {
+	errs := common.Errors{}
+
+	if strings.TrimSpace(suite.Template.Name) == "" {
+		errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("template.name should be required", nil), "template.name"))
+	}
+
+	names := map[string]struct{}{}
+	for i, testCase := range suite.Cases {
+		path := fmt.Sprintf("cases[%d]", i)
+		if strings.TrimSpace(testCase.Name) == "" {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("case name should be required", nil), path+".name"))
+		} else if _, ok := names[testCase.Name]; ok {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("case name %s should be unique", testCase.Name), nil), path+".name"))
+		}
+		names[testCase.Name] = struct{}{}
+
+		switch testCase.Match {
+		case "", TemplateTestMatchWhitespace, TemplateTestMatchExact:
+		default:
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError(fmt.Sprintf("match %s is not support, it should be whitespace or exact", testCase.Match), nil), path+".match"))
+		}
+		if len(testCase.Errors) > 0 && testCase.Jenkinsfile != "" {
+			errs = append(errs, common.WithPath(common.NewTemplateDefinitionError("jenkinsfile and errors could not be both expected", nil), path))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+var goldenNameRegx = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// GoldenFile path of golden file of test case
+func (suite *TemplateTestSuite) GoldenFile(testCase *TemplateTestCase) string {
+	dir := filepath.Dir(suite.File)
+	if testCase.Jenkinsfile != "" {
+		return filepath.Join(dir, testCase.Jenkinsfile)
+	}
+
+	base := filepath.Base(suite.File)
+	for _, suffix := range TemplateTestSuffixes {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	name := strings.Trim(goldenNameRegx.ReplaceAllString(testCase.Name, "-"), "-")
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.Jenkinsfile", base, name))
+}
+
+// Run render test case with templates in catalog, and compare with golden file
+func (suite *TemplateTestSuite) Run(catalog *TemplateCatalog, testCase *TemplateTestCase) *TemplateTestResult {
+	result := &TemplateTestResult{Case: testCase, Failures: []string{}}
+	result.Jenkinsfile, result.SkippedTasks, result.Err = suite.render(catalog, testCase)
+
+	if len(testCase.Errors) > 0 {
+		if result.Err == nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("expect errors %s, but render successfully", strings.Join(testCase.Errors, ", ")))
+			return result
+		}
+		messages := []string{}
+		for _, err := range common.FlattenErrors(result.Err) {
+			messages = append(messages, err.Error())
+		}
+		for _, expected := range testCase.Errors {
+			if !containsMessage(messages, expected) {
+				result.Failures = append(result.Failures, fmt.Sprintf("expect error `%s`, but got: %s", expected, strings.Join(messages, "; ")))
+			}
+		}
+		return result
+	}
+
+	if result.Err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("render error: %s", result.Err.Error()))
+		return result
+	}
+
+	if testCase.SkippedTasks != nil && strings.Join(testCase.SkippedTasks, ",") != strings.Join(result.SkippedTasks, ",") {
+		result.Failures = append(result.Failures, fmt.Sprintf("expect skipped tasks [%s], but got [%s]", strings.Join(testCase.SkippedTasks, ", "), strings.Join(result.SkippedTasks, ", ")))
+	}
+
+	golden := suite.GoldenFile(testCase)
+	byts, err := ioutil.ReadFile(golden)
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("read golden file error: %s", err.Error()))
+		return result
+	}
+	result.Expected = string(byts)
+
+	matched := result.Expected == result.Jenkinsfile
+	if testCase.Match != TemplateTestMatchExact {
+		matched = goutils.CompareText(result.Expected, result.Jenkinsfile)
+	}
+	if !matched {
+		result.Failures = append(result.Failures, fmt.Sprintf("rendered jenkinsfile is different from %s", golden))
+		result.Diff = goutils.UnifiedDiff(result.Expected, result.Jenkinsfile, golden, "rendered")
+	}
+	return result
+}
+
+func (suite *TemplateTestSuite) render(catalog *TemplateCatalog, testCase *TemplateTestCase) (string, []string, error) {
+	// spec is changed when rendering, so it is resolved for every case
+	spec, err := catalog.ResolvePipelineTemplate(suite.Template.Name, suite.Template.Version)
+	if err != nil {
+		return "", nil, err
+	}
+	if spec.IsExtended() {
+		spec, err = spec.Expand(catalog.ResolvePipelineTemplate)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	taskTemplates, err := catalog.ResolveTaskTemplates(spec)
+	if err != nil {
+		return "", nil, err
+	}
+
+	values := map[string]interface{}{}
+	for key, value := range testCase.Arguments {
+		values[key] = value
+	}
+	jenkinsfile, err := spec.RenderAndFormat(taskTemplates, values, testCase.SCM)
+	if err != nil {
+		return "", nil, err
+	}
+	return jenkinsfile, spec.SkippedTasks(), nil
+}
+
+func containsMessage(messages []string, expected string) bool {
+	for _, message := range messages {
+		if strings.Contains(message, expected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Block a `name { ... }` block of jenkinsfile, eg: stage("Build") { ... }
+type Block struct {
+	// Name text before `{`, eg: stage("Build"), steps, it is empty for the root block
+	Name string
+	// Line line that block starts at, starts from 1
+	Line     int
+	Children []*Block
+	// Statements non-blank lines directly in the block, lines of children are not included
+	Statements []Statement
+}
+
+// Statement a line of text in block
+type Statement struct {
+	Text string
+	Line int
+}
+
+// SyntaxError unbalanced braces or unterminated strings
+type SyntaxError struct {
+	Line    int
+	Message string
+}
+
+func (err SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", err.Line, err.Message)
+}
+
+// Directive the first word of block name, eg: stage("Build") -> stage, agent any -> agent
+func (block *Block) Directive() string {
+	name := strings.TrimSpace(block.Name)
+	if index := strings.IndexAny(name, "( \t"); index >= 0 {
+		return name[:index]
+	}
+	return name
+}
+
+// IsEmpty whether block has neither statements nor children
+func (block *Block) IsEmpty() bool {
+	return len(block.Statements) == 0 && len(block.Children) == 0
+}
+
+// ParseBlocks parse jenkinsfile to a tree of blocks by its braces, braces in strings are ignored.
+// the returned root block contains all top level blocks
+func ParseBlocks(jenkinsfile string) (*Block, []SyntaxError) {
+	s := scanner{
+		chars: []rune(jenkinsfile),
+		pos:   0,
+	}
+
+	root := &Block{Line: 1}
+	stack := []*Block{root}
+	errs := []SyntaxError{}
+
+	line := 1
+	pending, pendingLine := "", 0
+	flush := func() {
+		if text := strings.TrimSpace(pending); text != "" {
+			top := stack[len(stack)-1]
+			top.Statements = append(top.Statements, Statement{Text: text, Line: pendingLine})
+		}
+		pending, pendingLine = "", 0
+	}
+
+	for {
+		t, err := s.readToken()
+		if err != nil {
+			break
+		}
+
+		switch t.tokenType {
+		case leftBrace:
+			top := stack[len(stack)-1]
+			block := &Block{Name: strings.TrimSpace(pending), Line: pendingLine}
+			if block.Name == "" {
+				block.Line = line
+				// name is at the previous line, eg: stage("Build")\n{
+				if count := len(top.Statements); count > 0 && top.Statements[count-1].Line == line-1 {
+					block.Name, block.Line = top.Statements[count-1].Text, line-1
+					top.Statements = top.Statements[:count-1]
+				}
+			}
+			pending, pendingLine = "", 0
+			top.Children = append(top.Children, block)
+			stack = append(stack, block)
+		case rightBrace:
+			flush()
+			if len(stack) == 1 {
+				errs = append(errs, SyntaxError{Line: line, Message: "unexpected `}`"})
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		case eol:
+			flush()
+			line++
+		case lineComment, blockComment:
+			if t.unterminated {
+				errs = append(errs, SyntaxError{Line: line, Message: "comment is not terminated"})
+			}
+			line += strings.Count(t.value, "\n")
+		default:
+			if t.unterminated {
+				errs = append(errs, SyntaxError{Line: line, Message: fmt.Sprintf("string %s is not terminated", abbreviate(t.value))})
+			}
+			if pendingLine == 0 && strings.TrimSpace(t.value) != "" {
+				pendingLine = line
+			}
+			pending += t.value
+			// strings may be in multi lines
+			line += strings.Count(t.value, "\n")
+		}
+	}
+	flush()
+
+	for _, block := range stack[1:] {
+		errs = append(errs, SyntaxError{Line: block.Line, Message: fmt.Sprintf("`{` of %s is not closed", abbreviate(block.Name))})
+	}
+	return root, errs
+}
+
+func abbreviate(text string) string {
+	text = strings.TrimSpace(text)
+	if index := strings.Index(text, "\n"); index >= 0 {
+		text = text[:index] + "..."
+	}
+	if runes := []rune(text); len(runes) > 40 {
+		text = string(runes[:40]) + "..."
+	}
+	if text == "" {
+		return "block"
+	}
+	return text
+}
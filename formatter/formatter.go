@@ -1,84 +1,248 @@
 package formatter
 
 import (
-	"regexp"
+	"bufio"
+	"io"
 	"strings"
 )
 
 const defaultIndentSpaces = 4
 
-func Format(jenkinsfile string) string {
-	indentLevel := 0
-	scanner := scanner{
-		chars: []rune(jenkinsfile),
-		pos:   0,
+// line endings of Options.LineEnding
+const (
+	LineEndingLF   = "\n"
+	LineEndingCRLF = "\r\n"
+)
+
+// Options style of formatted jenkinsfile, the zero value is the default style
+type Options struct {
+	// IndentSpaces spaces of an indent level, 0 means 4, it is ignored when UseTabs is set
+	IndentSpaces int
+	UseTabs      bool
+	// MaxBlankLines max consecutive blank lines that are kept, 0 means blank lines are removed
+	MaxBlankLines int
+	// NoSpaceBeforeBrace remove spaces before `{`, eg: steps{, otherwise one space is kept, eg: steps {
+	NoSpaceBeforeBrace bool
+	// TrailingNewline end with exactly one line ending
+	TrailingNewline bool
+	// LineEnding LineEndingLF(default) or LineEndingCRLF, line endings of input are normalized to it
+	LineEnding string
+	// AlignAssignments align `=` of `key = value` entries in environment blocks
+	AlignAssignments bool
+}
+
+func (options Options) indent(indentLevel int) string {
+	if options.UseTabs {
+		return strings.Repeat("\t", indentLevel)
+	}
+	spaces := options.IndentSpaces
+	if spaces <= 0 {
+		spaces = defaultIndentSpaces
 	}
+	return strings.Repeat(" ", indentLevel*spaces)
+}
 
+func Format(jenkinsfile string) string {
+	return FormatWithOptions(jenkinsfile, Options{})
+}
+
+// FormatWithOptions format jenkinsfile in the style of options
+func FormatWithOptions(jenkinsfile string, options Options) string {
 	var result strings.Builder
+	// errors of strings.Reader and strings.Builder are always nil
+	FormatTo(&result, strings.NewReader(jenkinsfile), options)
+	return result.String()
+}
+
+// FormatTo format jenkinsfile that is read from r in the style of options, and write it to w.
+// it is formatted while it is read, so the whole jenkinsfile is never held in memory
+func FormatTo(w io.Writer, r io.Reader, options Options) error {
+	runeReader, ok := r.(io.RuneReader)
+	if !ok {
+		runeReader = bufio.NewReader(r)
+	}
+	scanner := &scanner{reader: runeReader}
+	stream := &tokenStream{scanner: scanner}
+	result := newOutput(w, options)
 
-	tokens := scanner.readAllToTokens()
-	for i := 0; i < len(tokens); i++ {
-		currentToken := tokens[i]
-		var nextToken *token
-		if i == len(tokens)-1 {
-			nextToken = nil
-		} else {
-			nextToken = &tokens[i+1]
+	indenter := &indenter{}
+	// spaced whether the last written text is indent or a separator
+	spaced := true
+	newLine := func(indentLevel int) {
+		result.write("\n", false)
+		indenter.lineLevel = indentLevel
+		result.write(options.indent(indentLevel), false)
+		spaced = true
+	}
+
+	for {
+		currentToken, ok := stream.next()
+		if !ok {
+			break
 		}
+		nextToken := stream.peek(0)
 
 		switch currentToken.tokenType {
 		case leftBrace:
-			result.WriteString(currentToken.value)
-			indentLevel++
+			result.write(currentToken.value, false)
+			spaced = false
+			indenter.open(true)
 
 			if nextToken == nil {
-				result.WriteString("\n")
+				result.write("\n", false)
 			} else if nextToken.tokenType == eol {
 				break
+			} else if isTrailingComment(nextToken) {
+				result.write(" ", false)
+				spaced = true
 			} else {
-				result.WriteString("\n")
-				result.WriteString(indent(indentLevel))
+				newLine(indenter.level())
 			}
 		case rightBrace:
-			result.WriteString("\n")
-			indentLevel--
-			result.WriteString(indent(indentLevel))
-			result.WriteString(currentToken.value)
+			newLine(indenter.close(true))
+			result.write(currentToken.value, false)
+			spaced = false
 
 			if nextToken == nil {
-				result.WriteString("\n")
-			} else if nextToken.tokenType == other {
-				result.WriteString("\n")
-				result.WriteString(indent(indentLevel))
+				result.write("\n", false)
+			} else if isTrailingComment(nextToken) {
+				result.write(" ", false)
+				spaced = true
+			} else if nextToken.tokenType == other && !isContinuation(nextToken) {
+				newLine(indenter.level())
 			}
 		case eol:
-			if nextToken == nil || nextToken.tokenType == eol || nextToken.tokenType == rightBrace || (nextToken.tokenType == other && strings.TrimSpace(nextToken.value) == "") {
+			// skip blank lines and spaces before next token
+			blankLines := 0
+			for t := stream.peek(0); t != nil && (t.tokenType == eol || (t.tokenType == other && strings.TrimSpace(t.value) == "")); t = stream.peek(0) {
+				if t.tokenType == eol {
+					blankLines++
+				}
+				stream.next()
+			}
+			// right brace starts a new line by itself
+			if t := stream.peek(0); t == nil || t.tokenType == rightBrace {
 				break
 			}
 
-			result.WriteString(currentToken.value)
-			result.WriteString(indent(indentLevel))
-		case other, singleQuoteStr, doubleQuoteStr, tripleSingleQuoteStr, tripleDoubleQuoteStr:
+			for n := 0; n < blankLines && n < options.MaxBlankLines; n++ {
+				result.write("\n", false)
+			}
+			// the line that starts with right brackets is indented as the line that opens them, eg: ])
+			newLine(indenter.levelAfterClosing(countRightBrackets(stream)))
+		default:
+			value := currentToken.value
 			if currentToken.tokenType == other {
-				result.WriteString(strings.TrimLeft(currentToken.value, " \t"))
-			} else {
-				result.WriteString(currentToken.value)
+				if spaced {
+					value = strings.TrimLeft(value, " \t")
+				}
+				// trailing spaces
+				if nextToken == nil || nextToken.tokenType == eol || nextToken.tokenType == rightBrace {
+					value = strings.TrimRight(value, " \t")
+				}
+			}
+			if nextToken != nil && nextToken.tokenType == leftBrace {
+				if options.NoSpaceBeforeBrace {
+					value = strings.TrimRight(value, " \t")
+				} else if value != "" && !strings.HasSuffix(value, " ") {
+					value += " "
+				}
+			}
+
+			switch currentToken.tokenType {
+			case leftBracket:
+				indenter.open(false)
+			case rightBracket:
+				indenter.close(false)
 			}
-			if nextToken != nil && nextToken.tokenType == leftBrace && !strings.HasSuffix(currentToken.value, " ") {
-				result.WriteString(" ")
+			// lines in multi-line strings and comments are kept as they are
+			result.write(value, true)
+			if value != "" {
+				spaced = false
 			}
 		}
 	}
 
-	return result.String()
+	if scanner.err != nil {
+		return scanner.err
+	}
+	return result.close()
+}
+
+// scope a indentation scope that is opened by a brace or bracket
+type scope struct {
+	brace bool
+	// lineLevel indent level of the line that opens the scope, the line that closes it has the same level
+	lineLevel int
+}
+
+// indenter tracks the scopes that are not closed yet.
+// contents of a scope are indented one level more than the line that opens it,
+// so scopes that are opened in the same line are indented only once, eg: checkout([
+type indenter struct {
+	scopes []scope
+	// lineLevel indent level of current line
+	lineLevel int
+}
+
+func (in *indenter) open(brace bool) {
+	in.scopes = append(in.scopes, scope{brace: brace, lineLevel: in.lineLevel})
+}
+
+// close right brace closes the nearest brace and brackets in it, right bracket only closes a bracket.
+// it returns the indent level of the line that opens the closed scope, unbalanced ones are ignored
+func (in *indenter) close(brace bool) int {
+	for i := len(in.scopes) - 1; i >= 0; i-- {
+		if in.scopes[i].brace == brace {
+			lineLevel := in.scopes[i].lineLevel
+			in.scopes = in.scopes[:i]
+			return lineLevel
+		}
+		if !brace {
+			break
+		}
+	}
+	return in.level()
 }
 
-func removeSpaceLine(jenkinsfile string) string {
-	re := regexp.MustCompile("(?m)^[\\s]*$[\r\n]*")
+// level indent level of contents in current scope
+func (in *indenter) level() int {
+	if len(in.scopes) == 0 {
+		return 0
+	}
+	return in.scopes[len(in.scopes)-1].lineLevel + 1
+}
 
-	return strings.Trim(re.ReplaceAllString(jenkinsfile, ""), "\r\n")
+// levelAfterClosing indent level of the line that starts with count right brackets, eg: ])
+func (in *indenter) levelAfterClosing(count int) int {
+	level := in.level()
+	for i := len(in.scopes) - 1; i >= 0 && count > 0 && !in.scopes[i].brace; i-- {
+		level = in.scopes[i].lineLevel
+		count--
+	}
+	return level
 }
 
-func indent(indentLevel int) string {
-	return strings.Repeat(" ", indentLevel*defaultIndentSpaces)
+// countRightBrackets count of right brackets that are next to current position of stream
+func countRightBrackets(stream *tokenStream) int {
+	count := 0
+	for t := stream.peek(0); t != nil && t.tokenType == rightBracket; t = stream.peek(count) {
+		count++
+	}
+	return count
+}
+
+// isContinuation text that continues the line of right brace, eg: }, b: {
+func isContinuation(t *token) bool {
+	value := strings.TrimLeft(t.value, " \t")
+	return value == "" || strings.HasPrefix(value, ",")
+}
+
+// isTrailingComment comment after brace in the same line, eg: steps { // comment
+func isTrailingComment(t *token) bool {
+	if t.tokenType != other && t.tokenType != lineComment && t.tokenType != blockComment {
+		return false
+	}
+	value := strings.TrimLeft(t.value, " \t")
+	return strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/*")
 }
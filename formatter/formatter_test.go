@@ -0,0 +1,235 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+)
+
+var formatCases = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{
+		name:     "line comment with brace and quote",
+		input:    "stages{\n// comment with { brace and \"quote\nstage(\"a\"){\nsteps{\necho 'a'\n}\n}\n}\n",
+		expected: "stages {\n    // comment with { brace and \"quote\n    stage(\"a\") {\n        steps {\n            echo 'a'\n        }\n    }\n}",
+	},
+	{
+		name:     "block comment with brace and quote is kept verbatim",
+		input:    "steps{\n/* block { comment\n } with 'quote */\necho 'a'\n}\n",
+		expected: "steps {\n    /* block { comment\n } with 'quote */\n    echo 'a'\n}",
+	},
+	{
+		name:     "braces in gstring interpolation",
+		input:    "script{\ndef m = \"a-${x.collect { it }}-b\"\nsh \"echo ${env.A} {\"\n}\n",
+		expected: "script {\n    def m = \"a-${x.collect { it }}-b\"\n    sh \"echo ${env.A} {\"\n}",
+	},
+	{
+		name:     "slashy string",
+		input:    "script{\ndef r = /re{2}\"x/\ndef q = a / b / c\n}\n",
+		expected: "script {\n    def r = /re{2}\"x/\n    def q = a / b / c\n}",
+	},
+	{
+		name:     "dollar slashy string",
+		input:    "script{\ndef d = $/dollar { slashy \\/ }/$\n}\n",
+		expected: "script {\n    def d = $/dollar { slashy \\/ }/$\n}",
+	},
+	{
+		name:     "unterminated single quote ends at line end",
+		input:    "steps{\nsh 'unterminated {\n}\n",
+		expected: "steps {\n    sh 'unterminated {\n}",
+	},
+	{
+		name:     "unterminated double quote ends at line end",
+		input:    "steps{\nsh \"unterminated {\n}\n",
+		expected: "steps {\n    sh \"unterminated {\n}",
+	},
+	{
+		name:     "unterminated block comment",
+		input:    "steps{\n/* unterminated {",
+		expected: "steps {\n    /* unterminated {",
+	},
+}
+
+func TestFormat(t *testing.T) {
+	for _, c := range formatCases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := Format(c.input); actual != c.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		children []string
+		hasError bool
+	}{
+		{
+			name:     "braces in comments and strings are ignored",
+			input:    "steps {\n// {\n/* { */\nsh \"${a} {\"\ndef r = /{/\ndef d = $/{/$\n}\nscript {\n}\n",
+			children: []string{"steps", "script"},
+		},
+		{
+			name:     "unbalanced brace",
+			input:    "steps {\n",
+			children: []string{"steps"},
+			hasError: true,
+		},
+		{
+			name:     "unterminated quote",
+			input:    "steps {\nsh 'a {\n}\n",
+			children: []string{"steps"},
+			hasError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root, errs := ParseBlocks(c.input)
+			names := []string{}
+			for _, child := range root.Children {
+				names = append(names, child.Directive())
+			}
+			if strings.Join(names, ",") != strings.Join(c.children, ",") {
+				t.Errorf("expected blocks %v, but got %v", c.children, names)
+			}
+			if (len(errs) > 0) != c.hasError {
+				t.Errorf("expected error: %t, but got %v", c.hasError, errs)
+			}
+		})
+	}
+}
+
+func FuzzFormat(f *testing.F) {
+	for _, c := range formatCases {
+		f.Add(c.input)
+	}
+	f.Add("pipeline {\n\tstages {\r\n\t\tstage('a') { steps { sh \"${a\" } }\n}")
+
+	f.Fuzz(func(t *testing.T, jenkinsfile string) {
+		// it should never panic on malformed input
+		Format(jenkinsfile)
+		FormatWithOptions(jenkinsfile, Options{UseTabs: true, MaxBlankLines: 1, TrailingNewline: true, LineEnding: LineEndingCRLF, AlignAssignments: true})
+	})
+}
+
+func FuzzParseBlocks(f *testing.F) {
+	for _, c := range formatCases {
+		f.Add(c.input)
+	}
+
+	f.Fuzz(func(t *testing.T, jenkinsfile string) {
+		ParseBlocks(jenkinsfile)
+	})
+}
+
+func TestFormatWithOptions(t *testing.T) {
+	input := "pipeline{\nenvironment{\nA = 'a'\nLONG_NAME = 'b'\n}\n\n\n\nstages{\nstage('a'){\nsteps{\necho 'a'\n}\n}\n}\n}\n\n"
+	cases := []struct {
+		name     string
+		options  Options
+		expected string
+	}{
+		{
+			name:     "default",
+			options:  Options{},
+			expected: "pipeline {\n    environment {\n        A = 'a'\n        LONG_NAME = 'b'\n    }\n    stages {\n        stage('a') {\n            steps {\n                echo 'a'\n            }\n        }\n    }\n}",
+		},
+		{
+			name:     "indent spaces",
+			options:  Options{IndentSpaces: 2},
+			expected: "pipeline {\n  environment {\n    A = 'a'\n    LONG_NAME = 'b'\n  }\n  stages {\n    stage('a') {\n      steps {\n        echo 'a'\n      }\n    }\n  }\n}",
+		},
+		{
+			name:     "tabs take precedence over indent spaces",
+			options:  Options{IndentSpaces: 2, UseTabs: true},
+			expected: "pipeline {\n\tenvironment {\n\t\tA = 'a'\n\t\tLONG_NAME = 'b'\n\t}\n\tstages {\n\t\tstage('a') {\n\t\t\tsteps {\n\t\t\t\techo 'a'\n\t\t\t}\n\t\t}\n\t}\n}",
+		},
+		{
+			name:     "max blank lines",
+			options:  Options{MaxBlankLines: 2},
+			expected: "pipeline {\n    environment {\n        A = 'a'\n        LONG_NAME = 'b'\n    }\n\n\n    stages {\n        stage('a') {\n            steps {\n                echo 'a'\n            }\n        }\n    }\n}",
+		},
+		{
+			name:     "no space before brace",
+			options:  Options{NoSpaceBeforeBrace: true, IndentSpaces: 2},
+			expected: "pipeline{\n  environment{\n    A = 'a'\n    LONG_NAME = 'b'\n  }\n  stages{\n    stage('a'){\n      steps{\n        echo 'a'\n      }\n    }\n  }\n}",
+		},
+		{
+			name:     "trailing newline",
+			options:  Options{TrailingNewline: true, IndentSpaces: 2},
+			expected: "pipeline {\n  environment {\n    A = 'a'\n    LONG_NAME = 'b'\n  }\n  stages {\n    stage('a') {\n      steps {\n        echo 'a'\n      }\n    }\n  }\n}\n",
+		},
+		{
+			name:     "crlf line ending",
+			options:  Options{LineEnding: LineEndingCRLF, TrailingNewline: true, IndentSpaces: 2},
+			expected: "pipeline {\r\n  environment {\r\n    A = 'a'\r\n    LONG_NAME = 'b'\r\n  }\r\n  stages {\r\n    stage('a') {\r\n      steps {\r\n        echo 'a'\r\n      }\r\n    }\r\n  }\r\n}\r\n",
+		},
+		{
+			name:     "align assignments",
+			options:  Options{AlignAssignments: true, IndentSpaces: 2},
+			expected: "pipeline {\n  environment {\n    A         = 'a'\n    LONG_NAME = 'b'\n  }\n  stages {\n    stage('a') {\n      steps {\n        echo 'a'\n      }\n    }\n  }\n}",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := FormatWithOptions(input, c.options); actual != c.expected {
+				t.Errorf("expected:\n%q\nbut got:\n%q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatLineEndingsOfInput(t *testing.T) {
+	// line endings of input are normalized to the one of options
+	input := "steps{\r\necho 'a'\r\n}\n"
+	if actual := FormatWithOptions(input, Options{}); actual != "steps {\n    echo 'a'\n}" {
+		t.Errorf("unexpected lf format: %q", actual)
+	}
+	if actual := FormatWithOptions(input, Options{LineEnding: LineEndingCRLF}); actual != "steps {\r\n    echo 'a'\r\n}" {
+		t.Errorf("unexpected crlf format: %q", actual)
+	}
+}
+
+func TestFormatBracketScopes(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "list",
+			input:    "script{\ndef l = [\n'a',\n'b'\n]\n}\n",
+			expected: "script {\n    def l = [\n        'a',\n        'b'\n    ]\n}",
+		},
+		{
+			name:     "arguments",
+			input:    "steps{\ncheckout(\nscm: scm,\nchangelog: false\n)\n}\n",
+			expected: "steps {\n    checkout(\n        scm: scm,\n        changelog: false\n    )\n}",
+		},
+		{
+			name:     "nested brackets and braces",
+			input:    "script{\ncheckout([\n$class: 'GitSCM',\nbranches: [[name: 'master']],\nextensions: [\n[$class: 'CloneOption', depth: 1]\n]\n])\nparallel(\na: {\necho 'a'\n}\n)\n}\n",
+			expected: "script {\n    checkout([\n        $class: 'GitSCM',\n        branches: [[name: 'master']],\n        extensions: [\n            [$class: 'CloneOption', depth: 1]\n        ]\n    ])\n    parallel(\n        a: {\n            echo 'a'\n        }\n    )\n}",
+		},
+		{
+			name:     "brackets in strings and comments are ignored",
+			input:    "script{\nsh \"echo [(\"\n// ( [\ndef m = [a: '(']\necho 'b'\n}\n",
+			expected: "script {\n    sh \"echo [(\"\n    // ( [\n    def m = [a: '(']\n    echo 'b'\n}",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := Format(c.input); actual != c.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package formatter
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	environmentBlockRegx = regexp.MustCompile(`^(\s*)environment\s*\{\s*$`)
+	assignmentRegx       = regexp.MustCompile(`^(\s*)([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+)
+
+// output writes formatted jenkinsfile to writer line by line,
+// it aligns assignments, trims trailing blank lines and converts line endings in the style of options
+type output struct {
+	options Options
+	writer  *bufio.Writer
+	// line current line that is not ended yet
+	line strings.Builder
+	// lineVerbatim current line starts in a multi-line string or comment
+	lineVerbatim bool
+	// environment lines of environment block that wait to be aligned, it is nil out of environment blocks
+	environment       []outputLine
+	environmentIndent string
+	// blank trailing spaces and line endings that are held until next non-blank text
+	blank strings.Builder
+}
+
+type outputLine struct {
+	text     string
+	verbatim bool
+	// ended whether the line is ended with line ending, only the last line is not ended
+	ended bool
+}
+
+func newOutput(w io.Writer, options Options) *output {
+	return &output{
+		options: options,
+		writer:  bufio.NewWriter(w),
+	}
+}
+
+// write text to current line, lines that start in text are verbatim lines when verbatim is true
+func (out *output) write(text string, verbatim bool) {
+	for {
+		index := strings.IndexByte(text, '\n')
+		if index < 0 {
+			out.line.WriteString(text)
+			return
+		}
+
+		out.line.WriteString(text[:index])
+		out.writeLine(outputLine{text: out.line.String(), verbatim: out.lineVerbatim, ended: true})
+		out.line.Reset()
+		out.lineVerbatim = verbatim
+		text = text[index+1:]
+	}
+}
+
+// close write the last line and flush all the held contents
+func (out *output) close() error {
+	if out.line.Len() > 0 {
+		out.writeLine(outputLine{text: out.line.String(), verbatim: out.lineVerbatim})
+		out.line.Reset()
+	}
+	if out.environment != nil {
+		out.flushEnvironment()
+	}
+
+	if out.options.TrailingNewline {
+		out.blank.Reset()
+		out.writeText("\n")
+	} else {
+		out.writeText(out.blank.String())
+	}
+	return out.writer.Flush()
+}
+
+func (out *output) writeLine(line outputLine) {
+	if !out.options.AlignAssignments {
+		out.emit(line)
+		return
+	}
+
+	if out.environment != nil {
+		// the block ends at the right brace with the same indent
+		if !line.verbatim && strings.TrimRight(line.text, " \t") == out.environmentIndent+"}" {
+			out.flushEnvironment()
+			out.emit(line)
+			return
+		}
+		out.environment = append(out.environment, line)
+		return
+	}
+
+	if !line.verbatim {
+		if match := environmentBlockRegx.FindStringSubmatch(line.text); match != nil {
+			out.environment = []outputLine{}
+			out.environmentIndent = match[1]
+		}
+	}
+	out.emit(line)
+}
+
+// flushEnvironment align `=` of assignments that directly in the environment block and write them
+func (out *output) flushEnvironment() {
+	lines := out.environment
+	out.environment = nil
+
+	entryIndent := ""
+	width := 0
+	for _, line := range lines {
+		if line.verbatim {
+			continue
+		}
+		if assignment := assignmentRegx.FindStringSubmatch(line.text); assignment != nil {
+			if entryIndent == "" {
+				entryIndent = assignment[1]
+			}
+			if assignment[1] == entryIndent && len(assignment[2]) > width {
+				width = len(assignment[2])
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if !line.verbatim {
+			assignment := assignmentRegx.FindStringSubmatch(line.text)
+			if assignment != nil && assignment[1] == entryIndent {
+				line.text = assignment[1] + assignment[2] + strings.Repeat(" ", width-len(assignment[2])) + " = " + assignment[3]
+			}
+		}
+		out.emit(line)
+	}
+}
+
+// emit write line, its trailing blank is held until next non-blank text
+func (out *output) emit(line outputLine) {
+	text := line.text
+	if line.ended {
+		text += "\n"
+	}
+
+	index := strings.LastIndexFunc(text, func(char rune) bool {
+		return char != ' ' && char != '\t' && char != '\n'
+	})
+	if index < 0 {
+		out.blank.WriteString(text)
+		return
+	}
+	out.writeText(out.blank.String() + text[:index+1])
+	out.blank.Reset()
+	out.blank.WriteString(text[index+1:])
+}
+
+func (out *output) writeText(text string) {
+	if out.options.LineEnding != "" && out.options.LineEnding != LineEndingLF {
+		text = strings.Replace(text, "\n", out.options.LineEnding, -1)
+	}
+	out.writer.WriteString(text)
+}
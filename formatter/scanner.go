@@ -1,169 +1,391 @@
 package formatter
 
-import "errors"
+import (
+	"errors"
+	"io"
+	"unicode"
+)
 
+// compactSize chars that are read are dropped when there are more than compactSize of them
+const compactSize = 4096
+
+// scanner a lexer of groovy that only recognizes the tokens that formatter cares about.
+// it never fails, malformed contents are returned as unterminated tokens or others
 type scanner struct {
 	pos   int
 	chars []rune
+	// reader chars are read from reader on demand when it is set, line endings of it are normalized to \n
+	reader io.RuneReader
+	// err error of reader except io.EOF
+	err error
+	// peeked the char that is read after \r
+	peeked    rune
+	hasPeeked bool
+}
+
+// stringKind how a kind of string is terminated
+type stringKind struct {
+	terminator []rune
+	// interpolation GString that contains ${...}
+	interpolation bool
+	multiline     bool
+	// dollarEscape escapes are $$ and $/ instead of backslash, only for dollar slashy string
+	dollarEscape bool
+}
+
+var (
+	singleQuoteKind       = stringKind{terminator: []rune(`'`)}
+	doubleQuoteKind       = stringKind{terminator: []rune(`"`), interpolation: true}
+	tripleSingleQuoteKind = stringKind{terminator: []rune(`'''`), multiline: true}
+	tripleDoubleQuoteKind = stringKind{terminator: []rune(`"""`), interpolation: true, multiline: true}
+	slashyKind            = stringKind{terminator: []rune(`/`), interpolation: true, multiline: true}
+	dollarSlashyKind      = stringKind{terminator: []rune(`/$`), interpolation: true, multiline: true, dollarEscape: true}
+)
+
+// keywords that could be followed by a slashy string, eg: return /x/
+var slashyKeywords = map[string]struct{}{
+	"return": {}, "case": {}, "in": {}, "assert": {},
 }
 
-func (s *scanner) readAllToTokens() []token {
-	tokens := make([]token, 0)
+// tokenStream tokens of scanner that could be looked ahead.
+// strings, comments and others are merged to other type if last token is other type
+type tokenStream struct {
+	scanner *scanner
+	tokens  []token
+	// pending last token that may be merged with next tokens
+	pending    token
+	hasPending bool
+}
 
-	for {
-		t, err := s.readToken()
+// peek the nth token after current position, it returns nil when there are not enough tokens
+func (stream *tokenStream) peek(n int) *token {
+	for len(stream.tokens) <= n {
+		t, err := stream.scanner.readToken()
 		if err != nil {
-			break
+			if !stream.hasPending {
+				return nil
+			}
+			stream.tokens = append(stream.tokens, stream.pending)
+			stream.hasPending = false
+			continue
 		}
 
-		// merge string type and other type to other type if last token is other type
-		if len(tokens) > 0 && tokens[len(tokens)-1].tokenType == other &&
-			(t.tokenType == singleQuoteStr || t.tokenType == doubleQuoteStr ||
-				t.tokenType == tripleDoubleQuoteStr || t.tokenType == tripleSingleQuoteStr || t.tokenType == other) {
-			lastToken := tokens[len(tokens)-1]
-			tokens[len(tokens)-1] = token{
-				tokenType: other,
-				value:     lastToken.value + t.value,
+		if stream.hasPending && stream.pending.tokenType == other && (t.tokenType.isVerbatim() || t.tokenType == other) {
+			stream.pending = token{
+				tokenType:    other,
+				value:        stream.pending.value + t.value,
+				unterminated: stream.pending.unterminated || t.unterminated,
 			}
-		} else {
-			tokens = append(tokens, t)
+			continue
 		}
+		if stream.hasPending {
+			stream.tokens = append(stream.tokens, stream.pending)
+		}
+		stream.pending, stream.hasPending = t, true
 	}
+	return &stream.tokens[n]
+}
 
-	return tokens
+// next read next token, it returns false at the end
+func (stream *tokenStream) next() (token, bool) {
+	t := stream.peek(0)
+	if t == nil {
+		return token{}, false
+	}
+	current := *t
+	stream.tokens = stream.tokens[1:]
+	return current, true
 }
 
 func (s *scanner) readToken() (token, error) {
+	s.compact()
 	startPos := s.pos
 
-	for ; s.pos < len(s.chars); s.pos++ {
-		currentChar := s.chars[s.pos]
+	for ; s.has(s.pos); s.pos++ {
+		if !s.isTokenStart(s.pos) {
+			continue
+		}
+		if startPos != s.pos {
+			return s.newToken(other, startPos, false), nil
+		}
+		return s.readSpecialToken(), nil
+	}
 
-		switch currentChar {
-		case '{', '}':
-			if startPos != s.pos {
-				return token{
-					tokenType: other,
-					value:     string(s.chars[startPos:s.pos]),
-				}, nil
-			}
+	if startPos < s.pos {
+		return s.newToken(other, startPos, false), nil
+	}
+	return token{}, errors.New("read to end of file")
+}
 
-			var tokenType tokenType
-			if currentChar == '{' {
-				tokenType = leftBrace
-			} else {
-				tokenType = rightBrace
+// has whether there is a char at pos, chars are read from reader when they are needed
+func (s *scanner) has(pos int) bool {
+	for pos >= len(s.chars) && (s.reader != nil || s.hasPeeked) {
+		char, ok := s.readRune()
+		if !ok {
+			break
+		}
+		if char == '\r' {
+			if next, ok := s.readRune(); ok && next != '\n' {
+				s.peeked, s.hasPeeked = next, true
+			} else if ok {
+				char = next
 			}
+		}
+		s.chars = append(s.chars, char)
+	}
+	return pos >= 0 && pos < len(s.chars)
+}
+
+func (s *scanner) readRune() (rune, bool) {
+	if s.hasPeeked {
+		s.hasPeeked = false
+		return s.peeked, true
+	}
+	if s.reader == nil {
+		return 0, false
+	}
+	char, _, err := s.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		s.reader = nil
+		return 0, false
+	}
+	return char, true
+}
+
+// compact drop chars before current line, they are never looked back
+func (s *scanner) compact() {
+	if s.reader == nil || s.pos < compactSize {
+		return
+	}
+	lineStart := s.pos
+	for lineStart > 0 && s.chars[lineStart-1] != '\n' {
+		lineStart--
+	}
+	// keep the line ending, it is looked back as the start of line
+	if lineStart--; lineStart <= 0 {
+		return
+	}
+	count := copy(s.chars, s.chars[lineStart:])
+	s.chars = s.chars[:count]
+	s.pos -= lineStart
+}
 
+func (s *scanner) newToken(tokenType tokenType, startPos int, unterminated bool) token {
+	return token{
+		tokenType:    tokenType,
+		value:        string(s.chars[startPos:s.pos]),
+		unterminated: unterminated,
+	}
+}
+
+// isTokenStart whether a token that is not other starts at pos
+func (s *scanner) isTokenStart(pos int) bool {
+	switch s.chars[pos] {
+	case '{', '}', '(', ')', '[', ']', '\n':
+		return true
+	case '\'', '"':
+		return !s.isEscaped(pos)
+	case '/':
+		return s.at(pos+1, '/') || s.at(pos+1, '*') || s.isSlashyStart(pos)
+	case '$':
+		return s.at(pos+1, '/')
+	}
+	return false
+}
+
+// readSpecialToken read the token that starts at current position, see isTokenStart
+func (s *scanner) readSpecialToken() token {
+	startPos := s.pos
+	currentChar := s.chars[s.pos]
+
+	switch {
+	case currentChar == '{':
+		s.pos++
+		return s.newToken(leftBrace, startPos, false)
+	case currentChar == '}':
+		s.pos++
+		return s.newToken(rightBrace, startPos, false)
+	case currentChar == '(' || currentChar == '[':
+		s.pos++
+		return s.newToken(leftBracket, startPos, false)
+	case currentChar == ')' || currentChar == ']':
+		s.pos++
+		return s.newToken(rightBracket, startPos, false)
+	case currentChar == '\n':
+		s.pos++
+		return s.newToken(eol, startPos, false)
+	case currentChar == '/' && s.at(s.pos+1, '/'):
+		for s.has(s.pos) && s.chars[s.pos] != '\n' {
+			s.pos++
+		}
+		return s.newToken(lineComment, startPos, false)
+	case currentChar == '/' && s.at(s.pos+1, '*'):
+		end := s.index(s.pos+2, []rune("*/"))
+		if end < 0 {
+			s.pos = len(s.chars)
+			return s.newToken(blockComment, startPos, true)
+		}
+		s.pos = end + 2
+		return s.newToken(blockComment, startPos, false)
+	case currentChar == '/' || currentChar == '$':
+		kind, tokenType, skip := slashyKind, slashyStr, 1
+		if currentChar == '$' {
+			kind, tokenType, skip = dollarSlashyKind, dollarSlashyStr, 2
+		}
+		end, ok := s.skipString(s.pos+skip, kind)
+		if !ok {
+			// it is an operator, not a string
 			s.pos++
-			return token{
-				tokenType: tokenType,
-				value:     string(currentChar),
-			}, nil
-		case '\'':
-			if startPos != s.pos {
-				return token{
-					tokenType: other,
-					value:     string(s.chars[startPos:s.pos]),
-				}, nil
+			return s.newToken(other, startPos, false)
+		}
+		s.pos = end
+		return s.newToken(tokenType, startPos, false)
+	case s.isTripleQuote(s.pos, currentChar):
+		kind, tokenType := tripleSingleQuoteKind, tripleSingleQuoteStr
+		if currentChar == '"' {
+			kind, tokenType = tripleDoubleQuoteKind, tripleDoubleQuoteStr
+		}
+		end, ok := s.skipString(s.pos+3, kind)
+		s.pos = end
+		return s.newToken(tokenType, startPos, !ok)
+	default:
+		kind, tokenType := singleQuoteKind, singleQuoteStr
+		if currentChar == '"' {
+			kind, tokenType = doubleQuoteKind, doubleQuoteStr
+		}
+		end, ok := s.skipString(s.pos+1, kind)
+		s.pos = end
+		return s.newToken(tokenType, startPos, !ok)
+	}
+}
+
+// skipString find the end of string whose content starts at pos, it returns false when string is not terminated.
+// single line strings are not terminated at the end of line, and the end of line is not included
+func (s *scanner) skipString(pos int, kind stringKind) (int, bool) {
+	for s.has(pos) {
+		currentChar := s.chars[pos]
+		switch {
+		case kind.dollarEscape && currentChar == '$' && (s.at(pos+1, '$') || s.at(pos+1, '/')):
+			pos += 2
+		case !kind.dollarEscape && currentChar == '\\':
+			pos += 2
+		case s.hasPrefix(pos, kind.terminator):
+			return pos + len(kind.terminator), true
+		case kind.interpolation && currentChar == '$' && s.at(pos+1, '{'):
+			end, ok := s.skipInterpolation(pos+2, kind.multiline)
+			if !ok {
+				return end, false
 			}
+			pos = end
+		case currentChar == '\n' && !kind.multiline:
+			return pos, false
+		default:
+			pos++
+		}
+	}
+	return len(s.chars), false
+}
 
-			if s.isEscaped(s.pos) {
-				continue
-			} else if s.isTripleQuote(s.pos, '\'') {
-				// if is triple quote, the content between this and next triple quote will be view as triple quote string
-				s.pos = s.pos + 3
-				for s.pos < len(s.chars) {
-					if s.isTripleQuote(s.pos, '\'') {
-						s.pos = s.pos + 3
-						return token{
-							tokenType: tripleSingleQuoteStr,
-							value:     string(s.chars[startPos:s.pos]),
-						}, nil
-					}
-					s.pos++
-				}
-
-			} else {
-				// if is single quote, the content between this and next single quote will be view as single quote string
-				for s.pos < len(s.chars) {
-					s.pos++
-
-					if s.chars[s.pos] == '\'' && !s.isEscaped(s.pos) {
-						s.pos++
-						return token{
-							tokenType: singleQuoteStr,
-							value:     string(s.chars[startPos:s.pos]),
-						}, nil
-					}
-				}
+// skipInterpolation find the end of ${...} whose content starts at pos, braces and strings could be nested in it
+func (s *scanner) skipInterpolation(pos int, multiline bool) (int, bool) {
+	depth := 1
+	for s.has(pos) {
+		currentChar := s.chars[pos]
+		switch currentChar {
+		case '{':
+			depth++
+			pos++
+		case '}':
+			depth--
+			pos++
+			if depth == 0 {
+				return pos, true
 			}
-		case '"':
-			if startPos != s.pos {
-				return token{
-					tokenType: other,
-					value:     string(s.chars[startPos:s.pos]),
-				}, nil
+		case '\'', '"':
+			kind, skip := singleQuoteKind, 1
+			switch {
+			case s.isTripleQuote(pos, currentChar) && currentChar == '"':
+				kind, skip = tripleDoubleQuoteKind, 3
+			case s.isTripleQuote(pos, currentChar):
+				kind, skip = tripleSingleQuoteKind, 3
+			case currentChar == '"':
+				kind = doubleQuoteKind
 			}
-
-			if s.isEscaped(s.pos) {
-				continue
-			} else if s.isTripleQuote(s.pos, '"') {
-				s.pos = s.pos + 3
-				for s.pos < len(s.chars) {
-					if s.isTripleQuote(s.pos, '"') {
-						s.pos = s.pos + 3
-						return token{
-							tokenType: tripleDoubleQuoteStr,
-							value:     string(s.chars[startPos:s.pos]),
-						}, nil
-					}
-					s.pos++
-				}
-			} else {
-				// if is double quote, the content between this and next single quote will be view as single quote string
-				for s.pos < len(s.chars) {
-					s.pos++
-					if s.pos == len(s.chars) {
-						return token{
-							tokenType: other,
-							value:     string(s.chars[startPos:s.pos]),
-						}, nil
-					}
-
-					if s.chars[s.pos] == '"' && !s.isEscaped(s.pos) {
-						s.pos++
-						return token{
-							tokenType: doubleQuoteStr,
-							value:     string(s.chars[startPos:s.pos]),
-						}, nil
-					}
-				}
+			end, ok := s.skipString(pos+skip, kind)
+			if !ok {
+				return end, false
 			}
+			pos = end
 		case '\n':
-			if startPos != s.pos {
-				return token{
-					tokenType: other,
-					value:     string(s.chars[startPos:s.pos]),
-				}, nil
+			if !multiline {
+				return pos, false
 			}
+			pos++
+		default:
+			pos++
+		}
+	}
+	return len(s.chars), false
+}
 
-			s.pos++
-			return token{
-				tokenType: eol,
-				value:     "\n",
-			}, nil
+// isSlashyStart `/` starts a slashy string when it could not be a division, eg: =~ /x/, (/x/), return /x/
+func (s *scanner) isSlashyStart(pos int) bool {
+	prev := pos - 1
+	for prev >= 0 && (s.chars[prev] == ' ' || s.chars[prev] == '\t' || s.chars[prev] == '\r') {
+		prev--
+	}
+	if prev < 0 || s.chars[prev] == '\n' {
+		return true
+	}
+
+	prevChar := s.chars[prev]
+	if isIdentifierChar(prevChar) {
+		start := prev
+		for start > 0 && isIdentifierChar(s.chars[start-1]) {
+			start--
 		}
+		_, ok := slashyKeywords[string(s.chars[start:prev+1])]
+		return ok
 	}
 
-	if startPos < len(s.chars) {
-		return token{
-			tokenType: other,
-			value:     string(s.chars[startPos:]),
-		}, nil
-	} else {
-		return token{}, errors.New("read to end of file")
+	switch prevChar {
+	case ')', ']', '}', '\'', '"', '/':
+		return false
+	}
+	return true
+}
+
+func isIdentifierChar(char rune) bool {
+	return char == '_' || char == '$' || unicode.IsLetter(char) || unicode.IsDigit(char)
+}
+
+func (s *scanner) at(pos int, char rune) bool {
+	return s.has(pos) && s.chars[pos] == char
+}
+
+func (s *scanner) hasPrefix(pos int, prefix []rune) bool {
+	if !s.has(pos + len(prefix) - 1) {
+		return false
+	}
+	for i, char := range prefix {
+		if s.chars[pos+i] != char {
+			return false
+		}
+	}
+	return true
+}
+
+// index index of sub in chars from pos, -1 means not found
+func (s *scanner) index(pos int, sub []rune) int {
+	for ; s.has(pos + len(sub) - 1); pos++ {
+		if s.hasPrefix(pos, sub) {
+			return pos
+		}
 	}
+	return -1
 }
 
 func (s *scanner) isEscaped(currentPos int) bool {
@@ -181,7 +403,7 @@ func (s *scanner) isEscaped(currentPos int) bool {
 }
 
 func (s *scanner) isTripleQuote(currentPos int, quote rune) bool {
-	return currentPos < len(s.chars)-2 &&
+	return s.has(currentPos+2) &&
 		(currentPos == 0 || !s.isEscaped(currentPos)) &&
 		s.chars[currentPos] == quote && s.chars[currentPos+1] == quote && s.chars[currentPos+2] == quote
 }
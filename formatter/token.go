@@ -2,20 +2,43 @@ package formatter
 
 type tokenType int
 
-// we only recognize four types of token now,
-// left brace, right brace, end of line and others
+// braces, brackets and end of line decide indentation, strings and comments are kept as they are,
+// braces, brackets and quotes in them are ignored. all the other contents are others
 const (
 	leftBrace tokenType = iota
 	rightBrace
+	// leftBracket `(` or `[`
+	leftBracket
+	// rightBracket `)` or `]`
+	rightBracket
 	eol
 	singleQuoteStr
 	doubleQuoteStr
 	tripleSingleQuoteStr
 	tripleDoubleQuoteStr
+	// slashyStr eg: /^v\d+$/
+	slashyStr
+	// dollarSlashyStr eg: $/C:\path\${name}/$
+	dollarSlashyStr
+	// lineComment eg: // comment, the end of line is not included
+	lineComment
+	// blockComment eg: /* comment */
+	blockComment
 	other
 )
 
 type token struct {
 	tokenType tokenType
 	value     string
+	// unterminated string or comment that reaches the end of line or file
+	unterminated bool
+}
+
+// isVerbatim strings and comments, their contents should not be changed
+func (t tokenType) isVerbatim() bool {
+	switch t {
+	case singleQuoteStr, doubleQuoteStr, tripleSingleQuoteStr, tripleDoubleQuoteStr, slashyStr, dollarSlashyStr, lineComment, blockComment:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,133 @@
+package goutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffLine struct {
+	kind byte // ' ', '-', '+'
+	text string
+}
+
+// UnifiedDiff return unified diff of two texts by lines, empty string means no difference
+func UnifiedDiff(txtSource string, txtTarget string, sourceName string, targetName string) string {
+	if txtSource == txtTarget {
+		return ""
+	}
+
+	lines := diffLines(strings.Split(txtSource, "\n"), strings.Split(txtTarget, "\n"))
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", sourceName, targetName))
+
+	for start := 0; start < len(lines); {
+		// find next changed line
+		for start < len(lines) && lines[start].kind == ' ' {
+			start++
+		}
+		if start >= len(lines) {
+			break
+		}
+
+		// extend the hunk until there are enough unchanged lines
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		end := start
+		for end < len(lines) {
+			if lines[end].kind != ' ' {
+				end++
+				continue
+			}
+			unchanged := 0
+			for end+unchanged < len(lines) && lines[end+unchanged].kind == ' ' {
+				unchanged++
+			}
+			if end+unchanged >= len(lines) || unchanged > diffContextLines*2 {
+				break
+			}
+			end += unchanged
+		}
+		hunkEnd := end + diffContextLines
+		if hunkEnd > len(lines) {
+			hunkEnd = len(lines)
+		}
+
+		sourceStart, targetStart := 1, 1
+		for _, line := range lines[:hunkStart] {
+			if line.kind != '+' {
+				sourceStart++
+			}
+			if line.kind != '-' {
+				targetStart++
+			}
+		}
+		sourceCount, targetCount := 0, 0
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.kind != '+' {
+				sourceCount++
+			}
+			if line.kind != '-' {
+				targetCount++
+			}
+		}
+
+		result.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", sourceStart, sourceCount, targetStart, targetCount))
+		for _, line := range lines[hunkStart:hunkEnd] {
+			result.WriteByte(line.kind)
+			result.WriteString(line.text)
+			result.WriteString("\n")
+		}
+		start = hunkEnd
+	}
+
+	return result.String()
+}
+
+// diffLines compute line changes by longest common subsequence
+func diffLines(source []string, target []string) []diffLine {
+	n, m := len(source), len(target)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if source[i] == target[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case source[i] == target[j]:
+			lines = append(lines, diffLine{' ', source[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', source[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', target[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, diffLine{'-', source[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, diffLine{'+', target[j]})
+	}
+	return lines
+}
@@ -0,0 +1,10 @@
+package goutils
+
+import (
+	"io/ioutil"
+	"log"
+)
+
+// Logger logger of diagnostic messages while rendering, it discards them by default so that
+// the jenkinsfile written to stdout is not polluted, use Logger.SetOutput(os.Stderr) to see them
+var Logger = log.New(ioutil.Discard, "", 0)
@@ -0,0 +1,272 @@
+package jenkinsfile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/otiszv/render/formatter"
+)
+
+// rules of lint
+const (
+	LintRuleSyntax               = "syntax"
+	LintRuleEmptySteps           = "empty-steps"
+	LintRuleEmptyStages          = "empty-stages"
+	LintRuleEmptyParallel        = "empty-parallel"
+	LintRuleSingleParallel       = "single-parallel"
+	LintRuleFailFast             = "failfast-without-parallel"
+	LintRuleEmptyPost            = "empty-post"
+	LintRuleUnknownPostCondition = "unknown-post-condition"
+	LintRuleDuplicateStage       = "duplicate-stage"
+	LintRuleUnknownDirective     = "unknown-directive"
+)
+
+// LintIssue a structural problem that makes jenkins reject the jenkinsfile
+type LintIssue struct {
+	Rule string `json:"rule"`
+	// Stage names of stage and its parents, eg: Build/Test, empty for problems out of stages
+	Stage string `json:"stage,omitempty"`
+	// Line line in jenkinsfile, 0 when it is found in Pipeline model
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (issue LintIssue) String() string {
+	parts := []string{issue.Rule}
+	if issue.Line > 0 {
+		parts = append(parts, fmt.Sprintf("line %d", issue.Line))
+	}
+	if issue.Stage != "" {
+		parts = append(parts, "stage "+issue.Stage)
+	}
+	parts = append(parts, issue.Message)
+	return strings.Join(parts, ": ")
+}
+
+// PostConditions all conditions that supported by post
+var PostConditions = []string{
+	POST_ALWAYS, POST_CHANGED, "fixed", "regression", POST_ABORTED,
+	POST_FAILURE, POST_SUCCESS, POST_UNSTABLE, "unsuccessful", "cleanup",
+}
+
+// directives that could be in pipeline and stage blocks
+var (
+	pipelineDirectives = []string{"agent", "environment", "options", "parameters", "triggers", "tools", "libraries", "stages", "post"}
+	stageDirectives    = []string{"agent", "environment", "options", "when", "input", "tools", "steps", "parallel", "stages", "matrix", "post"}
+)
+
+// Lint find structural problems of pipeline, they are not found by rendering
+func (pipeline *Pipeline) Lint() []LintIssue {
+	issues := []LintIssue{}
+	names := map[string]struct{}{}
+
+	var lintStage func(stage *Stage, parent string)
+	lintStage = func(stage *Stage, parent string) {
+		path := joinStagePath(parent, stage.Name)
+		if _, ok := names[stage.Name]; ok {
+			issues = append(issues, LintIssue{Rule: LintRuleDuplicateStage, Stage: path, Message: fmt.Sprintf("stage name %s is duplicate", stage.Name)})
+		}
+		names[stage.Name] = struct{}{}
+
+		switch {
+		case len(stage.Stages) > 1:
+			for _, child := range stage.Stages {
+				lintStage(child, path)
+			}
+			return
+		case len(stage.Stages) == 1:
+			issues = append(issues, LintIssue{Rule: LintRuleSingleParallel, Stage: path, Message: fmt.Sprintf("parallel has only one stage %s, it is rendered as steps", stage.Stages[0].Name)})
+		case stage.Stages != nil && stage.Steps == nil:
+			issues = append(issues, LintIssue{Rule: LintRuleEmptyParallel, Stage: path, Message: "parallel has no stages"})
+		case stage.Approve == nil && (stage.Steps == nil || strings.TrimSpace(stage.Steps.ScriptsContent) == ""):
+			issues = append(issues, LintIssue{Rule: LintRuleEmptySteps, Stage: path, Message: "steps is empty"})
+		}
+		if stage.FailFast {
+			issues = append(issues, LintIssue{Rule: LintRuleFailFast, Stage: path, Message: "failFast is only allowed in stage with parallel"})
+		}
+	}
+	if len(pipeline.Stages) == 0 {
+		issues = append(issues, LintIssue{Rule: LintRuleEmptyStages, Message: "stages has no stage"})
+	}
+	for _, stage := range pipeline.Stages {
+		lintStage(stage, "")
+	}
+
+	// post is always rendered
+	if len(pipeline.Post) == 0 {
+		issues = append(issues, LintIssue{Rule: LintRuleEmptyPost, Message: "post has no conditions"})
+	}
+	for _, postCondition := range pipeline.Post {
+		if !containsString(PostConditions, postCondition.Name) {
+			issues = append(issues, LintIssue{Rule: LintRuleUnknownPostCondition, Message: fmt.Sprintf("post condition %s is not support", postCondition.Name)})
+		}
+		if strings.TrimSpace(postCondition.Scripts) == "" {
+			issues = append(issues, LintIssue{Rule: LintRuleEmptyPost, Message: fmt.Sprintf("post condition %s is empty", postCondition.Name)})
+		}
+	}
+	return issues
+}
+
+// MergeLintIssues merge issues found by Pipeline.Lint and LintText of the same pipeline,
+// issues of pipeline that are also found in text are dropped, as the ones of text have lines
+func MergeLintIssues(pipelineIssues []LintIssue, textIssues []LintIssue) []LintIssue {
+	issues := append([]LintIssue{}, textIssues...)
+	for _, issue := range pipelineIssues {
+		found := false
+		for _, textIssue := range textIssues {
+			if textIssue.Rule == issue.Rule && textIssue.Stage == issue.Stage && textIssue.Message == issue.Message {
+				found = true
+				break
+			}
+		}
+		if !found {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// LintText find syntax and structural problems of declarative jenkinsfile,
+// it finds the same problems as Pipeline.Lint, and unbalanced braces, unterminated strings and unknown directives
+func LintText(jenkinsfile string) []LintIssue {
+	issues := []LintIssue{}
+
+	root, syntaxErrs := formatter.ParseBlocks(jenkinsfile)
+	for _, err := range syntaxErrs {
+		issues = append(issues, LintIssue{Rule: LintRuleSyntax, Line: err.Line, Message: err.Message})
+	}
+
+	var pipeline *formatter.Block
+	for _, block := range root.Children {
+		if block.Directive() == "pipeline" {
+			pipeline = block
+			break
+		}
+	}
+	if pipeline == nil {
+		return append(issues, LintIssue{Rule: LintRuleSyntax, Line: 1, Message: "pipeline block is required"})
+	}
+
+	linter := &textLinter{names: map[string]struct{}{}, issues: issues}
+	for _, block := range pipeline.Children {
+		switch block.Directive() {
+		case "stages":
+			linter.lintStages(block, "")
+		case "post":
+			linter.lintPost(block, "")
+		default:
+			linter.checkDirective(block, pipelineDirectives, "pipeline", "")
+		}
+	}
+	return linter.issues
+}
+
+type textLinter struct {
+	names  map[string]struct{}
+	issues []LintIssue
+}
+
+func (linter *textLinter) add(rule string, line int, stage string, message string) {
+	linter.issues = append(linter.issues, LintIssue{Rule: rule, Line: line, Stage: stage, Message: message})
+}
+
+func (linter *textLinter) checkDirective(block *formatter.Block, directives []string, parent string, stage string) {
+	if !containsString(directives, block.Directive()) {
+		linter.add(LintRuleUnknownDirective, block.Line, stage, fmt.Sprintf("%s is not a directive of %s", block.Directive(), parent))
+	}
+}
+
+func (linter *textLinter) lintStages(stages *formatter.Block, parent string) {
+	if stages.Directive() == "stages" && len(stages.Children) == 0 {
+		linter.add(LintRuleEmptyStages, stages.Line, parent, "stages has no stage")
+	}
+	for _, block := range stages.Children {
+		if block.Directive() != "stage" {
+			linter.add(LintRuleUnknownDirective, block.Line, parent, fmt.Sprintf("%s is not allowed in stages, it should be stage", block.Directive()))
+			continue
+		}
+		linter.lintStage(block, parent)
+	}
+}
+
+var stageNameRegx = regexp.MustCompile(`^stage\s*\(\s*(?:"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)')\s*\)$`)
+
+func (linter *textLinter) lintStage(stage *formatter.Block, parent string) {
+	name := stage.Name
+	if match := stageNameRegx.FindStringSubmatch(strings.TrimSpace(stage.Name)); match != nil {
+		name = match[1] + match[2]
+	}
+	path := joinStagePath(parent, name)
+	if _, ok := linter.names[name]; ok {
+		linter.add(LintRuleDuplicateStage, stage.Line, path, fmt.Sprintf("stage name %s is duplicate", name))
+	}
+	linter.names[name] = struct{}{}
+
+	executions := 0
+	hasParallel := false
+	for _, block := range stage.Children {
+		switch block.Directive() {
+		case "steps":
+			executions++
+			if block.IsEmpty() {
+				linter.add(LintRuleEmptySteps, block.Line, path, "steps is empty")
+			}
+		case "parallel":
+			executions++
+			hasParallel = true
+			if len(block.Children) == 0 {
+				linter.add(LintRuleEmptyParallel, block.Line, path, "parallel has no stages")
+			}
+			linter.lintStages(block, path)
+		case "stages":
+			executions++
+			linter.lintStages(block, path)
+		case "matrix":
+			executions++
+		case "post":
+			linter.lintPost(block, path)
+		default:
+			linter.checkDirective(block, stageDirectives, "stage", path)
+		}
+	}
+	if executions == 0 {
+		linter.add(LintRuleEmptySteps, stage.Line, path, "stage has none of steps, parallel, stages or matrix")
+	}
+
+	for _, statement := range stage.Statements {
+		if strings.HasPrefix(statement.Text, "failFast") && !hasParallel {
+			linter.add(LintRuleFailFast, statement.Line, path, "failFast is only allowed in stage with parallel")
+		}
+	}
+}
+
+func (linter *textLinter) lintPost(post *formatter.Block, stage string) {
+	if len(post.Children) == 0 {
+		linter.add(LintRuleEmptyPost, post.Line, stage, "post has no conditions")
+	}
+	for _, block := range post.Children {
+		if !containsString(PostConditions, block.Directive()) {
+			linter.add(LintRuleUnknownPostCondition, block.Line, stage, fmt.Sprintf("post condition %s is not support", block.Directive()))
+		}
+		if block.IsEmpty() {
+			linter.add(LintRuleEmptyPost, block.Line, stage, fmt.Sprintf("post condition %s is empty", block.Directive()))
+		}
+	}
+}
+
+func joinStagePath(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+func containsString(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package jenkinsfile
+
+import (
+	"testing"
+)
+
+func TestMergeLintIssues(t *testing.T) {
+	pipeline := &Pipeline{
+		Agent: map[string]interface{}{"label": "golang"},
+		Stages: []*Stage{
+			{Name: "Build", Steps: &Steps{ScriptsContent: "sh 'make'"}, Stages: []*Stage{{Name: "Compile", Steps: &Steps{ScriptsContent: "sh 'make'"}}}},
+			{Name: "Test", Steps: &Steps{ScriptsContent: " "}},
+		},
+		Post: []*PostCondition{{Name: POST_ALWAYS, Scripts: "deleteDir()"}},
+	}
+
+	pipelineIssues := pipeline.Lint()
+	render, err := pipeline.RenderAndFormat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issues := MergeLintIssues(pipelineIssues, LintText(render))
+
+	count := map[string]int{}
+	for _, issue := range issues {
+		count[issue.Rule+" "+issue.Stage]++
+		// issues that found in both are reported with line
+		if issue.Rule == LintRuleEmptySteps && issue.Line == 0 {
+			t.Errorf("issue should have line: %s", issue)
+		}
+	}
+	// single parallel is rendered as steps, it is only found in pipeline
+	if count[LintRuleSingleParallel+" Build"] != 1 || count[LintRuleEmptySteps+" Test"] != 1 || len(issues) != 2 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
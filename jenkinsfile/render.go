@@ -2,8 +2,12 @@ package jenkinsfile
 
 import (
 	"github.com/otiszv/render/formatter"
+	"github.com/otiszv/render/goutils"
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
 	"text/template"
 
 	"github.com/mitchellh/mapstructure"
@@ -98,14 +102,16 @@ const pipelineTemplate = `pipeline{
 	{{- if .Environments}}
 	environment{
 		{{- range $index, $env := .Environments}}
-			{{$env.Name}} = "{{$env.Value}}"
+			{{$env.Render}}
 		{{- end}}
 	}
 	{{- end}}
 
 	options{
+		{{- if not .ConcurrentBuilds}}
 		disableConcurrentBuilds()
-		buildDiscarder(logRotator(numToKeepStr: '200'))
+		{{- end}}
+		buildDiscarder({{.LogRotator}})
 		{{- if .Options}}
 		{{- if .Options.Timeout}}
 		timeout(time:{{- .Options.Timeout}}, unit:'SECONDS')
@@ -115,13 +121,13 @@ const pipelineTemplate = `pipeline{
 
 	stages{
 		{{range $i, $stage := .Stages}}
-		{{- renderStage $stage}}
+		{{- template "stage" $stage}}
 		{{- end}}
 	}
 
 	post{
 		{{range $index, $postCondition := .Post}}
-		{{- renderPostCondition $postCondition}}
+		{{- template "postCondition" $postCondition}}
 		{{- end}}
 	}
 }
@@ -132,47 +138,87 @@ func (pipeline *Pipeline) initDefault() {
 		pipeline.Agent = "any"
 	}
 
-	for _, stage := range pipeline.Stages {
+	setPipeline(pipeline.Stages, pipeline)
+}
+
+// setPipeline set pipeline of stages and their parallel stages, agents of stages are compared with agent of pipeline
+func setPipeline(stages []*Stage, pipeline *Pipeline) {
+	for _, stage := range stages {
 		stage.pipeline = pipeline
-		for _, pstage := range pipeline.Stages {
-			pstage.pipeline = pipeline
-		}
+		setPipeline(stage.Stages, pipeline)
 	}
 }
 
-func (pipeline *Pipeline) Render() (string, error) {
-	pipeline.initDefault()
-
-	t, err := template.New("pipeline-template").Funcs(template.FuncMap{
-		"renderStage":         RenderStage,
-		"join":                Join,
-		"renderAgent":         RenderPipelineAgent,
-		"renderPostCondition": renderPostCondition,
-	}).Parse(pipelineTemplate)
+// templates of pipeline, stage and post condition, they are parsed only once and safe for concurrent use
+var templates = func() *template.Template {
+	t := template.Must(template.New("pipeline").Funcs(template.FuncMap{
+		"join":             Join,
+		"renderAgent":      RenderPipelineAgent,
+		"renderStageAgent": renderStageAgent,
+	}).Parse(pipelineTemplate))
+	template.Must(t.New("stage").Parse(stageTemplate))
+	template.Must(t.New("postCondition").Parse(postConditionTemplate))
+	return t
+}()
 
-	if err != nil {
-		fmt.Printf("parse jenkinsfile pipeline template error:%#v\n", err)
+func (pipeline *Pipeline) Render() (string, error) {
+	buffer := bytes.NewBufferString("")
+	if err := pipeline.RenderTo(buffer); err != nil {
 		return "", err
 	}
+	return buffer.String(), nil
+}
 
-	buffer := bytes.NewBufferString("")
-	err = t.Execute(buffer, pipeline)
+// RenderTo render pipeline and write it to w
+func (pipeline *Pipeline) RenderTo(w io.Writer) error {
+	pipeline.initDefault()
+
+	err := templates.ExecuteTemplate(w, "pipeline", pipeline)
 	if err != nil {
-		fmt.Printf("execute jenkinsfile pipeline template error:%#v\n", err)
-		return "", err
+		goutils.Logger.Printf("execute jenkinsfile pipeline template error:%#v\n", err)
 	}
-
-	return buffer.String(), nil
+	return err
 }
 
-func (pipeline *Pipeline) RenderAndFormat() (render string, err error) {
+// RenderAndFormat render pipeline and format it in the style of options, the default style is used when options are omitted
+func (pipeline *Pipeline) RenderAndFormat(options ...formatter.Options) (render string, err error) {
 	render, err = pipeline.Render()
 	if err == nil {
-		render = formatter.Format(render)
+		render = formatter.FormatWithOptions(render, formatOptions(options))
 	}
 	return
 }
 
+// RenderAndFormatTo render pipeline and write it to w in the style of options, it is formatted while it is rendered.
+// contents that are rendered before an error may have been written to w
+func (pipeline *Pipeline) RenderAndFormatTo(w io.Writer, options ...formatter.Options) error {
+	reader, writer := io.Pipe()
+	go func() {
+		// template writes many small pieces, they are buffered so formatter is not woken up for each of them
+		buffered := bufio.NewWriterSize(writer, renderBufferSize)
+		err := pipeline.RenderTo(buffered)
+		if err == nil {
+			err = buffered.Flush()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	err := formatter.FormatTo(w, reader, formatOptions(options))
+	// stop rendering when formatter failed
+	reader.CloseWithError(err)
+	return err
+}
+
+// renderBufferSize size of buffer between rendering and formatting
+const renderBufferSize = 32 * 1024
+
+func formatOptions(options []formatter.Options) formatter.Options {
+	if len(options) == 0 {
+		return formatter.Options{}
+	}
+	return options[0]
+}
+
 type PostCondition struct {
 	Name    string
 	Scripts string
@@ -196,6 +242,36 @@ type Stage struct {
 
 type Options struct {
 	Timeout int
+	// HistoryCount number of builds that are kept, DefaultHistoryCount is used when it is 0, only for pipeline
+	HistoryCount int
+	// HistoryDays days that builds are kept, 0 means builds are not discarded by days, only for pipeline
+	HistoryDays int
+	// Concurrent builds of pipeline could run concurrently, only for pipeline
+	Concurrent bool
+}
+
+// DefaultHistoryCount number of builds that are kept by default
+const DefaultHistoryCount = 200
+
+// ConcurrentBuilds whether builds of pipeline could run concurrently, they are disabled by default
+func (pipeline *Pipeline) ConcurrentBuilds() bool {
+	return pipeline.Options != nil && pipeline.Options.Concurrent
+}
+
+// LogRotator groovy expression that discards old builds of pipeline, eg: logRotator(numToKeepStr: '200')
+func (pipeline *Pipeline) LogRotator() string {
+	count, days := DefaultHistoryCount, 0
+	if pipeline.Options != nil {
+		if pipeline.Options.HistoryCount > 0 {
+			count = pipeline.Options.HistoryCount
+		}
+		days = pipeline.Options.HistoryDays
+	}
+
+	if days > 0 {
+		return fmt.Sprintf("logRotator(numToKeepStr: '%d', daysToKeepStr: '%d')", count, days)
+	}
+	return fmt.Sprintf("logRotator(numToKeepStr: '%d')", count)
 }
 
 type Approve struct {
@@ -206,21 +282,124 @@ type Approve struct {
 type EnvVar struct {
 	Name  string
 	Value interface{}
+	// Type how value is rendered, see EnvVarTypeLiteral, EnvVarTypeCredentials and EnvVarTypeRaw
+	Type string
+}
+
+// types of EnvVar
+const (
+	// EnvVarTypeLiteral value is rendered as a double quoted string, it is the default type.
+	// `"` and `\` are escaped, `${...}` is kept so other env vars could be referred, eg: NAME = "${APP}-${BUILD_NUMBER}"
+	EnvVarTypeLiteral = "literal"
+	// EnvVarTypeCredentials value is id of jenkins credentials, eg: TOKEN = credentials('token-id')
+	EnvVarTypeCredentials = "credentials"
+	// EnvVarTypeRaw value is a groovy expression that is rendered as it is, eg: TAG = sh(script: 'git describe', returnStdout: true).trim()
+	EnvVarTypeRaw = "raw"
+)
+
+// EnvVarTypes all types of EnvVar
+var EnvVarTypes = []string{EnvVarTypeLiteral, EnvVarTypeCredentials, EnvVarTypeRaw}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+var singleQuotedEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// SingleQuoted single quoted groovy string, `\` and `'` are escaped, `${...}` is not interpolated in it
+func SingleQuoted(s string) string {
+	return "'" + singleQuotedEscaper.Replace(s) + "'"
+}
+
+// Expression groovy expression of value
+func (env EnvVar) Expression() string {
+	value := ""
+	if env.Value != nil {
+		value = fmt.Sprint(env.Value)
+	}
+
+	switch env.Type {
+	case EnvVarTypeCredentials:
+		return "credentials(" + SingleQuoted(value) + ")"
+	case EnvVarTypeRaw:
+		return value
+	default:
+		return `"` + literalEscaper.Replace(value) + `"`
+	}
+}
+
+// Render render env var as an entry of environment block, eg: NAME = "value"
+func (env EnvVar) Render() string {
+	return fmt.Sprintf("%s = %s", env.Name, env.Expression())
+}
+
+// MergeEnvVars merge env vars of outer scope and inner scope, env vars of inner scope override the ones of outer scope that have the same name
+func MergeEnvVars(outer []EnvVar, inner []EnvVar) []EnvVar {
+	if len(outer) == 0 {
+		return inner
+	}
+	if len(inner) == 0 {
+		return outer
+	}
+
+	names := map[string]struct{}{}
+	for _, env := range inner {
+		names[env.Name] = struct{}{}
+	}
+	merged := []EnvVar{}
+	for _, env := range outer {
+		if _, ok := names[env.Name]; !ok {
+			merged = append(merged, env)
+		}
+	}
+	return append(merged, inner...)
 }
 
 type When map[string][]string
 
+// keys of When, conditions of all should be all true, and one of any should be true
+const (
+	WhenAll = "all"
+	WhenAny = "any"
+)
+
+// MergeWhen merge conditions of outer stage into conditions of inner stage, the result is true only when both of them are true
+func MergeWhen(outer *When, inner *When) *When {
+	if outer == nil || len(*outer) == 0 {
+		return inner
+	}
+	if inner == nil || len(*inner) == 0 {
+		return outer
+	}
+
+	merged := When{}
+	all := append(append([]string{}, (*outer)[WhenAll]...), (*inner)[WhenAll]...)
+	outerAny, innerAny := (*outer)[WhenAny], (*inner)[WhenAny]
+	switch {
+	case len(outerAny) > 0 && len(innerAny) > 0:
+		// only one `any` could be kept, the other one is joined to all
+		merged[WhenAny] = outerAny
+		all = append(all, "("+Join(innerAny, "||")+")")
+	case len(outerAny) > 0:
+		merged[WhenAny] = outerAny
+	case len(innerAny) > 0:
+		merged[WhenAny] = innerAny
+	}
+	if len(all) > 0 {
+		merged[WhenAll] = all
+	}
+	return &merged
+}
+
 type Steps struct {
 	ScriptsContent string
 }
 
 const stageTemplate = `stage("{{- .Name}}"){
 
-	{{ renderAgent $.Agent}}
+	{{ renderStageAgent $}}
 	{{- if .Environments}}
 	environment{
 		{{- range $index, $env := .Environments}}
-		{{$env.Name}} = "{{$env.Value}}"
+		{{$env.Render}}
 		{{- end}}
 	}
 	{{end}}
@@ -252,7 +431,7 @@ const stageTemplate = `stage("{{- .Name}}"){
 	failFast {{.FailFast}}
 	parallel{
 		{{- range $i, $stage := .Stages}}
-		{{renderStage $stage}}
+		{{template "stage" $stage}}
 		{{- end}}
 	}
 	{{- else}}
@@ -270,7 +449,7 @@ const stageTemplate = `stage("{{- .Name}}"){
 }
 `
 
-//RenderStage template func to render stage
+//RenderStage render stage
 func RenderStage(stage Stage) (string, error) {
 	return stage.Render()
 }
@@ -280,10 +459,6 @@ const postConditionTemplate = `{{- .Name}}{
 }
 `
 
-func renderPostCondition(post PostCondition) (string, error) {
-	return post.Render()
-}
-
 // Join  join string array to string using ch
 func Join(arr []string, ch string) string {
 	res := ""
@@ -298,46 +473,43 @@ func Join(arr []string, ch string) string {
 }
 
 func (stage *Stage) Render() (string, error) {
-	t, err := template.New("stage-template").Funcs(template.FuncMap{
-		"renderStage": RenderStage,
-		"join":        Join,
-		"renderAgent": func(agent interface{}) (string, error) {
-			if stage.pipeline != nil {
-				return RenderStageAgent(agent, stage.pipeline.Agent)
-			}
-			return RenderStageAgent(agent, nil)
-		},
-	}).Parse(stageTemplate)
-
-	if err != nil {
-		fmt.Printf("parse jenkinsfile stage template error:%#v\n", err)
+	buffer := bytes.NewBufferString("")
+	if err := stage.RenderTo(buffer); err != nil {
 		return "", err
 	}
+	return buffer.String(), nil
+}
 
-	buffer := bytes.NewBufferString("")
-	err = t.Execute(buffer, stage)
+// RenderTo render stage and write it to w
+func (stage *Stage) RenderTo(w io.Writer) error {
+	err := templates.ExecuteTemplate(w, "stage", stage)
 	if err != nil {
-		fmt.Printf("execute jenkinsfile stage template error:%#v\n", err)
-		return "", err
+		goutils.Logger.Printf("execute jenkinsfile stage template error:%#v\n", err)
 	}
+	return err
+}
 
-	return buffer.String(), nil
+// renderStageAgent template func to render agent of stage, it is empty when it is same as pipeline agent
+func renderStageAgent(stage *Stage) (string, error) {
+	if stage.pipeline != nil {
+		return RenderStageAgent(stage.Agent, stage.pipeline.Agent)
+	}
+	return RenderStageAgent(stage.Agent, nil)
 }
 
 func (postCondition *PostCondition) Render() (string, error) {
-	t, err := template.New("postCondition-template").Parse(postConditionTemplate)
-
-	if err != nil {
-		fmt.Printf("parse jenkinsfile post condition template error:%#v\n", err)
+	buffer := bytes.NewBufferString("")
+	if err := postCondition.RenderTo(buffer); err != nil {
 		return "", err
 	}
+	return buffer.String(), nil
+}
 
-	buffer := bytes.NewBufferString("")
-	err = t.Execute(buffer, postCondition)
+// RenderTo render post condition and write it to w
+func (postCondition *PostCondition) RenderTo(w io.Writer) error {
+	err := templates.ExecuteTemplate(w, "postCondition", postCondition)
 	if err != nil {
-		fmt.Printf("execute jenkinsfile post condition template error:%#v\n", err)
-		return "", err
+		goutils.Logger.Printf("execute jenkinsfile post condition template error:%#v\n", err)
 	}
-
-	return buffer.String(), nil
+	return err
 }
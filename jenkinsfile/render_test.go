@@ -0,0 +1,125 @@
+package jenkinsfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/otiszv/render/formatter"
+)
+
+// newBenchmarkPipeline pipeline that has stages, and each of them has parallel stages
+func newBenchmarkPipeline(stages int, parallel int) *Pipeline {
+	pipeline := &Pipeline{
+		Agent:        map[string]interface{}{"label": "golang"},
+		Environments: []EnvVar{{Name: "APP", Value: "app"}, {Name: "TOKEN", Value: "token", Type: EnvVarTypeCredentials}},
+		Post:         []*PostCondition{{Name: POST_ALWAYS, Scripts: "deleteDir()"}},
+	}
+	for i := 0; i < stages; i++ {
+		stage := &Stage{
+			Name: fmt.Sprintf("Stage-%d", i),
+			When: &When{WhenAll: []string{"env.BRANCH_NAME == 'master'"}},
+		}
+		for j := 0; j < parallel; j++ {
+			stage.Stages = append(stage.Stages, &Stage{
+				Name:    fmt.Sprintf("Task-%d-%d", i, j),
+				Agent:   map[string]interface{}{"label": "java"},
+				Options: &Options{Timeout: 600},
+				Steps: &Steps{ScriptsContent: `script {
+sh "mvn -B package -Dversion=${env.APP}"
+if (env.TOKEN) { echo 'token is set' }
+}`},
+			})
+		}
+		pipeline.Stages = append(pipeline.Stages, stage)
+	}
+	return pipeline
+}
+
+func TestRenderNestedStageAgent(t *testing.T) {
+	pipeline := &Pipeline{
+		Agent: map[string]interface{}{"label": "golang"},
+		Stages: []*Stage{{
+			Name: "Build",
+			Stages: []*Stage{
+				{Name: "Same", Agent: map[string]interface{}{"label": "golang"}, Steps: &Steps{ScriptsContent: "echo 'same'"}},
+				{Name: "Other", Agent: map[string]interface{}{"label": "java"}, Steps: &Steps{ScriptsContent: "echo 'other'"}},
+			},
+		}},
+	}
+
+	render, err := pipeline.RenderAndFormat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// agent of parallel stage that is the same as the pipeline's is omitted
+	if strings.Count(render, `label "golang"`) != 1 || strings.Count(render, `label "java"`) != 1 {
+		t.Errorf("unexpected agents in:\n%s", render)
+	}
+}
+
+func TestRenderAndFormatTo(t *testing.T) {
+	expected, err := newBenchmarkPipeline(3, 2).RenderAndFormat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var actual strings.Builder
+	if err := newBenchmarkPipeline(3, 2).RenderAndFormatTo(&actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.String() != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, actual.String())
+	}
+}
+
+// BenchmarkRenderThenFormat the jenkinsfile is rendered to a string, then formatted
+func BenchmarkRenderThenFormat(b *testing.B) {
+	pipeline := newBenchmarkPipeline(20, 4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		render, err := pipeline.Render()
+		if err != nil {
+			b.Fatal(err)
+		}
+		formatter.Format(render)
+	}
+}
+
+// BenchmarkRenderAndFormatTo the jenkinsfile is formatted while it is rendered
+func BenchmarkRenderAndFormatTo(b *testing.B) {
+	pipeline := newBenchmarkPipeline(20, 4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := pipeline.RenderAndFormatTo(ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEnvVarExpression(t *testing.T) {
+	cases := []struct {
+		name     string
+		env      EnvVar
+		expected string
+	}{
+		{"literal", EnvVar{Name: "APP", Value: "app"}, `APP = "app"`},
+		{"literal refers to env", EnvVar{Name: "TAG", Value: "${APP}-${BUILD_NUMBER}", Type: EnvVarTypeLiteral}, `TAG = "${APP}-${BUILD_NUMBER}"`},
+		{"literal escapes quotes and backslashes", EnvVar{Name: "MSG", Value: `say "hi"\n`}, `MSG = "say \"hi\"\\n"`},
+		{"literal of number", EnvVar{Name: "REPLICAS", Value: 3}, `REPLICAS = "3"`},
+		{"literal of nil", EnvVar{Name: "EMPTY"}, `EMPTY = ""`},
+		{"credentials", EnvVar{Name: "TOKEN", Value: "token-id", Type: EnvVarTypeCredentials}, `TOKEN = credentials('token-id')`},
+		// backslash is escaped before quote, or the escaped quote is broken
+		{"credentials escapes quotes and backslashes", EnvVar{Name: "TOKEN", Value: `it's\`, Type: EnvVarTypeCredentials}, `TOKEN = credentials('it\'s\\')`},
+		{"raw", EnvVar{Name: "COMMIT", Value: "sh(script: 'git rev-parse HEAD', returnStdout: true).trim()", Type: EnvVarTypeRaw}, `COMMIT = sh(script: 'git rev-parse HEAD', returnStdout: true).trim()`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := c.env.Render(); actual != c.expected {
+				t.Errorf("expected %s, but got %s", c.expected, actual)
+			}
+		})
+	}
+}